@@ -0,0 +1,220 @@
+package parser2
+
+import "slices"
+
+// Range is a range of numbers like "1..10" or "0..100 step 5".
+// Both bounds are included. Step is nil if no step is given.
+type Range struct {
+	From AST
+	To   AST
+	Step AST
+	Line
+}
+
+func (r *Range) Traverse(visitor Visitor) {
+	if visitor.Visit(r) {
+		r.From.Traverse(visitor)
+		r.To.Traverse(visitor)
+		if r.Step != nil {
+			r.Step.Traverse(visitor)
+		}
+	}
+}
+
+func (r *Range) Optimize(optimizer Optimizer) {
+	r.From = opt(r.From, optimizer)
+	r.To = opt(r.To, optimizer)
+	if r.Step != nil {
+		r.Step = opt(r.Step, optimizer)
+	}
+}
+
+func (r *Range) String() string {
+	s := r.From.String() + ".." + r.To.String()
+	if r.Step != nil {
+		s += " step " + r.Step.String()
+	}
+	return s
+}
+
+// ListComprehension creates a list like in "[x*x for x in list if x>3]".
+// If there are several generators, the value is created for all combinations
+// of the items of the generators, where the list of a generator may depend
+// on the variables of the generators in front of it.
+type ListComprehension struct {
+	Value      AST
+	Generators []*Generator
+	// OuterIdents are the variables defined outside the comprehension which
+	// are used by it. The list of the first generator is not taken into account
+	// because it is evaluated at the time the comprehension is created.
+	OuterIdents []string
+	Line
+}
+
+// Generator is a part like "for x in list if x>3" of a list
+// comprehension. Cond is nil if there is no condition.
+type Generator struct {
+	Name string
+	List AST
+	Cond AST
+}
+
+func (l *ListComprehension) Traverse(visitor Visitor) {
+	if visitor.Visit(l) {
+		l.Value.Traverse(visitor)
+		for _, g := range l.Generators {
+			g.List.Traverse(visitor)
+			if g.Cond != nil {
+				g.Cond.Traverse(visitor)
+			}
+		}
+	}
+}
+
+func (l *ListComprehension) Optimize(optimizer Optimizer) {
+	l.Value = opt(l.Value, optimizer)
+	for _, g := range l.Generators {
+		g.List = opt(g.List, optimizer)
+		if g.Cond != nil {
+			g.Cond = opt(g.Cond, optimizer)
+		}
+	}
+}
+
+func (l *ListComprehension) String() string {
+	s := "[" + l.Value.String()
+	for _, g := range l.Generators {
+		s += " " + g.String()
+	}
+	return s + "]"
+}
+
+func (g *Generator) String() string {
+	s := "for " + g.Name + " in " + g.List.String()
+	if g.Cond != nil {
+		s += " if " + g.Cond.String()
+	}
+	return s
+}
+
+// isRange returns true if the next tokens start a range like "1..10"
+func isRange(tokenizer *Tokenizer) bool {
+	return tokenizer.Peek().typ == tDot && tokenizer.PeekPeek().typ == tDot
+}
+
+// isWord returns true if the token is the given word. Words like "in"
+// or "step" are only meaningful at certain positions, so they need not
+// be keywords.
+func isWord(t Token, word string) bool {
+	return (t.typ == tIdent || t.typ == tKeyWord) && t.image == word
+}
+
+// parseRange parses the upper bound and the optional step of a range.
+// The lower bound is already parsed, the two dots are not yet consumed.
+func (p *Parser[V]) parseRange(tokenizer *Tokenizer, from AST, start Line, idents Identifiers[V]) (AST, error) {
+	line := tokenizer.Next().Line
+	tokenizer.Next()
+	to, err := p.parseOp(tokenizer, 0, idents)
+	if err != nil {
+		return nil, err
+	}
+	var step AST
+	if isWord(tokenizer.Peek(), "step") {
+		tokenizer.Next()
+		step, err = p.parseOp(tokenizer, 0, idents)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Range{
+		From: from,
+		To:   to,
+		Step: step,
+		Line: line.span(start, tokenizer.Last().End),
+	}, nil
+}
+
+// comprehensionNames returns the names of the variables of the generators
+// if the list literal which starts at the next token is a list comprehension.
+// Since the value in front of the generators uses these variables, they are
+// required before the value is parsed. If the list literal is not a list
+// comprehension, nil is returned.
+func comprehensionNames(tokenizer *Tokenizer) []string {
+	var names []string
+	depth := 0
+	for n := 1; ; n++ {
+		t := tokenizer.peekN(n)
+		switch t.typ {
+		case tOpen, tOpenBracket, tOpenCurly:
+			depth++
+		case tClose, tCloseBracket, tCloseCurly:
+			if depth == 0 {
+				return names
+			}
+			depth--
+		case tComma:
+			if depth == 0 && names == nil {
+				return nil
+			}
+		case tKeyWord:
+			if depth == 0 && t.image == "for" {
+				names = append(names, tokenizer.peekN(n+1).image)
+			}
+		case tEof:
+			return names
+		}
+	}
+}
+
+// parseComprehension parses a list comprehension like "[x*x for x in list if x>3]".
+// The opening bracket is already consumed. The names are the variables of the
+// generators found by comprehensionNames.
+func (p *Parser[V]) parseComprehension(tokenizer *Tokenizer, start Line, names []string, idents Identifiers[V]) (AST, error) {
+	var outersUsed []string
+	value, err := p.parseExpression(tokenizer, idents.AddArgs(names, &outersUsed))
+	if err != nil {
+		return nil, err
+	}
+	var generators []*Generator
+	for i := range names {
+		if t := tokenizer.Next(); t.typ != tKeyWord || t.image != "for" {
+			return nil, unexpected("for", t)
+		}
+		t := tokenizer.Next()
+		if t.typ != tIdent {
+			return nil, t.Errorf("no identifier followed by for")
+		}
+		if slices.Contains(names[:i], t.image) {
+			return nil, t.Errorf("'%s' used twice in list comprehension", t.image)
+		}
+		if t := tokenizer.Next(); !isWord(t, "in") {
+			return nil, unexpected("in", t)
+		}
+		listIdents := idents
+		if i > 0 {
+			listIdents = idents.AddArgs(names[:i], &outersUsed)
+		}
+		list, err := p.parseExpression(tokenizer, listIdents)
+		if err != nil {
+			return nil, err
+		}
+		g := &Generator{Name: names[i], List: list}
+		if t := tokenizer.Peek(); t.typ == tKeyWord && t.image == "if" {
+			tokenizer.Next()
+			g.Cond, err = p.parseExpression(tokenizer, idents.AddArgs(names[:i+1], &outersUsed))
+			if err != nil {
+				return nil, err
+			}
+		}
+		generators = append(generators, g)
+	}
+	if err := expect(tokenizer, tCloseBracket, "]"); err != nil {
+		return nil, err
+	}
+	return &ListComprehension{
+		Value:       value,
+		Generators:  generators,
+		OuterIdents: outersUsed,
+		Line:        start.span(start, tokenizer.Last().End),
+	}, nil
+}
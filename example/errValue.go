@@ -57,15 +57,18 @@ func createErrValueMethods() value.MethodMap {
 		"val": value.MethodAtType(0, func(ev ErrValue, stack funcGen.Stack[value.Value]) (value.Value, error) {
 			return value.Float(ev.val), nil
 		}).
+			SetResult("float").
 			SetMethodDescription("Returns the value of the error value."),
 		"err": value.MethodAtType(0, func(ev ErrValue, stack funcGen.Stack[value.Value]) (value.Value, error) {
 			return value.Float(ev.err), nil
 		}).
+			SetResult("float").
 			SetMethodDescription("Returns the error of the error value."),
 		"string": value.MethodAtType(0, func(ev ErrValue, stack funcGen.Stack[value.Value]) (value.Value, error) {
 			s, err := ev.ToString(stack)
 			return value.String(s), err
 		}).
+			SetResult("string").
 			SetMethodDescription("Returns the string representation of the error value."),
 	}
 }
@@ -107,28 +110,29 @@ var ErrValueParser = value.New().
 		Func:   toErr,
 		Args:   1,
 		IsPure: true,
+		Result: "errValue",
 	}.SetDescription("float", "Creates an error value with the given float as the error. The value is set to 0."))
 
 func addAdd(f *value.FunctionGenerator) {
 	m := f.GetOpMatrix("+")
-	m.Register(errValType, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, errValType, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		av := a.(ErrValue)
 		bv := b.(ErrValue)
 		return ErrValue{val: av.val + bv.val, err: av.err + bv.err}, nil
 	})
-	m.Register(errValType, value.FloatTypeId, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		av := a.(ErrValue)
 		return ErrValue{val: av.val + float64(b.(value.Float)), err: av.err}, nil
 	})
-	m.Register(value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(value.FloatTypeId, errValType, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		bv := b.(ErrValue)
 		return ErrValue{val: bv.val + float64(a.(value.Float)), err: bv.err}, nil
 	})
-	m.Register(errValType, value.IntTypeId, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.IntTypeId, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		av := a.(ErrValue)
 		return ErrValue{val: av.val + float64(b.(value.Int)), err: av.err}, nil
 	})
-	m.Register(value.IntTypeId, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(value.IntTypeId, errValType, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		bv := b.(ErrValue)
 		return ErrValue{val: bv.val + float64(a.(value.Int)), err: bv.err}, nil
 	})
@@ -136,24 +140,24 @@ func addAdd(f *value.FunctionGenerator) {
 
 func addSub(f *value.FunctionGenerator) {
 	m := f.GetOpMatrix("-")
-	m.Register(errValType, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, errValType, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		av := a.(ErrValue)
 		bv := b.(ErrValue)
 		return ErrValue{val: av.val - bv.val, err: av.err + bv.err}, nil
 	})
-	m.Register(errValType, value.FloatTypeId, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		av := a.(ErrValue)
 		return ErrValue{val: av.val - float64(b.(value.Float)), err: av.err}, nil
 	})
-	m.Register(value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(value.FloatTypeId, errValType, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		bv := b.(ErrValue)
 		return ErrValue{val: float64(a.(value.Float)) - bv.val, err: bv.err}, nil
 	})
-	m.Register(errValType, value.IntTypeId, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.IntTypeId, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		av := a.(ErrValue)
 		return ErrValue{val: av.val - float64(b.(value.Int)), err: av.err}, nil
 	})
-	m.Register(value.IntTypeId, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
+	m.RegisterResult(value.IntTypeId, errValType, errValType, func(_ funcGen.Stack[value.Value], a, b value.Value) (value.Value, error) {
 		bv := b.(ErrValue)
 		return ErrValue{val: float64(a.(value.Int)) - bv.val, err: bv.err}, nil
 	})
@@ -161,24 +165,24 @@ func addSub(f *value.FunctionGenerator) {
 
 func addMul(f *value.FunctionGenerator) {
 	m := f.GetOpMatrix("*")
-	m.Register(errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		b := bv.(ErrValue)
 		return ErrValue{a.val * b.val, math.Abs(a.val*b.err) + math.Abs(b.val*a.err) + b.err*a.err}, nil
 	})
-	m.Register(errValType, value.FloatTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		return ErrValue{a.val * float64(bv.(value.Float)), math.Abs(float64(bv.(value.Float)) * a.err)}, nil
 	})
-	m.Register(value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(value.FloatTypeId, errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		b := bv.(ErrValue)
 		return ErrValue{float64(av.(value.Float)) * b.val, math.Abs(float64(av.(value.Float)) * b.err)}, nil
 	})
-	m.Register(errValType, value.IntTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.IntTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		return ErrValue{a.val * float64(bv.(value.Int)), math.Abs(float64(bv.(value.Int)) * a.err)}, nil
 	})
-	m.Register(value.IntTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(value.IntTypeId, errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		b := bv.(ErrValue)
 		return ErrValue{float64(av.(value.Int)) * b.val, math.Abs(float64(av.(value.Int)) * b.err)}, nil
 	})
@@ -186,28 +190,28 @@ func addMul(f *value.FunctionGenerator) {
 
 func addDiv(f *value.FunctionGenerator) {
 	m := f.GetOpMatrix("/")
-	m.Register(errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		b := bv.(ErrValue)
 		val := a.val / b.val
 		return ErrValue{val, (math.Abs(a.val)+a.err)/(math.Abs(b.val)-b.err) - math.Abs(val)}, nil
 	})
-	m.Register(errValType, value.FloatTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		val := a.val / float64(bv.(value.Float))
 		return ErrValue{val, (math.Abs(a.val)+a.err)/math.Abs(float64(bv.(value.Float))) - math.Abs(val)}, nil
 	})
-	m.Register(value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(value.FloatTypeId, errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		b := bv.(ErrValue)
 		val := float64(av.(value.Float)) / b.val
 		return ErrValue{val, math.Abs(float64(av.(value.Float)))/(math.Abs(b.val)-b.err) - math.Abs(val)}, nil
 	})
-	m.Register(errValType, value.IntTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.IntTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		val := a.val / float64(bv.(value.Int))
 		return ErrValue{val, (math.Abs(a.val)+a.err)/math.Abs(float64(bv.(value.Int))) - math.Abs(val)}, nil
 	})
-	m.Register(value.IntTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(value.IntTypeId, errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		b := bv.(ErrValue)
 		val := float64(av.(value.Int)) / b.val
 		return ErrValue{val, math.Abs(float64(av.(value.Int)))/(math.Abs(b.val)-b.err) - math.Abs(val)}, nil
@@ -216,17 +220,17 @@ func addDiv(f *value.FunctionGenerator) {
 
 func addEqual(f *value.FunctionGenerator) {
 	m := f.GetOpMatrix("=")
-	m.Register(errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, errValType, value.BoolTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		b := bv.(ErrValue)
 		return value.Bool(a.Matches(b)), nil
 	})
-	m.Register(errValType, value.FloatTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.FloatTypeId, value.BoolTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		b := float64(bv.(value.Float))
 		return value.Bool(a.MatchesFloat(b)), nil
 	})
-	m.Register(value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(value.FloatTypeId, errValType, value.BoolTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := float64(av.(value.Float))
 		b := bv.(ErrValue)
 		return value.Bool(b.MatchesFloat(a)), nil
@@ -235,27 +239,27 @@ func addEqual(f *value.FunctionGenerator) {
 
 func addLess(f *value.FunctionGenerator) {
 	m := f.GetOpMatrix("<")
-	m.Register(errValType, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, errValType, value.BoolTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		b := bv.(ErrValue)
 		return value.Bool(a.GetMax() < b.GetMin()), nil
 	})
-	m.Register(errValType, value.FloatTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.FloatTypeId, value.BoolTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		b := float64(bv.(value.Float))
 		return value.Bool(a.GetMax() < b), nil
 	})
-	m.Register(value.FloatTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(value.FloatTypeId, errValType, value.BoolTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := float64(av.(value.Float))
 		b := bv.(ErrValue)
 		return value.Bool(a < b.GetMin()), nil
 	})
-	m.Register(errValType, value.IntTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, value.IntTypeId, value.BoolTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		b := float64(bv.(value.Int))
 		return value.Bool(a.GetMax() < b), nil
 	})
-	m.Register(value.IntTypeId, errValType, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
+	m.RegisterResult(value.IntTypeId, errValType, value.BoolTypeId, func(_ funcGen.Stack[value.Value], av, bv value.Value) (value.Value, error) {
 		a := float64(av.(value.Int))
 		b := bv.(ErrValue)
 		return value.Bool(a < b.GetMin()), nil
@@ -264,7 +268,7 @@ func addLess(f *value.FunctionGenerator) {
 
 func addNeg(f *value.FunctionGenerator) {
 	m := f.GetUnaryList("-")
-	m.Register(errValType, func(av value.Value) (value.Value, error) {
+	m.RegisterResult(errValType, errValType, func(av value.Value) (value.Value, error) {
 		a := av.(ErrValue)
 		return ErrValue{-a.val, a.err}, nil
 	})
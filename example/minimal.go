@@ -19,7 +19,7 @@ var minimal = funcGen.New[float64]().
 	AddSimpleOp("-", false, func(a, b float64) (float64, error) { return a - b, nil }).
 	AddSimpleOp("*", true, func(a, b float64) (float64, error) { return a * b, nil }).
 	AddSimpleOp("/", false, func(a, b float64) (float64, error) { return a / b, nil }).
-	AddSimpleOp("^", false, func(a, b float64) (float64, error) { return math.Pow(a, b), nil }).
+	AddOpBehindAssoc("", "^", false, funcGen.OperatorFunc[float64](func(st funcGen.Stack[float64], a, b float64) (float64, error) { return math.Pow(a, b), nil }), true, parser2.RightAssociative).
 	AddUnaryFunc("-", func(a float64) (float64, error) { return -a, nil }).
 	AddSimpleFunction("sin", math.Sin).
 	AddSimpleFunction("cos", math.Cos).
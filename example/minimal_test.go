@@ -20,6 +20,10 @@ func TestMinimal(t *testing.T) {
 		{"(2+4)*4", 24, "24"},
 		{"4*(2+4)", 24, "24"},
 		{"3^2", 9, "9"},
+		{"2^3^2", 512, "512"},
+		{"(2^3)^2", 64, "64"},
+		{"a^a^a", 16, "a^(a^a)"},
+		{"(a^a)^a", 16, "(a^a)^a"},
 		{"a-1", 1, "a-1"},
 		{"1+a", 3, "1+a"},
 		{"4*4+a", 18, "16+a"},
@@ -0,0 +1,267 @@
+package parser2
+
+import (
+	"strings"
+	"unicode"
+)
+
+// formatLineWidth is the width of a line up to which the formatter
+// keeps method chains and map literals in a single line
+const formatLineWidth = 80
+
+// Comment is a comment found in the source code
+type Comment struct {
+	Text string
+	Line
+}
+
+// IsLineComment returns true if the comment is a '//' comment
+// which ends at the end of the line.
+func (c Comment) IsLineComment() bool {
+	return strings.HasPrefix(c.Text, "//")
+}
+
+// Format parses the given source code and formats it the same way PrettyPrint
+// does. In contrast to PrettyPrint, the comments are kept, all literals are
+// written as found in the source code, constants are not folded, blank lines
+// between definitions are kept, and method chains and map literals are only
+// wrapped if they do not fit in a line. Formatting an already formatted source
+// code does not change it. Comments are allowed even if they are not
+// enabled by AllowComments. The same errors as in Parse are reported.
+func (p *Parser[V]) Format(src string, idents Identifiers[V]) (string, error) {
+	if err := p.checkParse(src, idents); err != nil {
+		return "", err
+	}
+
+	np := *p
+	np.optimizer = nil
+	np.allowComments = true
+	np.formatting = true
+	tokenizer := np.newTokenizer(src)
+	tokenizer.collectComments = true
+
+	ast, err := np.parseLet(tokenizer, idents)
+	if err != nil {
+		return "", err
+	}
+	if t := tokenizer.Next(); t.typ != tEof {
+		return "", unexpected("EOF", t)
+	}
+
+	w := newWriter()
+	w.pw.f = &formatter{
+		src:      src,
+		comments: tokenizer.comments,
+		unaryPos: func(op string) int {
+			if u, ok := np.unary[op]; ok {
+				return u.opPos
+			}
+			return -1
+		},
+	}
+	prettyPrintAST[V](w, ast)
+	w.pw.f.flush(w, len(src))
+	return strings.TrimRight(w.String(), " \n") + "\n", nil
+}
+
+// formatter holds the state needed by the writer if the source code
+// is formatted instead of pretty printed.
+type formatter struct {
+	src string
+	// comments are the comments not yet written
+	comments []Comment
+	// lastEnd is the end of the source code written so far
+	lastEnd int
+	// flat is set if a node is written to check if it fits in a single line
+	flat     bool
+	unaryPos func(op string) int
+}
+
+// source returns the source code of the given line or
+// the given default if there is no such source code.
+func (f *formatter) source(l Line, def string) string {
+	if l.Num <= 0 || l.Start >= l.End || l.End > len(f.src) {
+		return def
+	}
+	s := f.src[l.Start:l.End]
+	if d, ok := superscriptDigits[s]; ok {
+		return d
+	}
+	return s
+}
+
+// verbatim returns the source code of the given line. All comments
+// contained in the source code are removed from the pending comments.
+func (f *formatter) verbatim(l Line) string {
+	var comments []Comment
+	for _, c := range f.comments {
+		if c.Start < l.Start || c.Start >= l.End {
+			comments = append(comments, c)
+		}
+	}
+	f.comments = comments
+	return f.src[l.Start:l.End]
+}
+
+var superscriptDigits = map[string]string{
+	"⁰": "0", "¹": "1", "²": "2", "³": "3", "⁴": "4",
+	"⁵": "5", "⁶": "6", "⁷": "7", "⁸": "8", "⁹": "9",
+}
+
+// enter is called before the given node is written.
+// All comments in front of the node are written.
+func (f *formatter) enter(w *writer, l Line) {
+	if l.Num > 0 {
+		f.flush(w, l.Start)
+	}
+}
+
+// leave is called after the given node is written
+func (f *formatter) leave(l Line) {
+	if l.Num > 0 && l.End > f.lastEnd {
+		f.lastEnd = l.End
+	}
+}
+
+// hasComments returns true if there are comments not yet
+// written which are located in front of the given position.
+func (f *formatter) hasComments(end int) bool {
+	return len(f.comments) > 0 && f.comments[0].Start < end
+}
+
+// flush writes all comments located in front of the given position
+func (f *formatter) flush(w *writer, end int) {
+	for f.hasComments(end) {
+		c := f.comments[0]
+		f.comments = f.comments[1:]
+		if f.startsLine(c.Start) {
+			if w.pw.col > w.tab {
+				w.pw.newLine()
+				w.writeIndent()
+			}
+		} else if w.pw.col > 0 && !w.pw.endsWithBlank() {
+			w.writeString(" ")
+		}
+		w.writeString(c.Text)
+		if c.End > f.lastEnd {
+			f.lastEnd = c.End
+		}
+		if c.IsLineComment() || f.newLineFollows(c.End) {
+			next := end
+			if f.hasComments(end) {
+				next = f.comments[0].Start
+			}
+			if f.blankLine(c.End, next) {
+				w.pw.newLine()
+			}
+			w.pw.newLine()
+			w.writeIndent()
+		} else {
+			w.writeString(" ")
+		}
+	}
+}
+
+// flushTrailing writes all comments which are located in the
+// same source line as the source code written so far.
+// It is called before a new line is started.
+func (f *formatter) flushTrailing(w *writer) {
+	for len(f.comments) > 0 && f.isTrailing(f.comments[0]) {
+		c := f.comments[0]
+		f.comments = f.comments[1:]
+		w.writeString(" " + c.Text)
+		if c.End > f.lastEnd {
+			f.lastEnd = c.End
+		}
+	}
+}
+
+// flushHeader writes the comments in front of the given position which are
+// located in the same source line as the header starting at from.
+func (f *formatter) flushHeader(w *writer, from, to int) {
+	for f.hasComments(to) && !strings.Contains(f.src[from:f.comments[0].Start], "\n") {
+		c := f.comments[0]
+		f.comments = f.comments[1:]
+		w.writeString(" " + c.Text)
+		f.lastEnd = c.End
+	}
+}
+
+// isTrailing returns true if the comment belongs to the source
+// line of the source code written so far. This is the case if there
+// are only blanks, brackets and operators in between.
+func (f *formatter) isTrailing(c Comment) bool {
+	if f.lastEnd == 0 {
+		return false
+	}
+	if c.Start < f.lastEnd {
+		return true
+	}
+	for _, r := range f.src[f.lastEnd:c.Start] {
+		if r == '\n' || r == '"' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// startsLine returns true if there is nothing but blanks
+// between the start of the source line and the given position.
+func (f *formatter) startsLine(pos int) bool {
+	before := strings.TrimRight(f.src[:pos], " \t\r")
+	return len(before) == 0 || before[len(before)-1] == '\n'
+}
+
+// newLineFollows returns true if there is nothing but blanks
+// between the given position and the end of the source line.
+func (f *formatter) newLineFollows(pos int) bool {
+	rest := strings.TrimLeft(f.src[pos:], " \t\r")
+	return len(rest) == 0 || rest[0] == '\n'
+}
+
+// blankLine returns true if there is an empty line between
+// the two given positions.
+func (f *formatter) blankLine(from, to int) bool {
+	if from >= to || to > len(f.src) {
+		return false
+	}
+	lines := strings.Split(f.src[from:to], "\n")
+	for i := 1; i < len(lines)-1; i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// fits returns true if the given node can be written in the current line.
+func fits[V any](w *writer, ast AST) bool {
+	f := w.pw.f
+	if f.flat {
+		return true
+	}
+	l := ast.GetLine()
+	if l.Num > 0 && f.hasComments(l.End) {
+		return false
+	}
+	trial := &writer{
+		pw: &posWriter{
+			col: w.pw.col,
+			f:   &formatter{src: f.src, flat: true, unaryPos: f.unaryPos},
+		},
+		tab: w.tab,
+	}
+	prettyPrintAST[V](trial, ast)
+	return trial.pw.col <= formatLineWidth && !strings.Contains(trial.String(), "\n")
+}
+
+// checkParse parses the given source code the normal way. If the code is
+// parsed for formatting, the constants are not folded, so errors like a
+// const definition with a value which is not constant are only detected
+// by parsing the code the normal way.
+func (p *Parser[V]) checkParse(src string, idents Identifiers[V]) error {
+	np := *p
+	np.allowComments = true
+	_, err := np.Parse(src, idents)
+	return err
+}
@@ -0,0 +1,64 @@
+package parser2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"simple", "a+ 2", "a+2\n"},
+		{"noFold", "1+1", "1+1\n"},
+		{"literal", "0012+a", "0012+a\n"},
+		{"lineComment", "// comment\na", "// comment\na\n"},
+		{"trailing", "let x=a+2; // two\nx+a", "let x = a+2; // two\nx+a\n"},
+		{"block", "let x= /* two */ 2;x", "let x = /* two */ 2;\nx\n"},
+		{"blankLine", "let x=1;\n\n\nlet y=2;x+y", "let x = 1;\n\nlet y = 2;\nx+y\n"},
+		{"end", "a // end", "a // end\n"},
+		{"endOwnLine", "a\n// end", "a\n// end\n"},
+		{"unary", "-(a+1)", "-(a+1)\n"},
+		{"superscript", "a²", "a^2\n"},
+		{"shortChain", "a.m(1).n(2)", "a.m(1).n(2)\n"},
+		{"longChain", "a.first(1000000000).second(2000000000).third(3000000000).fourth(4000000000).fifth()",
+			"a\n .first(1000000000)\n .second(2000000000)\n .third(3000000000)\n .fourth(4000000000)\n .fifth()\n"},
+		{"chainComment", "a.m(1) // one\n.n(2)", "a\n .m(1) // one\n .n(2)\n"},
+		{"func", "func f(n) // square\n n^2; f(5)", "func f(n) // square\n  n^2;\nf(5)\n"},
+		{"map", "{a:1,b:2}", "{a: 1, b: 2}\n"},
+		{"mapComment", "{a:1, // one\nb:2}", "{a: 1, // one\n b: 2}\n"},
+		{"destructuring", "let [x,y]=a;let {b}=x;b+y", "let [x, y] = a;\nlet {b} = x;\nb+y\n"},
+		{"destructuredArgs", "func f([x,y], {z}) x+y+z;f(a,a)", "func f([x, y], {z})\n  x+y+z;\nf(a, a)\n"},
+		{"destructuredClosure", "a.m(({x})->x)", "a.m(({x}) -> x)\n"},
+		{"defaults", "func f(x, s=2) x*s;f(a, s:3)", "func f(x, s=2)\n  x*s;\nf(a, s: 3)\n"},
+		{"closureDefaults", "a.m((x, y=1)->x+y)", "a.m((x, y=1) -> x+y)\n"},
+		{"pipe", "let f=a;a|>f(1)|>f", "let f = a;\na |> f(1) |> f\n"},
+		{"pipeParentheses", "let f=a;-(a|>f)+(a|>(f(1)))", "let f = a;\n-(a |> f)+(a |> (f(1)))\n"},
+		{"optional", "a?.b?.m( 1)", "a?.b?.m(1)\n"},
+		{"funcGroup", "func f(n) g(n); // f\n\nfunc g(n) f(n);f(a)", "func f(n)\n  g(n); // f\n\nfunc g(n)\n  f(n);\nf(a)\n"},
+		{"range", "0..a  step 2", "0..a step 2\n"},
+		{"rangeParentheses", "(1..a).size()+ -(1..2)", "(1..a).size()+-(1..2)\n"},
+		{"comprehension", "[x*x for x in a if x>1  for y in 1..x]", "[x*x for x in a if x>1 for y in 1..x]\n"},
+		{"import", "import  \"m\" as m; // module\n\nm.f(a)", "import \"m\" as m; // module\n\nm.f(a)\n"},
+		{"const", "const  c=1+1; // two\nc*a", "const c = 1+1; // two\nc*a\n"},
+		{"match", "switch a match [x, ...r] if x>1: x match {k: 1, v}: v default 0",
+			"switch a\n  match [x, ...r] if x>1: x\n  match {k: 1, v}: v\n  default 0\n"},
+	}
+
+	var idents Identifiers[int]
+	idents = idents.Add("a")
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, err := parser.Format(tt.src, idents)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, formatted)
+
+			again, err := parser.Format(formatted, idents)
+			assert.NoError(t, err)
+			assert.Equal(t, formatted, again, "formatting is not idempotent")
+		})
+	}
+}
@@ -0,0 +1,691 @@
+package funcGen
+
+import (
+	"github.com/hneemann/parser2"
+	"github.com/hneemann/parser2/listMap"
+	"slices"
+)
+
+// Backend selects how the generated functions are executed
+type Backend int
+
+const (
+	// TreeBackend creates a tree of nested go closures, one for each node
+	// of the AST. This is the default.
+	TreeBackend Backend = iota
+	// BytecodeBackend compiles the AST to a compact bytecode which is executed
+	// by a stack machine. The local variables are stored in the Stack of the
+	// function, the temporary values are kept by the machine itself, so both
+	// backends support the same recursion depth and produce the same results.
+	// This backend is an alternative execution model, not a performance option:
+	// the evaluation time is dominated by the operations on the values, which
+	// are the same for both backends, so it is not faster than the TreeBackend,
+	// see Benchmark_tree and Benchmark_bytecode in the value package. Use the
+	// TreeBackend if performance matters.
+	//
+	// Nodes which are not supported by the compiler are embedded as a tree of
+	// go closures. These are closure literals, switch, try-catch, list
+	// comprehensions, ranges, string interpolations and optional method calls
+	// without a nil handler. The value of a closure literal is created by
+	// the tree code, but its body is compiled by this backend again.
+	BytecodeBackend
+)
+
+// SetBackend sets the backend used to create the functions
+func (g *FunctionGenerator[V]) SetBackend(backend Backend) *FunctionGenerator[V] {
+	g.backend = backend
+	return g
+}
+
+type opCode uint8
+
+const (
+	// opConst pushes the constant a
+	opConst opCode = iota
+	// opLoad pushes the value stored at index a of the stack frame
+	opLoad
+	// opLoadCtx pushes the value stored at index a of the closure context
+	opLoadCtx
+	// opLet pops a value and stores it as a new local variable in the stack frame
+	opLet
+	// opDropLocals removes the a local variables created last from the stack frame
+	opDropLocals
+	// opJump jumps to a
+	opJump
+	// opJumpIfFalse pops the condition and jumps to a if it is false;
+	// b is the node used to report a condition which is not a bool
+	opJumpIfFalse
+	// opJumpIfFalseOperate jumps to a if the result of the operation b,
+	// which is the condition of an if, is false
+	opJumpIfFalseOperate
+	// opUnary applies the unary operation a to the top of the stack
+	opUnary
+	// opOperate pops two values and pushes the result of the operation a
+	opOperate
+	// opOperateRight replaces the top of the stack by the result of the
+	// operation a applied to it and the second operand of the operation
+	opOperateRight
+	// opOperateLeft replaces the top of the stack by the result of the
+	// operation a applied to the first operand of the operation and the top of the stack
+	opOperateLeft
+	// opOperateBoth pushes the result of the operation a applied to its operands
+	opOperateBoth
+	// opList pops a values and pushes a list containing them
+	opList
+	// opMap pops the values of the map literal a which are not read directly
+	// and pushes a map containing all values
+	opMap
+	// opMapAccess pops a map and pushes the value of the key a
+	opMapAccess
+	// opMapAccessOptional is like opMapAccess, but behaves like the optional chaining "m?.a"
+	opMapAccessOptional
+	// opLoadMapAccess pushes the value of the key b of the map stored at index a of the stack frame
+	opLoadMapAccess
+	// opListAccess pops a list and an index and pushes the indexed item
+	opListAccess
+	// opCallStatic calls the static function a with b arguments
+	opCallStatic
+	// opCheckFunc pops the function which is called with a arguments
+	// and keeps it for the call; b is the node of the function call
+	opCheckFunc
+	// opCall calls the function kept by opCheckFunc with a arguments; b is the node of the function call
+	opCall
+	// opTailCall reuses the stack frame for a call of the function itself with a arguments
+	opTailCall
+	// opMethod resolves the method call a on the top of the stack; jumps to b if the value is nil
+	opMethod
+	// opInvoke invokes the method resolved by opMethod
+	opInvoke
+	// opTree pushes the result of the tree function a
+	opTree
+)
+
+// maxTemps is the number of temporary values and maxCalls the number of
+// pending calls a program can use. Both are stored in arrays on the go stack,
+// so they neither occupy the Stack shared with the called functions nor
+// require write barriers. Functions which need more are executed by the tree code.
+const (
+	maxTemps = 8
+	maxCalls = 4
+)
+
+type instr struct {
+	op opCode
+	a  int32
+	b  int32
+}
+
+// errorWrap is used to enhance an error created by an instruction in the range
+// [from, to) the same way the tree backend enhances the error of a child node.
+type errorWrap struct {
+	from, to int
+	wrap     func(error) error
+}
+
+type operandKind uint8
+
+const (
+	// operandTemp is a value which is popped
+	operandTemp operandKind = iota
+	// operandConst is the constant index
+	operandConst
+	// operandLocal is the local variable index
+	operandLocal
+	// operandLocalKey is the value of key of the map stored in the local variable index
+	operandLocalKey
+)
+
+// operand is a value which is read directly by an operation
+// instead of being pushed
+type operand struct {
+	kind  operandKind
+	index int
+	key   string
+}
+
+// operation is an operation which reads some of its operands directly
+type operation[V any] struct {
+	impl OperatorImpl[V]
+	a, b operand
+	node *parser2.Operate
+	// cond is the if using the operation as its condition, only set for opJumpIfFalseOperate
+	cond *parser2.If
+}
+
+// mapEntry is an entry of a map literal. If the value is not read
+// directly, it is popped.
+type mapEntry struct {
+	key    string
+	direct bool
+	value  operand
+}
+
+// mapLiteral describes the map created by opMap
+type mapLiteral struct {
+	entries []mapEntry
+	// pushed is the number of values popped
+	pushed int
+}
+
+// methodCall contains the data needed to execute a method call
+type methodCall[V any] struct {
+	node *parser2.MethodCall
+	args int
+	nh   NilHandler[V]
+}
+
+// program is the compiled bytecode of a function
+type program[V any] struct {
+	g       *FunctionGenerator[V]
+	code    []instr
+	consts  []V
+	keys    []string
+	maps    []mapLiteral
+	ops     []OperatorImpl[V]
+	opers   []operation[V]
+	unary   []UnaryOperatorImpl[V]
+	static  []Function[V]
+	trees   []ParserFunc[V]
+	methods []methodCall[V]
+	nodes   []parser2.AST
+	wraps   []errorWrap
+	nh      NilHandler[V]
+	// locals is the number of values in the stack frame when the program is started
+	locals int
+}
+
+// wrapError enhances the error created by the instruction at pc
+func (p *program[V]) wrapError(pc int, err error) error {
+	for _, w := range p.wraps {
+		if pc >= w.from && pc < w.to {
+			err = w.wrap(err)
+		}
+	}
+	return err
+}
+
+// compiler creates the bytecode of a program
+type compiler[V any] struct {
+	g *FunctionGenerator[V]
+	p *program[V]
+	// slots are the names of the local variables in the stack frame
+	slots argsList
+	// temps is the number of temporary values, maxTemps the largest number used
+	temps, maxTemps int
+	// calls is the number of functions resolved but not yet called, maxCalls the largest number used
+	calls, maxCalls int
+	// base is the context the program is compiled in
+	base GeneratorContext
+}
+
+// compile creates a function which executes the given AST using the bytecode machine
+func (g *FunctionGenerator[V]) compile(ast parser2.AST, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	c := &compiler[V]{
+		g:     g,
+		p:     &program[V]{g: g, locals: len(gc.am)},
+		slots: slices.Clone(gc.am),
+		base:  gc,
+	}
+	if nh, ok := g.mapHandler.(NilHandler[V]); ok {
+		c.p.nh = nh
+	}
+	pure, err := c.compile(ast)
+	if err != nil {
+		return nil, false, err
+	}
+	if c.maxTemps > maxTemps || c.maxCalls > maxCalls {
+		return g.generateTree(ast, gc)
+	}
+	// the local variables need not be removed at the end of the program
+	for len(c.p.code) > 0 && c.p.code[len(c.p.code)-1].op == opDropLocals {
+		c.p.code = c.p.code[:len(c.p.code)-1]
+	}
+	if len(c.p.code) == 1 && c.p.code[0].op == opTree {
+		// nothing is compiled, so the machine is not required
+		return c.p.trees[0], pure, nil
+	}
+	return c.p.run, pure, nil
+}
+
+func (c *compiler[V]) pc() int {
+	return len(c.p.code)
+}
+
+// emit adds an instruction and returns its address
+func (c *compiler[V]) emit(op opCode, a, b int) int {
+	c.p.code = append(c.p.code, instr{op: op, a: int32(a), b: int32(b)})
+	return len(c.p.code) - 1
+}
+
+// wrap enhances the errors of the instructions emitted since from
+func (c *compiler[V]) wrap(from int, line parser2.Line, m string, a ...any) {
+	c.p.wraps = append(c.p.wraps, errorWrap{from: from, to: c.pc(), wrap: func(err error) error {
+		return line.EnhanceErrorf(err, m, a...)
+	}})
+}
+
+func (c *compiler[V]) node(ast parser2.AST) int {
+	c.p.nodes = append(c.p.nodes, ast)
+	return len(c.p.nodes) - 1
+}
+
+// push adds a temporary value
+func (c *compiler[V]) push() {
+	c.temps++
+	c.maxTemps = max(c.maxTemps, c.temps)
+}
+
+// drop removes n temporary values
+func (c *compiler[V]) drop(n int) {
+	c.temps -= n
+}
+
+// resolved adds a function which is resolved but not yet called
+func (c *compiler[V]) resolved() {
+	c.calls++
+	c.maxCalls = max(c.maxCalls, c.calls)
+}
+
+func (c *compiler[V]) gc() GeneratorContext {
+	gc := c.base
+	gc.am = slices.Clone(c.slots)
+	return gc
+}
+
+// fallback embeds the tree function of the given AST
+func (c *compiler[V]) fallback(ast parser2.AST) (bool, error) {
+	f, pure, err := c.g.generateTree(ast, c.gc())
+	if err != nil {
+		return false, err
+	}
+	c.embed(f)
+	return pure, nil
+}
+
+func (c *compiler[V]) embed(f ParserFunc[V]) {
+	c.p.trees = append(c.p.trees, f)
+	c.emit(opTree, len(c.p.trees)-1, 0)
+	c.push()
+}
+
+// compileWrapped compiles the given AST and enhances its errors
+func (c *compiler[V]) compileWrapped(ast parser2.AST, line parser2.Line, m string, a ...any) (bool, error) {
+	start := c.pc()
+	pure, err := c.compile(ast)
+	if err != nil {
+		return false, err
+	}
+	c.wrap(start, line, m, a...)
+	return pure, nil
+}
+
+// compileList compiles the given ASTs and enhances their errors
+func (c *compiler[V]) compileList(list []parser2.AST, line parser2.Line, m string, a ...any) (bool, error) {
+	pure := true
+	for _, item := range list {
+		p, err := c.compileWrapped(item, line, m, a...)
+		if err != nil {
+			return false, err
+		}
+		pure = pure && p
+	}
+	return pure, nil
+}
+
+// compile emits the code of the given AST. The code leaves
+// the value of the AST on top of the stack.
+func (c *compiler[V]) compile(ast parser2.AST) (bool, error) {
+	g := c.g
+	if g.customGenerator != nil {
+		f, pure, err := g.customGenerator.GenerateCustom(ast, c.gc(), g)
+		if err != nil {
+			return false, err
+		}
+		if f != nil {
+			c.embed(f)
+			return pure, nil
+		}
+	}
+	switch a := ast.(type) {
+	case *parser2.Const[V]:
+		c.p.consts = append(c.p.consts, a.Value)
+		c.emit(opConst, len(c.p.consts)-1, 0)
+		c.push()
+		return true, nil
+	case *parser2.Ident:
+		if index, ok := c.slots.get(a.Name); ok {
+			c.emit(opLoad, index, 0)
+			c.push()
+			return true, nil
+		}
+		if index, ok := c.base.cm.get(a.Name); ok {
+			c.emit(opLoadCtx, index, 0)
+			c.push()
+			return true, nil
+		}
+		var avail []string
+		for _, n := range append(c.slots, c.base.cm...) {
+			if n != "" {
+				avail = append(avail, n)
+			}
+		}
+		return false, parser2.NewNotFoundError(a.Name, a.Errorf("not found: %s", a.Name)).SetAvail(avail...)
+	case *parser2.Let:
+		if a.Destructuring == nil {
+			pure, err := c.compileWrapped(a.Value, a.Line, "error in let")
+			if err != nil {
+				return false, err
+			}
+			slots, err := c.slots.add(a.Name)
+			if err != nil {
+				return false, a.EnhanceErrorf(err, "error in let")
+			}
+			c.emit(opLet, 0, 0)
+			c.drop(1)
+			c.slots = slots
+			mainPure, err := c.compile(a.Inner)
+			if err != nil {
+				return false, err
+			}
+			c.emit(opDropLocals, 1, 0)
+			c.slots = c.slots[:len(c.slots)-1]
+			return pure && mainPure, nil
+		}
+	case *parser2.ConstDef:
+		// all usages of the constant are already replaced by its value
+		return c.compile(a.Inner)
+	case *parser2.If:
+		if g.toBool != nil {
+			var jumpElse int
+			condPure := true
+			if o, ok := c.operation(a.Cond); ok && o.a.kind != operandTemp && o.b.kind != operandTemp {
+				o.cond = a
+				c.p.opers = append(c.p.opers, o)
+				jumpElse = c.emit(opJumpIfFalseOperate, 0, len(c.p.opers)-1)
+			} else {
+				var err error
+				condPure, err = c.compileWrapped(a.Cond, a.Line, "error in if")
+				if err != nil {
+					return false, err
+				}
+				jumpElse = c.emit(opJumpIfFalse, 0, c.node(a))
+				c.drop(1)
+			}
+			thenPure, err := c.compile(a.Then)
+			if err != nil {
+				return false, err
+			}
+			jumpEnd := c.emit(opJump, 0, 0)
+			c.drop(1)
+			c.p.code[jumpElse].a = int32(c.pc())
+			elsePure, err := c.compile(a.Else)
+			if err != nil {
+				return false, err
+			}
+			c.p.code[jumpEnd].a = int32(c.pc())
+			return condPure && thenPure && elsePure, nil
+		}
+	case *parser2.Unary:
+		pure, err := c.compileWrapped(a.Value, a.Line, "error in unary %v", a.Operator)
+		if err != nil {
+			return false, err
+		}
+		c.p.unary = append(c.p.unary, g.uMap[a.Operator].Impl)
+		c.emit(opUnary, len(c.p.unary)-1, 0)
+		return pure, nil
+	case *parser2.Operate:
+		if o, ok := c.operation(a); ok {
+			c.p.opers = append(c.p.opers, o)
+			index := len(c.p.opers) - 1
+			switch {
+			case o.a.kind == operandTemp:
+				pure, err := c.compileWrapped(a.A, a.Line, "error in operation %v", a.Operator)
+				if err != nil {
+					return false, err
+				}
+				c.emit(opOperateRight, index, 0)
+				return pure, nil
+			case o.b.kind == operandTemp:
+				pure, err := c.compileWrapped(a.B, a.Line, "error in operation %v", a.Operator)
+				if err != nil {
+					return false, err
+				}
+				c.emit(opOperateLeft, index, 0)
+				return pure, nil
+			default:
+				c.emit(opOperateBoth, index, 0)
+				c.push()
+				return true, nil
+			}
+		}
+		pure, err := c.compileList([]parser2.AST{a.A, a.B}, a.Line, "error in operation %v", a.Operator)
+		if err != nil {
+			return false, err
+		}
+		c.p.ops = append(c.p.ops, g.opMap[a.Operator].Impl)
+		c.emit(opOperate, len(c.p.ops)-1, 0)
+		c.drop(1)
+		return pure, nil
+	case *parser2.ListLiteral:
+		if g.listHandler != nil {
+			pure, err := c.compileList(a.List, a.Line, "List literal error")
+			if err != nil {
+				return false, err
+			}
+			c.emit(opList, len(a.List), 0)
+			c.drop(len(a.List))
+			c.push()
+			return pure, nil
+		}
+	case *parser2.ListAccess:
+		if g.listHandler != nil {
+			indexPure, err := c.compileWrapped(a.Index, a.Line, "error in list index")
+			if err != nil {
+				return false, err
+			}
+			listPure, err := c.compileWrapped(a.List, a.Line, "error in getting list")
+			if err != nil {
+				return false, err
+			}
+			c.emit(opListAccess, 0, 0)
+			c.drop(1)
+			return indexPure && listPure, nil
+		}
+	case *parser2.MapLiteral:
+		if g.mapHandler != nil {
+			start := c.pc()
+			var ml mapLiteral
+			pure := true
+			var err error
+			a.Map.Iter(func(key string, v parser2.AST) bool {
+				if o, ok := c.operand(v); ok {
+					ml.entries = append(ml.entries, mapEntry{key: key, direct: true, value: o})
+					return true
+				}
+				var p bool
+				p, err = c.compile(v)
+				pure = pure && p
+				ml.entries = append(ml.entries, mapEntry{key: key})
+				ml.pushed++
+				return err == nil
+			})
+			if err != nil {
+				return false, err
+			}
+			c.p.maps = append(c.p.maps, ml)
+			c.emit(opMap, len(c.p.maps)-1, 0)
+			c.wrap(start, a.Line, "Map literal error")
+			c.drop(ml.pushed)
+			c.push()
+			return pure, nil
+		}
+	case *parser2.MapAccess:
+		if g.mapHandler != nil && (!a.Optional || c.p.nh != nil) {
+			if id, ok := a.MapValue.(*parser2.Ident); ok && !a.Optional {
+				if index, ok := c.slots.get(id.Name); ok {
+					c.p.keys = append(c.p.keys, a.Key)
+					c.emit(opLoadMapAccess, index, len(c.p.keys)-1)
+					c.push()
+					return true, nil
+				}
+			}
+			pure, err := c.compileWrapped(a.MapValue, a.Line, "error in getting map")
+			if err != nil {
+				return false, err
+			}
+			c.p.keys = append(c.p.keys, a.Key)
+			if a.Optional {
+				c.emit(opMapAccessOptional, len(c.p.keys)-1, 0)
+			} else {
+				c.emit(opMapAccess, len(c.p.keys)-1, 0)
+			}
+			return pure, nil
+		}
+	case *parser2.FunctionCall:
+		if ok, pure, err := c.compileCall(a); ok || err != nil {
+			return pure, err
+		}
+	case *parser2.MethodCall:
+		if !a.Optional || c.p.nh != nil {
+			return c.compileMethodCall(a)
+		}
+	}
+	return c.fallback(ast)
+}
+
+// operand returns the operand which is read directly by an operation, if
+// the given AST is a constant, a local variable or the value of a key of a
+// map stored in a local variable. In this case the value needs not to be pushed.
+func (c *compiler[V]) operand(ast parser2.AST) (operand, bool) {
+	switch a := ast.(type) {
+	case *parser2.Const[V]:
+		c.p.consts = append(c.p.consts, a.Value)
+		return operand{kind: operandConst, index: len(c.p.consts) - 1}, true
+	case *parser2.Ident:
+		if index, ok := c.slots.get(a.Name); ok {
+			return operand{kind: operandLocal, index: index}, true
+		}
+	case *parser2.MapAccess:
+		if id, ok := a.MapValue.(*parser2.Ident); ok && c.g.mapHandler != nil && !a.Optional {
+			if index, ok := c.slots.get(id.Name); ok {
+				return operand{kind: operandLocalKey, index: index, key: a.Key}, true
+			}
+		}
+	}
+	return operand{}, false
+}
+
+// operation returns the operation of the given AST, if the AST is an
+// operation which reads at least one of its operands directly
+func (c *compiler[V]) operation(ast parser2.AST) (operation[V], bool) {
+	a, ok := ast.(*parser2.Operate)
+	if !ok {
+		return operation[V]{}, false
+	}
+	aOp, aDirect := c.operand(a.A)
+	bOp, bDirect := c.operand(a.B)
+	if !aDirect && !bDirect {
+		return operation[V]{}, false
+	}
+	return operation[V]{impl: c.g.opMap[a.Operator].Impl, a: aOp, b: bOp, node: a}, true
+}
+
+// compileCall compiles a function call. If the call is not
+// supported by the compiler, false is returned.
+func (c *compiler[V]) compileCall(a *parser2.FunctionCall) (bool, bool, error) {
+	g := c.g
+	n := len(a.Args)
+	if id, ok := a.Func.(*parser2.Ident); ok {
+		if fun, ok := g.staticFunctions[id.Name]; ok {
+			if a.ArgNames != nil || fun.argsNumberNotMatching(n) {
+				return false, false, nil
+			}
+			pure, err := c.compileList(a.Args, a.Line, "error in function call to %s", id.Name)
+			if err != nil {
+				return true, false, err
+			}
+			c.p.static = append(c.p.static, fun)
+			c.emit(opCallStatic, len(c.p.static)-1, n)
+			c.drop(n)
+			c.push()
+			return true, fun.IsPure && pure, nil
+		}
+		if _, isLocal := c.slots.get(id.Name); c.base.tailCalls[a] && !isLocal {
+			pure, err := c.compileList(a.Args, a.Line, "error in arguments in function call to %v", a.Func)
+			if err != nil {
+				return true, false, err
+			}
+			c.emit(opTailCall, n, 0)
+			c.drop(n)
+			c.push()
+			return true, pure, nil
+		}
+	}
+	if a.ArgNames != nil {
+		return false, false, nil
+	}
+	start := c.pc()
+	funcPure, err := c.compile(a.Func)
+	if err != nil {
+		return true, false, g.generateStaticFunctionDocu(err)
+	}
+	c.wrap(start, a.Line, "error in getting function")
+	node := c.node(a)
+	c.emit(opCheckFunc, n, node)
+	c.drop(1)
+	c.resolved()
+	argsPure, err := c.compileList(a.Args, a.Line, "error in arguments in function call to %v", a.Func)
+	if err != nil {
+		return true, false, err
+	}
+	c.emit(opCall, n, node)
+	c.calls--
+	c.drop(n)
+	c.push()
+	return true, funcPure && argsPure, nil
+}
+
+func (c *compiler[V]) compileMethodCall(a *parser2.MethodCall) (bool, error) {
+	valuePure, err := c.compileWrapped(a.Value, a.Line, "error in method call to %s", a.Name)
+	if err != nil {
+		return false, err
+	}
+	m := methodCall[V]{node: a, args: len(a.Args)}
+	if a.Optional {
+		m.nh = c.p.nh
+	}
+	c.p.methods = append(c.p.methods, m)
+	index := len(c.p.methods) - 1
+	resolve := c.emit(opMethod, index, 0)
+	c.resolved()
+	argsPure, err := c.compileList(a.Args, a.Line, "error in arguments in method call to %s", a.Name)
+	if err != nil {
+		return false, err
+	}
+	c.emit(opInvoke, index, 0)
+	c.calls--
+	c.p.code[resolve].b = int32(c.pc())
+	c.drop(len(a.Args) + 1)
+	c.push()
+	return valuePure && argsPure, nil
+}
+
+// createMap creates the map of the given map literal,
+// the values popped by opMap are given in pushed
+func (p *program[V]) createMap(st Stack[V], ml *mapLiteral, pushed []V) (listMap.ListMap[V], error) {
+	m := listMap.New[V](len(ml.entries))
+	for i := range ml.entries {
+		e := &ml.entries[i]
+		if e.direct {
+			v, err := p.operand(st, &e.value)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Append(e.key, v)
+		} else {
+			m = m.Append(e.key, pushed[0])
+			pushed = pushed[1:]
+		}
+	}
+	return m, nil
+}
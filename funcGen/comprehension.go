@@ -0,0 +1,188 @@
+package funcGen
+
+import (
+	"github.com/hneemann/iterator"
+	"github.com/hneemann/parser2"
+)
+
+// generateRange creates the function of a range like "0..100 step 5"
+func (g *FunctionGenerator[V]) generateRange(a *parser2.Range, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	lg, ok := g.listHandler.(ListGenerator[V])
+	if !ok {
+		return nil, false, a.Errorf("ranges not supported")
+	}
+	fromFunc, fromPure, err := g.GenerateFunc(a.From, gc)
+	if err != nil {
+		return nil, false, err
+	}
+	toFunc, toPure, err := g.GenerateFunc(a.To, gc)
+	if err != nil {
+		return nil, false, err
+	}
+	stepFunc, stepPure := ParserFunc[V](nil), true
+	if a.Step != nil {
+		stepFunc, stepPure, err = g.GenerateFunc(a.Step, gc)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return func(st Stack[V], cs []V) (V, error) {
+		var zero V
+		from, err := fromFunc(st, cs)
+		if err != nil {
+			return zero, a.EnhanceErrorf(err, "error in range")
+		}
+		to, err := toFunc(st, cs)
+		if err != nil {
+			return zero, a.EnhanceErrorf(err, "error in range")
+		}
+		var step V
+		if stepFunc != nil {
+			step, err = stepFunc(st, cs)
+			if err != nil {
+				return zero, a.EnhanceErrorf(err, "error in range")
+			}
+		}
+		r, err := lg.Range(from, to, step, stepFunc != nil)
+		if err != nil {
+			return zero, a.EnhanceErrorf(err, "error in range")
+		}
+		return r, nil
+	}, fromPure && toPure && stepPure, nil
+}
+
+// generateComprehension creates the function of a list comprehension like
+// "[x*y for x in a for y in b if x<y]". The list of the first generator is
+// evaluated if the comprehension is created. All other parts are evaluated
+// while the created list is iterated. Therefore, they access the variables
+// of the generators and the outer values through the context.
+func (g *FunctionGenerator[V]) generateComprehension(a *parser2.ListComprehension, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	lg, ok := g.listHandler.(ListGenerator[V])
+	if !ok {
+		return nil, false, a.Errorf("list comprehensions not supported")
+	}
+	firstFunc, pure, err := g.GenerateFunc(a.Generators[0].List, gc)
+	if err != nil {
+		return nil, false, err
+	}
+	access, err := createContextAccess[V](a.OuterIdents, len(a.OuterIdents), gc, nil, a.Line)
+	if err != nil {
+		return nil, false, err
+	}
+
+	context := argsList(a.OuterIdents)
+	for _, gen := range a.Generators {
+		context, err = context.copyAndAdd(gen.Name)
+		if err != nil {
+			return nil, false, a.EnhanceErrorf(err, "error in list comprehension")
+		}
+	}
+	innerGc := gc.closureContext(nil, context)
+
+	valueFunc, valuePure, err := g.GenerateFunc(a.Value, innerGc)
+	if err != nil {
+		return nil, false, err
+	}
+	pure = pure && valuePure
+	listFuncs := make([]ParserFunc[V], len(a.Generators))
+	condFuncs := make([]ParserFunc[V], len(a.Generators))
+	for i, gen := range a.Generators {
+		if i > 0 {
+			f, p, err := g.GenerateFunc(gen.List, innerGc)
+			if err != nil {
+				return nil, false, err
+			}
+			listFuncs[i] = f
+			pure = pure && p
+		}
+		if gen.Cond != nil {
+			if g.toBool == nil {
+				return nil, false, gen.Cond.GetLine().Errorf("conditions not supported")
+			}
+			f, p, err := g.GenerateFunc(gen.Cond, innerGc)
+			if err != nil {
+				return nil, false, err
+			}
+			condFuncs[i] = f
+			pure = pure && p
+		}
+	}
+
+	offs := len(a.OuterIdents)
+	last := len(a.Generators) - 1
+	var zero V
+	return func(st Stack[V], cs []V) (V, error) {
+		first, err := firstFunc(st, cs)
+		if err != nil {
+			return zero, a.EnhanceErrorf(err, "error in list comprehension")
+		}
+		firstItems, size, ok := lg.Items(first)
+		if !ok {
+			return zero, a.Generators[0].List.GetLine().Errorf("for requires a list")
+		}
+		if last > 0 || condFuncs[0] != nil {
+			size = -1
+		}
+		outer := make([]V, len(access))
+		for i, acc := range access {
+			outer[i] = acc(st, cs, zero, nil)
+		}
+
+		return lg.FromProducer(func(st Stack[V]) iterator.Producer[V] {
+			return func(yield iterator.Consumer[V]) {
+				fr := st.CreateFrame(0)
+				ctx := make([]V, len(context))
+				copy(ctx, outer)
+				var iterate func(level int, items iterator.Producer[V]) bool
+				iterate = func(level int, items iterator.Producer[V]) bool {
+					for item, err := range items {
+						if err != nil {
+							yield(zero, err)
+							return false
+						}
+						ctx[offs+level] = item
+						if condFunc := condFuncs[level]; condFunc != nil {
+							c, err := condFunc(fr, ctx)
+							if err != nil {
+								yield(zero, a.EnhanceErrorf(err, "error in list comprehension"))
+								return false
+							}
+							if b, ok := g.toBool(c); !ok {
+								yield(zero, a.Generators[level].Cond.GetLine().Errorf("condition is not a bool"))
+								return false
+							} else if !b {
+								continue
+							}
+						}
+						if level == last {
+							v, err := valueFunc(fr, ctx)
+							if err != nil {
+								yield(zero, a.EnhanceErrorf(err, "error in list comprehension"))
+								return false
+							}
+							if !yield(v, nil) {
+								return false
+							}
+						} else {
+							list, err := listFuncs[level+1](fr, ctx)
+							if err != nil {
+								yield(zero, a.EnhanceErrorf(err, "error in list comprehension"))
+								return false
+							}
+							listItems, _, ok := lg.Items(list)
+							if !ok {
+								yield(zero, a.Generators[level+1].List.GetLine().Errorf("for requires a list"))
+								return false
+							}
+							if !iterate(level+1, listItems(fr)) {
+								return false
+							}
+						}
+					}
+					return true
+				}
+				iterate(0, firstItems(fr))
+			}
+		}, size), nil
+	}, pure, nil
+}
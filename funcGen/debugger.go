@@ -0,0 +1,201 @@
+package funcGen
+
+import (
+	"errors"
+	"github.com/hneemann/parser2"
+	"sync"
+)
+
+// DebugKind is the kind of node the evaluation has entered
+type DebugKind int
+
+const (
+	// DebugLet is used if a let is entered
+	DebugLet DebugKind = iota
+	// DebugFunctionCall is used if a function call is entered
+	DebugFunctionCall
+	// DebugMethodCall is used if a method call is entered
+	DebugMethodCall
+	// DebugClosure is used if a closure is invoked
+	DebugClosure
+)
+
+func (k DebugKind) String() string {
+	switch k {
+	case DebugLet:
+		return "let"
+	case DebugFunctionCall:
+		return "function call"
+	case DebugMethodCall:
+		return "method call"
+	default:
+		return "closure"
+	}
+}
+
+// DebugAction defines how the evaluation continues after the debugger has stopped it
+type DebugAction int
+
+const (
+	// DebugStepInto stops at the next event
+	DebugStepInto DebugAction = iota
+	// DebugStepOver stops at the next event which is not inside of a
+	// closure invoked after the current event
+	DebugStepOver
+	// DebugContinue stops only if Debugger.Break returns true
+	DebugContinue
+	// DebugAbort aborts the evaluation with ErrDebugAbort
+	DebugAbort
+)
+
+// ErrDebugAbort is returned if the evaluation is aborted by the debugger
+var ErrDebugAbort = errors.New("evaluation aborted by debugger")
+
+// DebugEvent describes the position at which the evaluation is stopped
+type DebugEvent[V any] struct {
+	Kind DebugKind
+	// AST is the node which is entered
+	AST parser2.AST
+	parser2.Line
+	// Depth is the number of closures invoked, zero at top level
+	Depth int
+	// Names contains the names of the visible variables
+	Names []string
+	// Values contains the values of the visible variables
+	Values []V
+}
+
+// Debugger is used to step through the evaluation of a function.
+// The debugger is called on entering each let, function call,
+// method call and closure invocation. The evaluation starts
+// in the DebugStepInto mode, so the debugger is stopped at the
+// first event.
+type Debugger[V any] interface {
+	// Break is called in the DebugContinue mode and also for the events
+	// skipped by DebugStepOver. If true is returned, the evaluation is stopped.
+	// It is used to implement breakpoints or to pause a running evaluation.
+	Break(line parser2.Line) bool
+	// Stopped is called if the evaluation is stopped. The evaluation
+	// is paused until this method returns. The returned action defines
+	// how the evaluation continues.
+	Stopped(ev DebugEvent[V]) DebugAction
+}
+
+// SetDebugger enables the debug mode. In debug mode the generated code calls
+// the given debugger. The debugger requires the tree backend, so generating
+// a function fails if another backend is selected. Functions
+// generated before the debugger is set are not affected. Each call of a
+// function returned by Generate starts a new debug session.
+func (g *FunctionGenerator[V]) SetDebugger(debugger Debugger[V]) *FunctionGenerator[V] {
+	g.debugger = debugger
+	return g
+}
+
+// IsAborted returns true if the given error is caused by an aborted evaluation.
+// Such errors are not to be caught by the evaluated code.
+func IsAborted(err error) bool {
+	var le *LimitError
+	return errors.As(err, &le) || errors.Is(err, ErrDebugAbort)
+}
+
+// debugSession is the state of the debugger shared by all stacks used in
+// the same evaluation. It is created if a function returned by Generate
+// is called. Stacks without a session, like the stack used by the
+// optimizer, are not debugged.
+type debugSession struct {
+	mutex sync.Mutex
+	mode  DebugAction
+	// depth is the depth at which the last step over was requested
+	depth int
+}
+
+// debugGenerate creates the function of the given AST and adds
+// the calls of the debugger
+func (g *FunctionGenerator[V]) debugGenerate(ast parser2.AST, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	fu, pure, err := g.generateTree(ast, gc)
+	if err != nil {
+		return nil, false, err
+	}
+	var kind DebugKind
+	switch ast.(type) {
+	case *parser2.Let:
+		kind = DebugLet
+	case *parser2.FunctionCall:
+		kind = DebugFunctionCall
+	case *parser2.MethodCall:
+		kind = DebugMethodCall
+	default:
+		return fu, pure, nil
+	}
+	return func(st Stack[V], cs []V) (V, error) {
+		if err := g.debugEvent(kind, ast, gc, st, cs); err != nil {
+			var zero V
+			return zero, err
+		}
+		return fu(st, cs)
+	}, pure, nil
+}
+
+// debugClosure adds the call of the debugger to the body of a closure
+// if the debug mode is enabled.
+func (g *FunctionGenerator[V]) debugClosure(a *parser2.ClosureLiteral, gc GeneratorContext, fu ParserFunc[V]) ParserFunc[V] {
+	if g.debugger == nil {
+		return fu
+	}
+	return func(st Stack[V], cs []V) (V, error) {
+		st.storage.depth++
+		defer func() { st.storage.depth-- }()
+		if err := g.debugEvent(DebugClosure, a, gc, st, cs); err != nil {
+			var zero V
+			return zero, err
+		}
+		return fu(st, cs)
+	}
+}
+
+// debugEvent calls the debugger if the evaluation is to be stopped
+func (g *FunctionGenerator[V]) debugEvent(kind DebugKind, ast parser2.AST, gc GeneratorContext, st Stack[V], cs []V) error {
+	s := st.storage.debug
+	if s == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	line := ast.GetLine()
+	depth := st.storage.depth
+	switch s.mode {
+	case DebugAbort:
+		return ErrDebugAbort
+	case DebugContinue:
+		if !g.debugger.Break(line) {
+			return nil
+		}
+	case DebugStepOver:
+		if depth > s.depth && !g.debugger.Break(line) {
+			return nil
+		}
+	}
+
+	ev := DebugEvent[V]{Kind: kind, AST: ast, Line: line, Depth: depth}
+	for i, n := range gc.am {
+		if n != "" && i < st.Size() {
+			ev.Names = append(ev.Names, n)
+			ev.Values = append(ev.Values, st.Get(i))
+		}
+	}
+	for i, n := range gc.cm {
+		if i < len(cs) {
+			ev.Names = append(ev.Names, n)
+			ev.Values = append(ev.Values, cs[i])
+		}
+	}
+
+	s.mode = g.debugger.Stopped(ev)
+	s.depth = depth
+	if s.mode == DebugAbort {
+		return ErrDebugAbort
+	}
+	return nil
+}
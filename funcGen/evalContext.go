@@ -0,0 +1,205 @@
+package funcGen
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// LimitKind describes why an evaluation was aborted
+type LimitKind int
+
+const (
+	// StepLimit is used if the maximum number of evaluation steps is exceeded
+	StepLimit LimitKind = iota
+	// ListItemLimit is used if the maximum number of list items is exceeded
+	ListItemLimit
+	// Canceled is used if the context.Context is canceled or its deadline is exceeded
+	Canceled
+	// MemoryLimit is used if the estimated memory allocated exceeds the maximum
+	MemoryLimit
+)
+
+// LimitError is returned if an evaluation is aborted by its EvalContext.
+// Use errors.As to detect it, also if it is wrapped by other errors.
+type LimitError struct {
+	Kind LimitKind
+	// Limit is the limit which was exceeded
+	Limit int64
+	// Cause is the error of the context.Context if the evaluation was canceled
+	Cause error
+}
+
+func (e *LimitError) Error() string {
+	switch e.Kind {
+	case StepLimit:
+		return fmt.Sprintf("evaluation aborted: more than %d steps", e.Limit)
+	case ListItemLimit:
+		return fmt.Sprintf("evaluation aborted: more than %d list items", e.Limit)
+	case MemoryLimit:
+		return fmt.Sprintf("evaluation aborted: memory limit exceeded, more than %d bytes allocated", e.Limit)
+	default:
+		return fmt.Sprintf("evaluation aborted: %v", e.Cause)
+	}
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Cause
+}
+
+// contextCheckInterval is the number of steps after which the
+// context.Context is checked
+const contextCheckInterval = 64
+
+// EvalContext limits the evaluation of a function. It is carried by the
+// stack and checked cooperatively: Every invocation of a closure, of a
+// static function or of a method is a step, and every item produced by
+// a list is counted. The context.Context is checked every few steps and
+// list items. The memory is accounted by the code which materializes
+// values like lists, maps or strings. It is an estimate of all memory
+// allocated during the evaluation; memory which becomes garbage is not
+// subtracted. An EvalContext can be used by several goroutines concurrently.
+// A limit of zero means there is no limit.
+type EvalContext struct {
+	ctx          context.Context
+	maxSteps     int64
+	maxListItems int64
+	maxMemory    int64
+	steps        atomic.Int64
+	listItems    atomic.Int64
+	memory       atomic.Int64
+}
+
+// NewEvalContext creates a new evaluation context. The evaluation is aborted
+// if the given context is canceled. The context may be nil.
+func NewEvalContext(ctx context.Context) *EvalContext {
+	return &EvalContext{ctx: ctx}
+}
+
+// SetMaxSteps sets the maximum number of evaluation steps
+func (e *EvalContext) SetMaxSteps(maxSteps int64) *EvalContext {
+	e.maxSteps = maxSteps
+	return e
+}
+
+// SetMaxListItems sets the maximum number of list items produced
+func (e *EvalContext) SetMaxListItems(maxListItems int64) *EvalContext {
+	e.maxListItems = maxListItems
+	return e
+}
+
+// SetMaxMemory sets the maximum number of bytes allocated
+func (e *EvalContext) SetMaxMemory(maxMemory int64) *EvalContext {
+	e.maxMemory = maxMemory
+	return e
+}
+
+// Steps returns the number of steps executed so far
+func (e *EvalContext) Steps() int64 {
+	return e.steps.Load()
+}
+
+// ListItems returns the number of list items produced so far
+func (e *EvalContext) ListItems() int64 {
+	return e.listItems.Load()
+}
+
+// Memory returns the estimated number of bytes allocated so far
+func (e *EvalContext) Memory() int64 {
+	return e.memory.Load()
+}
+
+func (e *EvalContext) step() error {
+	n := e.steps.Add(1)
+	if e.maxSteps > 0 && n > e.maxSteps {
+		return &LimitError{Kind: StepLimit, Limit: e.maxSteps}
+	}
+	if n%contextCheckInterval == 0 {
+		return e.checkContext()
+	}
+	return nil
+}
+
+func (e *EvalContext) listItem() error {
+	n := e.listItems.Add(1)
+	if e.maxListItems > 0 && n > e.maxListItems {
+		return &LimitError{Kind: ListItemLimit, Limit: e.maxListItems}
+	}
+	if n%contextCheckInterval == 0 {
+		return e.checkContext()
+	}
+	return nil
+}
+
+func (e *EvalContext) alloc(bytes int) error {
+	n := e.memory.Add(int64(bytes))
+	if e.maxMemory > 0 && n > e.maxMemory {
+		return &LimitError{Kind: MemoryLimit, Limit: e.maxMemory}
+	}
+	return nil
+}
+
+func (e *EvalContext) checkContext() error {
+	if e.ctx != nil {
+		if err := e.ctx.Err(); err != nil {
+			return &LimitError{Kind: Canceled, Cause: err}
+		}
+	}
+	return nil
+}
+
+// NewEmptyStackWithContext creates an empty stack which limits
+// the evaluation by the given evaluation context
+func NewEmptyStackWithContext[V any](ec *EvalContext) Stack[V] {
+	st := NewEmptyStack[V]()
+	st.storage.ec = ec
+	return st
+}
+
+// NewEmpty creates a new empty stack which shares the evaluation context,
+// the debugger state and the position in the call tree of the profiler with
+// this stack. It is used if a function is evaluated in a different goroutine.
+func (s Stack[V]) NewEmpty() Stack[V] {
+	st := NewEmptyStackWithContext[V](s.storage.ec)
+	st.storage.debug = s.storage.debug
+	st.storage.depth = s.storage.depth
+	st.storage.profile = s.storage.profile
+	return st
+}
+
+// HasEvalContext returns true if the evaluation is limited by an evaluation context
+func (s Stack[V]) HasEvalContext() bool {
+	return s.storage.ec != nil
+}
+
+// Step counts an evaluation step. An error is returned
+// if the evaluation is to be aborted.
+func (s Stack[V]) Step() error {
+	if ec := s.storage.ec; ec != nil {
+		return ec.step()
+	}
+	return nil
+}
+
+// ListItem counts a produced list item. An error is returned
+// if the evaluation is to be aborted.
+func (s Stack[V]) ListItem() error {
+	if ec := s.storage.ec; ec != nil {
+		return ec.listItem()
+	}
+	return nil
+}
+
+// Alloc accounts the given number of bytes allocated. An error
+// is returned if the memory limit is exceeded.
+func (s Stack[V]) Alloc(bytes int) error {
+	if ec := s.storage.ec; ec != nil {
+		return ec.alloc(bytes)
+	}
+	return nil
+}
+
+// EvalWith evaluates the function limited by the given evaluation context
+func (f Func[V]) EvalWith(ec *EvalContext, args ...V) (V, error) {
+	return f(NewEmptyStackWithContext[V](ec).Init(args...))
+}
@@ -2,19 +2,33 @@ package funcGen
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"github.com/hneemann/iterator"
 	"github.com/hneemann/parser2"
 	"github.com/hneemann/parser2/listMap"
 	"log"
 	"reflect"
 	"runtime/debug"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 )
 
 type stackStorage[V any] struct {
 	data []V
+	// ec limits the evaluation, nil if there are no limits
+	ec *EvalContext
+	// debug is the debugger state, nil if the debugger was not yet called
+	debug *debugSession
+	// depth is the number of closures invoked, only maintained in debug mode
+	depth int
+	// profile is the node of the call tree of the profiler which is
+	// currently evaluated, nil if there is none
+	profile *profileNode
 }
 
 func (s *stackStorage[V]) set(n int, v V) {
@@ -119,6 +133,8 @@ type Operator[V any] struct {
 	IsPure bool
 	// IsCommutative is true if the operation is commutative
 	IsCommutative bool
+	// Associativity defines how chains of this operator are grouped
+	Associativity parser2.Associativity
 }
 
 type UnaryOperatorImpl[V any] interface {
@@ -146,10 +162,20 @@ type UnaryOperator[V any] struct {
 type ParserFunc[V any] func(stack Stack[V], closureStore []V) (V, error)
 
 type FunctionDescription struct {
-	Args        []string
+	Args []string
+	// Defaults are the default values of the last arguments
+	Defaults    []string
 	Description string
 }
 
+// arg returns the i-th argument together with its default value
+func (f *FunctionDescription) arg(i int) string {
+	if d := i - (len(f.Args) - len(f.Defaults)); d >= 0 {
+		return f.Args[i] + "=" + f.Defaults[d]
+	}
+	return f.Args[i]
+}
+
 func (f *FunctionDescription) String(name string) string {
 	if f == nil {
 		return name
@@ -161,11 +187,11 @@ func (f *FunctionDescription) String(name string) string {
 
 func (f *FunctionDescription) StringArgs() string {
 	args := "("
-	for i, a := range f.Args {
+	for i := range f.Args {
 		if i > 0 {
 			args += ", "
 		}
-		args += a
+		args += f.arg(i)
 	}
 	return args + ")"
 }
@@ -178,11 +204,11 @@ func (f *FunctionDescription) WriteTo(b *bytes.Buffer, name string) {
 	}
 
 	b.WriteString("(")
-	for i, a := range f.Args {
+	for i := range f.Args {
 		if i > 0 {
 			b.WriteString(", ")
 		}
-		b.WriteString(a)
+		b.WriteString(f.arg(i))
 	}
 	b.WriteString(")\n\t")
 	pos := 0
@@ -231,6 +257,7 @@ func (f *FunctionDescription) addOptional(min int, max int) *FunctionDescription
 
 	return &FunctionDescription{
 		Args:        f.Args,
+		Defaults:    f.Defaults,
 		Description: f.Description + desc,
 	}
 }
@@ -247,6 +274,19 @@ type Function[V any] struct {
 	IsPure bool
 	// Description is a description of the function
 	Description *FunctionDescription
+	// Source is the closure literal the function is created from.
+	// It is set if the optimizer replaces a closure literal by a constant.
+	Source *parser2.ClosureLiteral
+	// Defaults are the default values of the last arguments.
+	// If there are fewer arguments given, the missing ones are
+	// set to their default values.
+	Defaults []V
+	// ArgNames are the names of the arguments which are used to resolve
+	// named arguments. If not set, the names given in the description are used.
+	ArgNames []string
+	// Result is the name of the type of the result. It is used by
+	// static type checks. Empty if the result type is not declared.
+	Result string
 }
 
 type FunctionDocumentation struct {
@@ -283,6 +323,12 @@ func CreateTypeDocumentation[V any](typeDescription TypeDescription, mm map[stri
 	}
 }
 
+// SetResult sets the name of the type of the result
+func (f Function[V]) SetResult(result string) Function[V] {
+	f.Result = result
+	return f
+}
+
 func (f Function[V]) Pure(pure bool) Function[V] {
 	f.IsPure = pure
 	return f
@@ -304,6 +350,7 @@ func (f Function[V]) VarArgs(min, max int) Function[V] {
 		Args:        -1,
 		IsPure:      f.IsPure,
 		Description: f.Description.addOptional(min, max),
+		Result:      f.Result,
 	}
 }
 
@@ -323,6 +370,7 @@ func (f Function[V]) VarArgsMethod(min, max int) Function[V] {
 		Args:        -1,
 		IsPure:      f.IsPure,
 		Description: f.Description.addOptional(min, max),
+		Result:      f.Result,
 	}
 }
 
@@ -366,20 +414,136 @@ func (f Function[V]) EvalSt(st Stack[V], a ...V) (V, error) {
 	return f.Func(st.CreateFrame(len(a)), nil)
 }
 
+// SetDefaults sets the default values of the last arguments
+// of the function. The function has to have a fixed number of arguments.
+func (f Function[V]) SetDefaults(defaults ...V) Function[V] {
+	if f.Args < len(defaults) {
+		panic(fmt.Errorf("%d defaults given, but there are only %d arguments", len(defaults), f.Args))
+	}
+	if f.Description != nil {
+		d := *f.Description
+		d.Defaults = make([]string, len(defaults))
+		for i, v := range defaults {
+			d.Defaults[i] = fmt.Sprint(v)
+		}
+		f.Description = &d
+	}
+	f.Defaults = defaults
+	f.Func = fillDefaults(f.Func, f.Args, defaults)
+	return f
+}
+
+// fillDefaults returns a function which sets the missing
+// arguments to their default values.
+func fillDefaults[V any](fu ParserFunc[V], args int, defaults []V) ParserFunc[V] {
+	first := args - len(defaults)
+	return func(st Stack[V], cs []V) (V, error) {
+		n := st.Size()
+		if n < first || n > args {
+			var zero V
+			return zero, fmt.Errorf("wrong number of arguments, required %d to %d, found %d", first, args, n)
+		}
+		for ; n < args; n++ {
+			st.Push(defaults[n-first])
+		}
+		return fu(st, cs)
+	}
+}
+
+// minArgs returns the minimal number of arguments
+func (f Function[V]) minArgs() int {
+	return f.Args - len(f.Defaults)
+}
+
 func (f Function[V]) argsNumberNotMatching(available int) bool {
-	return f.Args >= 0 && f.Args != available
+	return f.Args >= 0 && (available > f.Args || available < f.minArgs())
 }
 
 func (f Function[V]) argsNumberNotMatchingError(name string, available int) string {
+	if len(f.Defaults) > 0 {
+		return fmt.Sprintf("wrong number of arguments at call of \"%s\", required %d to %d, found %d", f.Description.String(name), f.minArgs(), f.Args, available)
+	}
 	return fmt.Sprintf("wrong number of arguments at call of \"%s\", required %d, found %d", f.Description.String(name), f.Args, available)
 }
 
+// argNames returns the names of the arguments or nil if they are not known
+func (f Function[V]) argNames() []string {
+	if f.ArgNames != nil {
+		return f.ArgNames
+	}
+	if f.Description != nil {
+		return f.Description.Args
+	}
+	return nil
+}
+
+// arrangeArgs maps the given arguments to the arguments of the function.
+// The names are the names of the given arguments with an empty string for
+// the positional arguments. For each argument of the function, the index
+// of the given argument is returned or -1 if the default value is to be used.
+// Missing trailing arguments are omitted.
+func (f Function[V]) arrangeArgs(names []string) ([]int, error) {
+	params := f.argNames()
+	if params == nil {
+		return nil, errors.New("function does not support named arguments")
+	}
+	pos := make([]int, len(params))
+	for i := range pos {
+		pos[i] = -1
+	}
+	last := -1
+	for i, n := range names {
+		j := i
+		if n == "" {
+			if i >= len(params) {
+				return nil, fmt.Errorf("too many arguments, required %d, found %d", len(params), len(names))
+			}
+		} else {
+			j = slices.Index(params, n)
+			if j < 0 {
+				return nil, fmt.Errorf("function has no argument '%s'; available are: %s", n, strings.Join(params, ", "))
+			}
+			if pos[j] >= 0 {
+				return nil, fmt.Errorf("argument '%s' given twice", n)
+			}
+		}
+		pos[j] = i
+		last = max(last, j)
+	}
+	first := len(params) - len(f.Defaults)
+	missing := last + 1
+	if f.Args >= 0 && missing < f.minArgs() {
+		return nil, fmt.Errorf("argument '%s' is missing", params[missing])
+	}
+	for j := 0; j <= last; j++ {
+		if pos[j] < 0 && j < first {
+			return nil, fmt.Errorf("argument '%s' is missing", params[j])
+		}
+	}
+	return pos[:last+1], nil
+}
+
+// pushArranged pushes the given arguments to the stack in the order
+// given by arrangeArgs. It returns the number of pushed values.
+func (f Function[V]) pushArranged(st *Stack[V], pos []int, args []V) int {
+	first := len(f.argNames()) - len(f.Defaults)
+	for j, p := range pos {
+		if p >= 0 {
+			st.Push(args[p])
+		} else {
+			st.Push(f.Defaults[j-first])
+		}
+	}
+	return len(pos)
+}
+
 // ListHandler is used to create and access lists or arrays
 type ListHandler[V any] interface {
 	// FromList is used to convert a list to a value
 	FromList(items []V) V
-	// AccessList is used to get a value from a list
-	AccessList(list V, index V) (V, error)
+	// AccessList is used to get a value from a list.
+	// The stack carries the EvalContext of the caller.
+	AccessList(st Stack[V], list V, index V) (V, error)
 }
 
 // MapHandler is used to create and access maps
@@ -392,6 +556,45 @@ type MapHandler[V any] interface {
 	IsMap(value V) bool
 }
 
+// StringJoiner is implemented by the string converter if string
+// interpolation like "a=${a}" is supported. It converts the given
+// values to strings and concatenates them.
+type StringJoiner[V any] interface {
+	JoinStrings(st Stack[V], values []V) (V, error)
+}
+
+// ListDestructor is implemented by the list handler if lists can
+// be destructured like in "let [a, b] = list;". ListItems returns
+// all items of the given list and false if the value is not a list.
+type ListDestructor[V any] interface {
+	ListItems(st Stack[V], list V) ([]V, bool, error)
+}
+
+// ListGenerator is implemented by the list handler if ranges like "1..10" and
+// list comprehensions like "[x*x for x in list if x>3]" are supported. Both
+// create lazy lists, which means the items are created while the list is iterated.
+type ListGenerator[V any] interface {
+	// Range returns the list of numbers from 'from' up to and including 'to'.
+	// If hasStep is false, no step is given and step is to be ignored.
+	Range(from, to, step V, hasStep bool) (V, error)
+	// FromProducer creates a lazy list. The size is -1 if it is not known.
+	FromProducer(producer func(st Stack[V]) iterator.Producer[V], size int) V
+	// Items returns the producer of the items of the given list and its
+	// size which is -1 if it is not known. False is returned if the value
+	// is not a list.
+	Items(list V) (func(st Stack[V]) iterator.Producer[V], int, bool)
+}
+
+// NilHandler is implemented by the map handler if there is a nil value
+// which is required by the optional chaining "m?.a". Such an access
+// returns nil if the map is nil or if the key is not present in the map.
+type NilHandler[V any] interface {
+	// Nil returns the nil value
+	Nil() V
+	// IsNil returns true if the given value is nil
+	IsNil(v V) bool
+}
+
 // ClosureHandler is used to convert closures
 type ClosureHandler[V any] interface {
 	// FromClosure is used to convert a closure to a value
@@ -428,6 +631,7 @@ type FunctionGenerator[V any] struct {
 	operators       []Operator[V]
 	unary           []UnaryOperator[V]
 	numberParser    parser2.NumberParser[V]
+	numberMatcher   parser2.Matcher
 	keyWords        []string
 	stringHandler   parser2.StringConverter[V]
 	listHandler     ListHandler[V]
@@ -438,11 +642,18 @@ type FunctionGenerator[V any] struct {
 	identifier      parser2.Identifiers[V]
 	toBool          ToBool[V]
 	isEqual         BoolFunc[V]
+	typeTests       map[string]func(V) bool
 	staticFunctions map[string]Function[V]
 	opMap           map[string]Operator[V]
 	uMap            map[string]UnaryOperator[V]
 	customGenerator Generator[V]
 	comfort         bool
+	moduleResolver  ModuleResolver
+	modulesMutex    sync.Mutex
+	modules         map[string]module[V]
+	backend         Backend
+	debugger        Debugger[V]
+	profiler        *Profiler
 }
 
 // New creates a new FunctionGenerator
@@ -463,6 +674,16 @@ func (g *FunctionGenerator[V]) SetNumberParser(numberParser parser2.NumberParser
 	return g
 }
 
+// SetNumberMatcher sets the matcher used to detect numbers. If not set,
+// the default matcher of the parser is used.
+func (g *FunctionGenerator[V]) SetNumberMatcher(numberMatcher parser2.Matcher) *FunctionGenerator[V] {
+	if g.parser != nil {
+		panic("parser already created")
+	}
+	g.numberMatcher = numberMatcher
+	return g
+}
+
 func (g *FunctionGenerator[V]) SetKeyWords(keyWords ...string) *FunctionGenerator[V] {
 	if g.parser != nil {
 		panic("parser already created")
@@ -517,6 +738,16 @@ func (g *FunctionGenerator[V]) SetIsEqual(isEqual BoolFunc[V]) *FunctionGenerato
 	return g
 }
 
+// AddTypeTest adds a type which can be used in the match
+// cases of a switch like in "switch v match int: v+1 default 0"
+func (g *FunctionGenerator[V]) AddTypeTest(name string, test func(V) bool) *FunctionGenerator[V] {
+	if g.typeTests == nil {
+		g.typeTests = map[string]func(V) bool{}
+	}
+	g.typeTests[name] = test
+	return g
+}
+
 func (g *FunctionGenerator[V]) AddUnaryFunc(operator string, impl UnaryOperatorFunc[V]) *FunctionGenerator[V] {
 	return g.AddUnary(operator, impl)
 }
@@ -576,10 +807,17 @@ func (g *FunctionGenerator[V]) AddOpPure(operator string, isCommutative bool, im
 	return g.AddOpBehind("", operator, isCommutative, impl, isPure)
 }
 
-// AddOpBehind adds an operation to the generator.
+// AddOpBehind adds a left associative operation to the generator.
 // Adds the new operator right behind the given existing operator in the priority list.
 // If the given operator is an empty string, the new operator is added at the end.
 func (g *FunctionGenerator[V]) AddOpBehind(behindOperator, newOperator string, isCommutative bool, impl OperatorImpl[V], isPure bool) *FunctionGenerator[V] {
+	return g.AddOpBehindAssoc(behindOperator, newOperator, isCommutative, impl, isPure, parser2.LeftAssociative)
+}
+
+// AddOpBehindAssoc adds an operation with the given associativity to the generator.
+// Adds the new operator right behind the given existing operator in the priority list.
+// If the given operator is an empty string, the new operator is added at the end.
+func (g *FunctionGenerator[V]) AddOpBehindAssoc(behindOperator, newOperator string, isCommutative bool, impl OperatorImpl[V], isPure bool, associativity parser2.Associativity) *FunctionGenerator[V] {
 	if g.parser != nil {
 		panic("parser already created")
 	}
@@ -595,6 +833,7 @@ func (g *FunctionGenerator[V]) AddOpBehind(behindOperator, newOperator string, i
 		Impl:          impl,
 		IsPure:        isPure,
 		IsCommutative: isCommutative,
+		Associativity: associativity,
 	}
 
 	if behindOperator == "" {
@@ -662,6 +901,12 @@ func (g *FunctionGenerator[V]) AddStaticFunction(n string, f Function[V]) *Funct
 	return g
 }
 
+// GetStaticFunction returns the static function with the given name
+func (g *FunctionGenerator[V]) GetStaticFunction(n string) (Function[V], bool) {
+	f, ok := g.staticFunctions[n]
+	return f, ok
+}
+
 func (g *FunctionGenerator[V]) EnhanceStaticFunction(n string, f func(Function[V]) Function[V]) *FunctionGenerator[V] {
 	oldFunc := g.staticFunctions[n]
 	if oldFunc.Func == nil {
@@ -671,6 +916,9 @@ func (g *FunctionGenerator[V]) EnhanceStaticFunction(n string, f func(Function[V
 	if newFunc.Description == nil {
 		newFunc.Description = oldFunc.Description
 	}
+	if newFunc.Result == "" {
+		newFunc.Result = oldFunc.Result
+	}
 	g.staticFunctions[n] = newFunc
 	return g
 }
@@ -697,11 +945,18 @@ func (g *FunctionGenerator[V]) GetParser() *parser2.Parser[V] {
 			SetKeyWords(g.keyWords...).
 			SetStringConverter(g.stringHandler).
 			SetOptimizer(g.optimizer).
+			SetTypeNames(func(name string) bool {
+				_, ok := g.typeTests[name]
+				return ok
+			}).
 			Comfort(g.comfort)
+		if g.numberMatcher != nil {
+			parser.SetNumberMatcher(g.numberMatcher)
+		}
 
 		opMap := map[string]Operator[V]{}
 		for _, o := range g.operators {
-			parser.Op(o.Operator)
+			parser.OpAssoc(o.Associativity, o.Operator)
 			opMap[o.Operator] = o
 		}
 		uMap := map[string]UnaryOperator[V]{}
@@ -765,6 +1020,11 @@ func (am argsList) copyAndAdd(name string) (argsList, error) {
 type GeneratorContext struct {
 	am argsList
 	cm argsList
+	// tailCalls are the self tail calls of the closure whose body is created
+	tailCalls map[*parser2.FunctionCall]bool
+	// importing contains the names of the modules which are imported
+	// at the moment. It is used to detect import cycles.
+	importing []string
 }
 
 func (c GeneratorContext) addLocalVar(name string) (GeneratorContext, error) {
@@ -772,7 +1032,14 @@ func (c GeneratorContext) addLocalVar(name string) (GeneratorContext, error) {
 	if err != nil {
 		return GeneratorContext{}, err
 	}
-	return GeneratorContext{am: newAm, cm: c.cm}, nil
+	c.am = newAm
+	return c, nil
+}
+
+// closureContext returns the context used to create the body of a
+// closure with the given arguments and the given closure context
+func (c GeneratorContext) closureContext(am, cm argsList) GeneratorContext {
+	return GeneratorContext{am: am, cm: cm, importing: c.importing}
 }
 
 type Func[V any] func(Stack[V]) (V, error)
@@ -806,14 +1073,24 @@ func (g *FunctionGenerator[V]) generateIntern(args []string, exp string, idents
 	if err != nil {
 		return nil, false, err
 	}
+	return g.GenerateFromAST(ast, args...)
+}
 
+// GenerateFromAST creates a function from an already existing AST,
+// e.g. an AST which was decoded by parser2.DecodeJSON.
+// The args are the names of the arguments of the function.
+func (g *FunctionGenerator[V]) GenerateFromAST(ast parser2.AST, args ...string) (Func[V], bool, error) {
 	gc := GeneratorContext{am: args, cm: nil}
 
 	f, pure, err := g.GenerateFunc(ast, gc)
 	if err != nil {
 		return nil, false, err
 	}
+	debugging := g.debugger != nil
 	return func(st Stack[V]) (val V, err error) {
+		if debugging && st.storage.debug == nil {
+			st.storage.debug = &debugSession{mode: DebugStepInto}
+		}
 		defer func() {
 			if rec := recover(); rec != nil {
 				log.Print("panic in function: ", rec)
@@ -838,7 +1115,32 @@ func (g *FunctionGenerator[V]) CreateAst(exp string, idents parser2.Identifiers[
 	return ast, nil
 }
 
+// GenerateFunc creates the function of the given AST using the selected backend.
+// The boolean returned is true if the function is pure.
 func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	if g.profiler != nil {
+		if g.debugger != nil {
+			return nil, false, errors.New("the profiler can not be used together with the debugger")
+		}
+		if g.backend != TreeBackend {
+			return nil, false, errors.New("the profiler requires the tree backend")
+		}
+		return g.profileGenerate(ast, gc)
+	}
+	if g.debugger != nil {
+		if g.backend != TreeBackend {
+			return nil, false, errors.New("the debugger requires the tree backend")
+		}
+		return g.debugGenerate(ast, gc)
+	}
+	if g.backend == BytecodeBackend {
+		return g.compile(ast, gc)
+	}
+	return g.generateTree(ast, gc)
+}
+
+// generateTree creates the function of the given AST as a tree of nested go closures
+func (g *FunctionGenerator[V]) generateTree(ast parser2.AST, gc GeneratorContext) (ParserFunc[V], bool, error) {
 	var zero V
 	if g.customGenerator != nil {
 		c, pure, err := g.customGenerator.GenerateCustom(ast, gc, g)
@@ -870,6 +1172,9 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 			}
 		}
 	case *parser2.Let:
+		if a.Destructuring != nil {
+			return g.generateDestructuring(a, gc)
+		}
 		valFunc, pure, err := g.GenerateFunc(a.Value, gc)
 		if err != nil {
 			return nil, false, err
@@ -890,6 +1195,17 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 			st.Push(va)
 			return mainFunc(st, cs)
 		}, pure && mainPure, nil
+	case *parser2.FuncGroup:
+		return g.generateFuncGroup(a, gc)
+	case *parser2.Range:
+		return g.generateRange(a, gc)
+	case *parser2.ListComprehension:
+		return g.generateComprehension(a, gc)
+	case *parser2.Import:
+		return g.generateImport(a, gc)
+	case *parser2.ConstDef:
+		// all usages of the constant are already replaced by its value
+		return g.GenerateFunc(a.Inner, gc)
 	case *parser2.If:
 		if g.toBool != nil {
 			condFunc, condPure, err := g.GenerateFunc(a.Cond, gc)
@@ -934,10 +1250,47 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 			pure := switchPure && defaultPure
 			type caseFunc struct {
 				constFunc  ParserFunc[V]
+				match      matcher[V]
+				guardFunc  ParserFunc[V]
 				resultFunc ParserFunc[V]
 			}
 			var cases []caseFunc
 			for _, c := range a.Cases {
+				if c.Pattern != nil {
+					match, matchPure, err := g.compilePattern(c.Pattern, gc)
+					if err != nil {
+						return nil, false, err
+					}
+					caseGc := gc
+					for _, name := range parser2.PatternVars(c.Pattern) {
+						caseGc, err = caseGc.addLocalVar(name)
+						if err != nil {
+							return nil, false, c.Pattern.GetLine().EnhanceErrorf(err, "error in match")
+						}
+					}
+					var guardFunc ParserFunc[V]
+					guardPure := true
+					if c.Guard != nil {
+						if g.toBool == nil {
+							return nil, false, c.Pattern.GetLine().Errorf("guards not supported")
+						}
+						guardFunc, guardPure, err = g.GenerateFunc(c.Guard, caseGc)
+						if err != nil {
+							return nil, false, err
+						}
+					}
+					resultFunc, resultPure, err := g.GenerateFunc(c.Value, caseGc)
+					if err != nil {
+						return nil, false, err
+					}
+					pure = pure && matchPure && guardPure && resultPure
+					cases = append(cases, caseFunc{
+						match:      match,
+						guardFunc:  guardFunc,
+						resultFunc: resultFunc,
+					})
+					continue
+				}
 				constFunc, condPure, err := g.GenerateFunc(c.CaseConst, gc)
 				pure = pure && condPure
 				if err != nil {
@@ -959,6 +1312,28 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 					return zero, a.EnhanceErrorf(err, "error in switch")
 				}
 				for _, c := range cases {
+					if c.match != nil {
+						cst := st
+						ok, err := c.match(&cst, cs, val)
+						if err != nil {
+							return zero, a.EnhanceErrorf(err, "error in switch-match")
+						}
+						if !ok {
+							continue
+						}
+						if c.guardFunc != nil {
+							gv, err := c.guardFunc(cst, cs)
+							if err != nil {
+								return zero, a.EnhanceErrorf(err, "error in switch-match guard")
+							}
+							if guard, ok := g.toBool(gv); !ok {
+								return zero, a.Errorf("guard does not return a bool")
+							} else if !guard {
+								continue
+							}
+						}
+						return c.resultFunc(cst, cs)
+					}
 					constVal, err := c.constFunc(st, cs)
 					if err != nil {
 						return zero, a.EnhanceErrorf(err, "error in switch-case")
@@ -1032,13 +1407,15 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 	case *parser2.ClosureLiteral:
 		if len(a.OuterIdents) == 0 && !a.Recursive {
 			// not a closure, not recursive, just a pure function
-			closureFunc, pure, err := g.GenerateFunc(a.Func, GeneratorContext{am: a.Names})
+			closureGc := gc.closureContext(a.Names, nil)
+			closureFunc, pure, err := g.GenerateFunc(a.Func, closureGc)
 			if err != nil {
 				return nil, false, err
 			}
+			closureFunc = g.debugClosure(a, closureGc, closureFunc)
 			return func(st Stack[V], cs []V) (V, error) {
 				return g.closureHandler.FromClosure(Function[V]{
-					Func: closureFunc,
+					Func: countStep(closureFunc),
 					Args: len(a.Names),
 				}), nil
 			}, pure, nil
@@ -1046,6 +1423,31 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 			// is a real closure
 			return g.createClosureLiteralFunc(a, gc)
 		}
+	case *parser2.Interpolation:
+		joiner, ok := g.stringHandler.(StringJoiner[V])
+		if !ok {
+			return nil, false, a.Errorf("string interpolation not supported")
+		}
+		valueFuncs, pure, err := g.genFuncList(a.Values, gc)
+		if err != nil {
+			return nil, false, err
+		}
+		strs := make([]V, len(a.Strings))
+		for i, s := range a.Strings {
+			strs[i] = g.stringHandler.FromString(s)
+		}
+		return func(st Stack[V], cs []V) (V, error) {
+			parts := make([]V, 0, len(strs)+len(valueFuncs))
+			parts = append(parts, strs[0])
+			for i, valueFunc := range valueFuncs {
+				v, err := valueFunc(st, cs)
+				if err != nil {
+					return zero, a.EnhanceErrorf(err, "error in string interpolation")
+				}
+				parts = append(parts, v, strs[i+1])
+			}
+			return joiner.JoinStrings(st, parts)
+		}, pure, nil
 	case *parser2.ListLiteral:
 		if g.listHandler != nil {
 			itemFuncs, pure, err := g.genFuncList(a.List, gc)
@@ -1083,7 +1485,7 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 				if err != nil {
 					return zero, a.EnhanceErrorf(err, "error in getting list")
 				}
-				return g.listHandler.AccessList(l, i)
+				return g.listHandler.AccessList(st, l, i)
 			}, iPure && lPure, nil
 		}
 	case *parser2.MapLiteral:
@@ -1116,6 +1518,19 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 			if err != nil {
 				return nil, false, err
 			}
+			if a.Optional {
+				nh, ok := g.mapHandler.(NilHandler[V])
+				if !ok {
+					return nil, false, a.Errorf("optional chaining not supported")
+				}
+				return func(st Stack[V], cs []V) (V, error) {
+					l, err := mapFunc(st, cs)
+					if err != nil {
+						return zero, a.EnhanceErrorf(err, "error in getting map")
+					}
+					return accessOptional(g.mapHandler, nh, l, a.Key)
+				}, pure, nil
+			}
 			return func(st Stack[V], cs []V) (V, error) {
 				l, err := mapFunc(st, cs)
 				if err != nil {
@@ -1127,6 +1542,28 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 	case *parser2.FunctionCall:
 		if id, ok := a.Func.(*parser2.Ident); ok {
 			if fun, ok := g.staticFunctions[id.Name]; ok {
+				fun = g.profileFunction(fun, id.Name)
+				if a.ArgNames != nil {
+					pos, err := fun.arrangeArgs(a.ArgNames)
+					if err != nil {
+						return nil, false, id.EnhanceErrorf(err, "error in call of %s", fun.Description.String(id.Name))
+					}
+					argsFuncList, pure, err := g.genFuncList(a.Args, gc)
+					if err != nil {
+						return nil, false, err
+					}
+					return func(st Stack[V], cs []V) (V, error) {
+						args, err := evalArgs(st, cs, argsFuncList)
+						if err != nil {
+							return zero, a.EnhanceErrorf(err, "error in function call to %s", id.Name)
+						}
+						if err := st.Step(); err != nil {
+							return zero, err
+						}
+						n := fun.pushArranged(&st, pos, args)
+						return fun.Func(st.CreateFrame(n), nil)
+					}, fun.IsPure && pure, nil
+				}
 				if fun.argsNumberNotMatching(len(a.Args)) {
 					return nil, false, id.Error(fun.argsNumberNotMatchingError(id.Name, len(a.Args)))
 				}
@@ -1142,9 +1579,15 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 						}
 						st.Push(v)
 					}
+					if err := st.Step(); err != nil {
+						return zero, err
+					}
 					return fun.Func(st.CreateFrame(len(argsFuncList)), nil)
 				}, fun.IsPure && pure, nil
 			}
+			if _, isLocal := gc.am.get(id.Name); gc.tailCalls[a] && !isLocal {
+				return g.generateTailCall(a, gc)
+			}
 		}
 		funcFunc, fPure, err := g.GenerateFunc(a.Func, gc)
 		if err != nil {
@@ -1163,8 +1606,23 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 			if !ok {
 				return zero, parser2.NewNotAFunction(a.String(), a.Errorf("not a function: %v", a.Func))
 			}
+			if a.ArgNames != nil {
+				pos, err := theFunc.arrangeArgs(a.ArgNames)
+				if err != nil {
+					return zero, a.EnhanceErrorf(err, "error in function call to %v", a.Func)
+				}
+				args, err := evalArgs(st, cs, argsFuncList)
+				if err != nil {
+					return zero, a.EnhanceErrorf(err, "error in arguments in function call to %v", a.Func)
+				}
+				n := theFunc.pushArranged(&st, pos, args)
+				return theFunc.Func(st.CreateFrame(n), cs)
+			}
 			if theFunc.argsNumberNotMatching(len(argsFuncList)) {
-				return zero, fmt.Errorf("wrong number of arguments at call of function, required %d, found %d in line %d", theFunc.Args, len(argsFuncList), a.Line)
+				if len(theFunc.Defaults) > 0 {
+					return zero, a.Errorf("wrong number of arguments at call of function, required %d to %d, found %d", theFunc.minArgs(), theFunc.Args, len(argsFuncList))
+				}
+				return zero, a.Errorf("wrong number of arguments at call of function, required %d, found %d", theFunc.Args, len(argsFuncList))
 			}
 			for _, argFunc := range argsFuncList {
 				v, err := argFunc(st, cs)
@@ -1185,11 +1643,22 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 		if err != nil {
 			return nil, false, err
 		}
+		var nh NilHandler[V]
+		if a.Optional {
+			var ok bool
+			if nh, ok = g.mapHandler.(NilHandler[V]); !ok {
+				return nil, false, a.Errorf("optional chaining not supported")
+			}
+		}
+		mc := g.methodCounter(name)
 		return func(st Stack[V], cs []V) (V, error) {
 			value, err := valFunc(st, cs)
 			if err != nil {
 				return zero, a.EnhanceErrorf(err, "error in method call to %s", name)
 			}
+			if nh != nil && nh.IsNil(value) {
+				return value, nil
+			}
 			// name could be a method, but it could also be the name of a field which stores a closure
 			// If it is a closure field, this should be a map access!
 			if g.mapHandler != nil && g.mapHandler.IsMap(value) {
@@ -1221,6 +1690,9 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 				if me.Args > 0 && me.Args != len(argsFuncList)+1 {
 					return zero, a.Errorf("wrong number of arguments at call of \"%s\", required %d, found %d", me.Description.String(name), me.Args-1, len(argsFuncList))
 				}
+				if err := st.Step(); err != nil {
+					return zero, err
+				}
 				st.Push(value)
 				for _, arg := range argsFuncList {
 					v, err := arg(st, cs)
@@ -1229,7 +1701,12 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 					}
 					st.Push(v)
 				}
-				v, err := me.Func(st.CreateFrame(len(argsFuncList)+1), nil)
+				var v V
+				if mc != nil {
+					v, err = measure(mc, me.Func, st.CreateFrame(len(argsFuncList)+1), nil)
+				} else {
+					v, err = me.Func(st.CreateFrame(len(argsFuncList)+1), nil)
+				}
 				if err != nil {
 					err = fmt.Errorf("error in method %s: %w", name, err)
 				}
@@ -1241,7 +1718,186 @@ func (g *FunctionGenerator[V]) GenerateFunc(ast parser2.AST, gc GeneratorContext
 	return nil, false, ast.GetLine().Errorf("not supported: %v", ast)
 }
 
+// accessOptional accesses the given map like the optional chaining
+// "m?.a" does. It returns nil if the map is nil or if the key is not
+// present in the map.
+func accessOptional[V any](mh MapHandler[V], nh NilHandler[V], m V, key string) (V, error) {
+	if nh.IsNil(m) {
+		return m, nil
+	}
+	v, err := mh.AccessMap(m, key)
+	if err != nil {
+		var nf parser2.NotFoundError
+		if mh.IsMap(m) && errors.As(err, &nf) {
+			return nh.Nil(), nil
+		}
+	}
+	return v, err
+}
+
+// generateDestructuring creates the function of a let which splits a
+// list or a map into several variables.
+func (g *FunctionGenerator[V]) generateDestructuring(a *parser2.Let, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	d := a.Destructuring
+	var split func(st Stack[V], v V) ([]V, error)
+	if d.IsMap {
+		if g.mapHandler == nil {
+			return nil, false, d.Errorf("map destructuring not supported")
+		}
+		split = func(st Stack[V], v V) ([]V, error) {
+			if !g.mapHandler.IsMap(v) {
+				return nil, fmt.Errorf("destructuring requires a map")
+			}
+			values := make([]V, len(d.Names))
+			for i, name := range d.Names {
+				val, err := g.mapHandler.AccessMap(v, name)
+				if err != nil {
+					return nil, err
+				}
+				values[i] = val
+			}
+			return values, nil
+		}
+	} else {
+		destructor, ok := g.listHandler.(ListDestructor[V])
+		if !ok {
+			return nil, false, d.Errorf("list destructuring not supported")
+		}
+		split = func(st Stack[V], v V) ([]V, error) {
+			items, ok, err := destructor.ListItems(st, v)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("destructuring requires a list")
+			}
+			if len(items) != len(d.Names) {
+				return nil, fmt.Errorf("list destructuring requires %d items, found %d", len(d.Names), len(items))
+			}
+			return items, nil
+		}
+	}
+
+	valFunc, pure, err := g.GenerateFunc(a.Value, gc)
+	if err != nil {
+		return nil, false, err
+	}
+	newGc := gc
+	for _, name := range d.Names {
+		newGc, err = newGc.addLocalVar(name)
+		if err != nil {
+			return nil, false, a.EnhanceErrorf(err, "error in let")
+		}
+	}
+	mainFunc, mainPure, err := g.GenerateFunc(a.Inner, newGc)
+	if err != nil {
+		return nil, false, err
+	}
+	return func(st Stack[V], cs []V) (V, error) {
+		var zero V
+		va, err := valFunc(st, cs)
+		if err != nil {
+			return zero, a.EnhanceErrorf(err, "error in let")
+		}
+		values, err := split(st, va)
+		if err != nil {
+			return zero, d.EnhanceErrorf(err, "error in destructuring %v", d)
+		}
+		for _, v := range values {
+			st.Push(v)
+		}
+		return mainFunc(st, cs)
+	}, pure && mainPure, nil
+}
+
+// closureDefaults returns the default values of the arguments of the given closure
+func (g *FunctionGenerator[V]) closureDefaults(a *parser2.ClosureLiteral) ([]V, error) {
+	if len(a.Defaults) == 0 {
+		return nil, nil
+	}
+	defaults := make([]V, len(a.Defaults))
+	for i, d := range a.Defaults {
+		c, ok := d.(*parser2.Const[V])
+		if !ok {
+			return nil, d.GetLine().Errorf("default value is not a constant: %v", d)
+		}
+		defaults[i] = c.Value
+	}
+	return defaults, nil
+}
+
+// closureFunction creates the function of the given closure literal
+func closureFunction[V any](a *parser2.ClosureLiteral, fu ParserFunc[V], defaults []V) Function[V] {
+	fu = countStep(fu)
+	f := Function[V]{
+		Func:     fu,
+		Args:     len(a.Names),
+		ArgNames: a.Names,
+	}
+	if len(defaults) > 0 {
+		f.Defaults = defaults
+		f.Func = fillDefaults(fu, f.Args, defaults)
+	}
+	return f
+}
+
+// countStep counts every invocation of the given function as an evaluation step
+func countStep[V any](fu ParserFunc[V]) ParserFunc[V] {
+	return func(st Stack[V], cs []V) (V, error) {
+		if err := st.Step(); err != nil {
+			var zero V
+			return zero, err
+		}
+		return fu(st, cs)
+	}
+}
+
+// contextAccess returns a value of the captured environment of a closure.
+// The group contains the closures created together with the closure, which
+// is this.
+type contextAccess[V any] func(st Stack[V], cs []V, this V, group []V) V
+
+// closureParts contains everything needed to create a closure at runtime
+type closureParts[V any] struct {
+	closureFunc ParserFunc[V]
+	access      []contextAccess[V]
+	defaults    []V
+}
+
+// create creates the closure and the slice which is used to store
+// the captured environment. The environment is not yet filled.
+func (c *closureParts[V]) create(g *FunctionGenerator[V], a *parser2.ClosureLiteral) (V, []V) {
+	closureContext := make([]V, len(c.access))
+	closure := g.closureHandler.FromClosure(closureFunction(a, func(st Stack[V], cs []V) (V, error) {
+		return c.closureFunc(st, closureContext)
+	}, c.defaults))
+	return closure, closureContext
+}
+
+// fill fills the captured environment of the given closure
+func (c *closureParts[V]) fill(st Stack[V], cs []V, closure V, closureContext []V, group []V) {
+	for i, access := range c.access {
+		closureContext[i] = access(st, cs, closure, group)
+	}
+}
+
 func (g *FunctionGenerator[V]) createClosureLiteralFunc(a *parser2.ClosureLiteral, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	parts, pure, err := g.createClosureParts(a, gc, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	return func(st Stack[V], cs []V) (V, error) {
+		closure, closureContext := parts.create(g, a)
+		parts.fill(st, cs, closure, closureContext, nil)
+		return closure, nil
+	}, pure, nil
+}
+
+// createClosureParts creates the parts of a closure. The group contains
+// the names of the functions of a func group the closure belongs to. They
+// are taken from the closures created together with this closure instead
+// of the stack or the context.
+func (g *FunctionGenerator[V]) createClosureParts(a *parser2.ClosureLiteral, gc GeneratorContext, group []string) (*closureParts[V], bool, error) {
 	usedVars := argsList(a.OuterIdents)
 	if a.Recursive {
 		var err error
@@ -1250,41 +1906,115 @@ func (g *FunctionGenerator[V]) createClosureLiteralFunc(a *parser2.ClosureLitera
 			return nil, false, err
 		}
 	}
-	closureFunc, pure, err := g.GenerateFunc(a.Func, GeneratorContext{am: a.Names, cm: usedVars})
+	closureGc := gc.closureContext(a.Names, usedVars)
+	closureGc.tailCalls = findTailCalls[V](a)
+	closureFunc, pure, err := g.GenerateFunc(a.Func, closureGc)
 	if err != nil {
 		return nil, false, err
 	}
+	if len(closureGc.tailCalls) > 0 {
+		closureFunc = tailCallLoop(closureFunc)
+	}
+	closureFunc = g.debugClosure(a, closureGc, closureFunc)
+
+	accessContextOperations, err := createContextAccess[V](a.OuterIdents, len(usedVars), gc, group, a.Line)
+	if err != nil {
+		return nil, false, err
+	}
+	if a.Recursive {
+		accessContextOperations = append(accessContextOperations, func(st Stack[V], cs []V, this V, group []V) V { return this })
+	}
+
+	defaults, err := g.closureDefaults(a)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &closureParts[V]{
+		closureFunc: closureFunc,
+		access:      accessContextOperations,
+		defaults:    defaults,
+	}, pure, nil
+}
 
-	type accessContextOperation func(st Stack[V], cs []V, this V) V
-	accessContextOperations := make([]accessContextOperation, len(a.OuterIdents), len(usedVars))
-	for ci, name := range a.OuterIdents {
-		if i, ok := gc.am.get(name); ok {
-			accessContextOperations[ci] = func(st Stack[V], cs []V, this V) V { return st.Get(i) }
+// createContextAccess creates the operations which read the outer values
+// with the given names. Names contained in the group are taken from the
+// closures of the group. The capacity is the capacity of the returned slice.
+func createContextAccess[V any](names []string, capacity int, gc GeneratorContext, group []string, line parser2.Line) ([]contextAccess[V], error) {
+	access := make([]contextAccess[V], len(names), capacity)
+	for ci, name := range names {
+		if gi := slices.Index(group, name); gi >= 0 {
+			access[ci] = func(st Stack[V], cs []V, this V, group []V) V { return group[gi] }
+		} else if i, ok := gc.am.get(name); ok {
+			access[ci] = func(st Stack[V], cs []V, this V, group []V) V { return st.Get(i) }
 		} else {
 			if i, ok := gc.cm.get(name); ok {
-				accessContextOperations[ci] = func(st Stack[V], cs []V, this V) V { return cs[i] }
+				access[ci] = func(st Stack[V], cs []V, this V, group []V) V { return cs[i] }
 			} else {
-				return nil, false, parser2.NewNotFoundError(name, a.Errorf("outer value '%s' not found", name))
+				return nil, parser2.NewNotFoundError(name, line.Errorf("outer value '%s' not found", name))
 			}
 		}
 	}
-	if a.Recursive {
-		accessContextOperations = append(accessContextOperations, func(st Stack[V], cs []V, this V) V { return this })
-	}
+	return access, nil
+}
 
+// generateFuncGroup creates the function of a group of func definitions.
+// All closures of the group are created first, after that the captured
+// environments are filled, so that the closures are able to call each other.
+func (g *FunctionGenerator[V]) generateFuncGroup(a *parser2.FuncGroup, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	if g.closureHandler == nil {
+		return nil, false, a.Errorf("closures not supported")
+	}
+	names := a.Names()
+	parts := make([]*closureParts[V], len(a.Funcs))
+	pure := true
+	for i, cl := range a.Funcs {
+		p, clPure, err := g.createClosureParts(cl, gc, names)
+		if err != nil {
+			return nil, false, err
+		}
+		parts[i] = p
+		pure = pure && clPure
+	}
+	newGc := gc
+	for _, name := range names {
+		var err error
+		newGc, err = newGc.addLocalVar(name)
+		if err != nil {
+			return nil, false, a.EnhanceErrorf(err, "error in func")
+		}
+	}
+	mainFunc, mainPure, err := g.GenerateFunc(a.Inner, newGc)
+	if err != nil {
+		return nil, false, err
+	}
 	return func(st Stack[V], cs []V) (V, error) {
-		closureContext := make([]V, len(accessContextOperations))
-		closure := g.closureHandler.FromClosure(Function[V]{
-			Func: func(st Stack[V], cs []V) (V, error) {
-				return closureFunc(st, closureContext)
-			},
-			Args: len(a.Names),
-		})
-		for i, accessContext := range accessContextOperations {
-			closureContext[i] = accessContext(st, cs, closure)
+		closures := make([]V, len(parts))
+		contexts := make([][]V, len(parts))
+		for i, p := range parts {
+			closures[i], contexts[i] = p.create(g, a.Funcs[i])
 		}
-		return closure, nil
-	}, pure, nil
+		for i, p := range parts {
+			p.fill(st, cs, closures[i], contexts[i], closures)
+		}
+		for _, c := range closures {
+			st.Push(c)
+		}
+		return mainFunc(st, cs)
+	}, pure && mainPure, nil
+}
+
+// evalArgs evaluates the given argument functions
+func evalArgs[V any](st Stack[V], cs []V, argsFuncList []ParserFunc[V]) ([]V, error) {
+	args := make([]V, len(argsFuncList))
+	for i, argFunc := range argsFuncList {
+		v, err := argFunc(st, cs)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return args, nil
 }
 
 func (g *FunctionGenerator[V]) genFuncList(a []parser2.AST, gc GeneratorContext) ([]ParserFunc[V], bool, error) {
@@ -1496,3 +2226,18 @@ func PrintMatchingCode[V any](v V) {
 	fmt.Print("}\n\n\n")
 }
 */
+
+// Format formats the given source code while keeping its comments.
+// The args are the names of the arguments the source code uses.
+// See parser2.Parser.Format for details.
+func (g *FunctionGenerator[V]) Format(src string, args ...string) (string, error) {
+	return g.GetParser().Format(src, g.identifier.AddArgs(args, nil))
+}
+
+// Lint checks the given source code for probable mistakes.
+// The args are the names of the arguments the source code uses.
+// The given rules are not checked.
+// See parser2.Parser.Lint for details.
+func (g *FunctionGenerator[V]) Lint(src string, args []string, disabled ...parser2.LintRule) ([]parser2.Warning, error) {
+	return g.GetParser().Lint(src, g.identifier.AddArgs(args, nil), disabled...)
+}
@@ -9,6 +9,7 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -103,9 +104,10 @@ func NewGen() *FunctionGenerator[Value] {
 		)
 }
 
-func TestFunctionGenerator_Generate(t *testing.T) {
-	fg := NewGen()
+// backends are the backends all generator tests are run with
+var backends = map[string]Backend{"tree": TreeBackend, "bytecode": BytecodeBackend}
 
+func TestFunctionGenerator_Generate(t *testing.T) {
 	tests := []struct {
 		args     []string
 		exp      string
@@ -190,35 +192,66 @@ func TestFunctionGenerator_Generate(t *testing.T) {
 			exp:      "try a catch 1",
 			result:   2,
 		},
+		{
+			exp:    "func f(x, s=2) x*s; f(3)",
+			result: 6,
+		},
+		{
+			exp:    "func f(x, s=2) x*s; f(3, s: 3)",
+			result: 9,
+		},
+		{
+			exp:    "func f(x, s=2, o=-1) x*s+o; f(o: 1, x: 3)",
+			result: 7,
+		},
+		{
+			args:     []string{"a"},
+			argsVals: []Value{Float(2)},
+			exp:      "let f=(x, y=1)->x+y*a; f(1)+f(y: 2, x: 1)",
+			result:   8,
+		},
+		{
+			args:     []string{"a"},
+			argsVals: []Value{Float(2)},
+			exp:      "let f=(x=2*3+1)->x*a; f()",
+			result:   14,
+		},
 	}
 
-	for _, te := range tests {
-		test := te
-		t.Run(test.exp, func(t *testing.T) {
-			f, _, err := fg.Generate(test.exp, test.args...)
-			assert.NoError(t, err)
-			assert.NotNil(t, f)
-			if f != nil {
-				res, err := f(NewStack(test.argsVals...))
+	for name, backend := range backends {
+		fg := NewGen().SetBackend(backend)
+		for _, te := range tests {
+			test := te
+			t.Run(name+"/"+test.exp, func(t *testing.T) {
+				f, _, err := fg.Generate(test.exp, test.args...)
 				assert.NoError(t, err)
-				if res != nil {
-					fl, err := res.Float()
+				assert.NotNil(t, f)
+				if f != nil {
+					res, err := f(NewStack(test.argsVals...))
 					assert.NoError(t, err)
-					assert.InDelta(t, test.result, fl, 1e-6)
+					if res != nil {
+						fl, err := res.Float()
+						assert.NoError(t, err)
+						assert.InDelta(t, test.result, fl, 1e-6)
+					}
 				}
-			}
-		})
+			})
+		}
 	}
 }
 
 func TestReflectionError(t *testing.T) {
-	f, _, err := NewGen().Generate("a.doesNotExist()", "a")
-	assert.NoError(t, err)
-	_, err = f(NewStack[Value](Float(2)))
-	assert.Error(t, err)
-	errStr := err.Error()
-	assert.True(t, strings.Contains(errStr, "method DoesNotExist not found"))
-	assert.True(t, strings.Contains(errStr, "available are: Sqrt()"))
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			f, _, err := NewGen().SetBackend(backend).Generate("a.doesNotExist()", "a")
+			assert.NoError(t, err)
+			_, err = f(NewStack[Value](Float(2)))
+			assert.Error(t, err)
+			errStr := err.Error()
+			assert.True(t, strings.Contains(errStr, "method DoesNotExist not found"))
+			assert.True(t, strings.Contains(errStr, "available are: Sqrt()"))
+		})
+	}
 }
 
 func BenchmarkFunc(b *testing.B) {
@@ -255,6 +288,11 @@ func TestFunctionDescription_String(t *testing.T) {
 			fu:   Function[int]{Args: 2}.SetMethodDescription("func([item])", "A a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a."),
 			want: "z(func([item]))\n\tA a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a\n\ta a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a a\n\ta.",
 		},
+		{
+			name: "scale",
+			fu:   Function[int]{Args: 2}.SetDescription("x", "factor", "Scales x.").SetDefaults(3),
+			want: "scale(x, factor=3)\n\tScales x.",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -264,3 +302,68 @@ func TestFunctionDescription_String(t *testing.T) {
 		})
 	}
 }
+
+func TestTailCall(t *testing.T) {
+	tests := []struct {
+		exp    string
+		result float64
+	}{
+		{exp: "func sum(n, s) if n then sum(n+-1, s+n) else s; sum(100000, 0)", result: 5000050000},
+		{exp: "func sum(n, s) let m=n+-1; if n then sum(m, s+n) else s; sum(100000, 0)", result: 5000050000},
+		{exp: "func sum(n, s=0) if n then sum(n+-1, s+n) else s; sum(100000)", result: 5000050000},
+		{exp: "func fib(n, a, b) if n then fib(n+-1, b, a+b) else a; fib(50, 0, 1)", result: 12586269025},
+		{exp: "func f(n) if n then f(n+-1)+1 else 0; f(100)", result: 100},
+		{exp: "func f(n, s) if n then (x->f(x, s+n))(n+-1) else s; f(100, 0)", result: 5050},
+		{exp: "func f(n) let f=x->x*2; f(n); f(3)", result: 6},
+		{exp: "func f(n) func g(m, s) if m then g(m+-1, s+n) else s; g(100000, 0); f(2)", result: 200000},
+	}
+
+	for name, backend := range backends {
+		fg := NewGen().SetBackend(backend)
+		for _, te := range tests {
+			test := te
+			t.Run(name+"/"+test.exp, func(t *testing.T) {
+				f, _, err := fg.Generate(test.exp)
+				assert.NoError(t, err)
+				if f != nil {
+					res, err := f(NewEmptyStack[Value]())
+					assert.NoError(t, err)
+					if res != nil {
+						fl, err := res.Float()
+						assert.NoError(t, err)
+						assert.InDelta(t, test.result, fl, 1e-6)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestTailCallConcurrent(t *testing.T) {
+	fg := NewGen()
+	const exp = "func sum(n, s) if n then sum(n+-1, s+n) else s; sum(1000, 0)"
+	// the parser is initialized lazily at the first use, which is not thread safe
+	_, _, err := fg.Generate("1")
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				f, _, err := fg.Generate(exp)
+				if !assert.NoError(t, err) {
+					return
+				}
+				res, err := f(NewEmptyStack[Value]())
+				if !assert.NoError(t, err) {
+					return
+				}
+				fl, err := res.Float()
+				assert.NoError(t, err)
+				assert.InDelta(t, 500500, fl, 1e-6)
+			}
+		}()
+	}
+	wg.Wait()
+}
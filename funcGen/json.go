@@ -0,0 +1,82 @@
+package funcGen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hneemann/parser2"
+)
+
+// ConstCodec returns a codec for the constants of an AST created by this
+// generator. Constant closures created by the optimizer are encoded by their
+// source and are compiled again if decoded. All other values are encoded by
+// the given value codec.
+func (g *FunctionGenerator[V]) ConstCodec(values parser2.ConstCodec[V]) parser2.ConstCodec[V] {
+	return constCodec[V]{g: g, values: values}
+}
+
+type constCodec[V any] struct {
+	g      *FunctionGenerator[V]
+	values parser2.ConstCodec[V]
+}
+
+type jsonConst struct {
+	Closure json.RawMessage `json:"closure,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+}
+
+func (c constCodec[V]) EncodeConst(v V) (json.RawMessage, error) {
+	if c.g.closureHandler != nil {
+		if cl, ok := c.g.closureHandler.ToClosure(v); ok {
+			if cl.Source == nil {
+				return nil, errors.New("closure without source can not be encoded")
+			}
+			data, err := parser2.EncodeJSON[V](cl.Source, c)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(jsonConst{Closure: data})
+		}
+	}
+	data, err := c.values.EncodeConst(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonConst{Value: data})
+}
+
+func (c constCodec[V]) DecodeConst(data json.RawMessage) (V, error) {
+	var zero V
+	var jc jsonConst
+	err := json.Unmarshal(data, &jc)
+	if err != nil {
+		return zero, err
+	}
+	if jc.Closure == nil {
+		return c.values.DecodeConst(jc.Value)
+	}
+
+	if c.g.closureHandler == nil {
+		return zero, errors.New("closures not supported")
+	}
+	ast, err := parser2.DecodeJSON[V](jc.Closure, c)
+	if err != nil {
+		return zero, err
+	}
+	cl, ok := ast.(*parser2.ClosureLiteral)
+	if !ok {
+		return zero, fmt.Errorf("closure expected, found %v", ast)
+	}
+	f, _, err := c.g.GenerateFunc(cl.Func, GeneratorContext{am: cl.Names})
+	if err != nil {
+		return zero, err
+	}
+	defaults, err := c.g.closureDefaults(cl)
+	if err != nil {
+		return zero, err
+	}
+	fu := closureFunction(cl, f, defaults)
+	fu.IsPure = true
+	fu.Source = cl
+	return c.g.closureHandler.FromClosure(fu), nil
+}
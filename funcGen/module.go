@@ -0,0 +1,151 @@
+package funcGen
+
+import (
+	"fmt"
+	"github.com/hneemann/parser2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ModuleResolver is used to find the source code of the modules
+// imported by 'import "name" as n;'.
+type ModuleResolver interface {
+	// Resolve returns the source code of the module with the given name
+	Resolve(name string) (string, error)
+}
+
+// MapResolver resolves modules by a map from the module name to its source code
+type MapResolver map[string]string
+
+func (m MapResolver) Resolve(name string) (string, error) {
+	if src, ok := m[name]; ok {
+		return src, nil
+	}
+	return "", fmt.Errorf("module '%s' not found", name)
+}
+
+// DirResolver resolves modules by reading files from a directory.
+// The source code of the module "stats" is read from the file
+// named "stats" followed by Ext.
+type DirResolver struct {
+	Dir string
+	Ext string
+}
+
+func (d DirResolver) Resolve(name string) (string, error) {
+	file := filepath.FromSlash(name + d.Ext)
+	if !filepath.IsLocal(file) {
+		return "", fmt.Errorf("module name '%s' not allowed", name)
+	}
+	data, err := os.ReadFile(filepath.Join(d.Dir, file))
+	if err != nil {
+		return "", fmt.Errorf("module '%s' not found: %w", name, err)
+	}
+	return string(data), nil
+}
+
+// SetModuleResolver sets the resolver used to find imported modules.
+// Each module is compiled only once. After that, the compiled module is
+// used by all imports of this module. The module is evaluated every time
+// the import is evaluated, so the evaluation of the module is limited
+// by the evaluation context of the importing code.
+func (g *FunctionGenerator[V]) SetModuleResolver(resolver ModuleResolver) *FunctionGenerator[V] {
+	g.modulesMutex.Lock()
+	defer g.modulesMutex.Unlock()
+	g.moduleResolver = resolver
+	g.modules = nil
+	return g
+}
+
+// module is a compiled module
+type module[V any] struct {
+	// fu creates the map of the top-level definitions
+	fu   ParserFunc[V]
+	pure bool
+}
+
+// cachedModule returns the module with the given name if it is already
+// imported. If not, the module resolver is returned.
+func (g *FunctionGenerator[V]) cachedModule(name string) (module[V], bool, ModuleResolver) {
+	g.modulesMutex.Lock()
+	defer g.modulesMutex.Unlock()
+	m, ok := g.modules[name]
+	return m, ok, g.moduleResolver
+}
+
+// importModule returns the compiled module with the given name. The
+// module is compiled at the first import and taken from the cache after
+// that. The modules are compiled without holding a lock, so if a module
+// is imported by several goroutines concurrently for the first time, it
+// may be compiled more than once, but all imports get the same module.
+// The importing slice contains the modules which import this module.
+func (g *FunctionGenerator[V]) importModule(name string, importing []string) (module[V], error) {
+	m, ok, resolver := g.cachedModule(name)
+	if ok {
+		return m, nil
+	}
+	if resolver == nil {
+		return m, fmt.Errorf("imports not supported; no module resolver set")
+	}
+	if slices.Contains(importing, name) {
+		return m, fmt.Errorf("import cycle: %s -> %s", strings.Join(importing, " -> "), name)
+	}
+	src, err := resolver.Resolve(name)
+	if err != nil {
+		return m, err
+	}
+
+	ast, err := g.GetParser().ParseModule(src, g.identifier)
+	if err != nil {
+		return m, fmt.Errorf("error parsing module '%s': %w", name, err)
+	}
+	m.fu, m.pure, err = g.GenerateFunc(ast, GeneratorContext{importing: append(slices.Clip(importing), name)})
+	if err != nil {
+		return m, fmt.Errorf("error in module '%s': %w", name, err)
+	}
+
+	g.modulesMutex.Lock()
+	defer g.modulesMutex.Unlock()
+	if cached, ok := g.modules[name]; ok {
+		// imported concurrently by an other goroutine
+		return cached, nil
+	}
+	if g.modules == nil {
+		g.modules = map[string]module[V]{}
+	}
+	g.modules[name] = m
+	return m, nil
+}
+
+// generateImport creates the function of an import which evaluates the
+// imported module and stores the created map in a local variable. The
+// module is evaluated in a new stack which shares the evaluation context
+// of the importing code.
+func (g *FunctionGenerator[V]) generateImport(a *parser2.Import, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	if g.mapHandler == nil {
+		return nil, false, a.Errorf("imports not supported")
+	}
+	module, err := g.importModule(a.Module, gc.importing)
+	if err != nil {
+		return nil, false, a.EnhanceErrorf(err, "error in import of module '%s'", a.Module)
+	}
+	newGc, err := gc.addLocalVar(a.Name)
+	if err != nil {
+		return nil, false, a.EnhanceErrorf(err, "error in import")
+	}
+	mainFunc, mainPure, err := g.GenerateFunc(a.Inner, newGc)
+	if err != nil {
+		return nil, false, err
+	}
+	return func(st Stack[V], cs []V) (V, error) {
+		m, err := module.fu(st.NewEmpty(), nil)
+		if err != nil {
+			var zero V
+			return zero, a.EnhanceErrorf(err, "error evaluating module '%s'", a.Module)
+		}
+		st.Push(m)
+		return mainFunc(st, cs)
+	}, module.pure && mainPure, nil
+}
@@ -1,6 +1,7 @@
 package funcGen
 
 import (
+	"errors"
 	"github.com/hneemann/parser2"
 	"github.com/hneemann/parser2/listMap"
 	"log"
@@ -11,10 +12,33 @@ type optimizer[V any] struct {
 	g  *FunctionGenerator[V]
 }
 
+// Limits of a single constant folding operation. If a constant expression
+// exceeds these limits, it is not folded but evaluated at run time, where
+// it is limited by the EvalContext of the caller.
+const (
+	foldMaxSteps     = 10000
+	foldMaxListItems = 10000
+	foldMaxMemory    = 1 << 20
+)
+
+// NewOptimizer creates a new optimizer which folds constant expressions.
+// If the given stack carries an EvalContext, it limits all folding
+// operations; otherwise each folding operation gets its own budget.
 func NewOptimizer[V any](st Stack[V], g *FunctionGenerator[V]) parser2.Optimizer {
 	return optimizer[V]{st: st, g: g}
 }
 
+// stack returns the stack used to evaluate a constant expression
+func (o optimizer[V]) stack() Stack[V] {
+	if o.st.HasEvalContext() {
+		return o.st.NewEmpty()
+	}
+	return NewEmptyStackWithContext[V](NewEvalContext(nil).
+		SetMaxSteps(foldMaxSteps).
+		SetMaxListItems(foldMaxListItems).
+		SetMaxMemory(foldMaxMemory))
+}
+
 func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 	// evaluate const operations like 1+2
 	if oper, ok := ast.(*parser2.Operate); ok {
@@ -22,7 +46,7 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 			if bc, ok := o.isConst(oper.B); ok {
 				if operator.IsPure {
 					if ac, ok := o.isConst(oper.A); ok {
-						co, err := operator.Impl.Calc(o.st, ac, bc)
+						co, err := operator.Impl.Calc(o.stack(), ac, bc)
 						if err != nil {
 							return ast
 						}
@@ -32,7 +56,7 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 				if operator.IsCommutative {
 					if aOp, ok := oper.A.(*parser2.Operate); ok && aOp.Operator == oper.Operator {
 						if iac, ok := o.isConst(aOp.A); ok {
-							co, err := operator.Impl.Calc(o.st, iac, bc)
+							co, err := operator.Impl.Calc(o.stack(), iac, bc)
 							if err != nil {
 								return ast
 							}
@@ -44,7 +68,7 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 							}
 						}
 						if ibc, ok := o.isConst(aOp.B); ok {
-							co, err := operator.Impl.Calc(o.st, ibc, bc)
+							co, err := operator.Impl.Calc(o.stack(), ibc, bc)
 							if err != nil {
 								return ast
 							}
@@ -96,7 +120,7 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 		} else if la, ok := ast.(*parser2.ListAccess); ok {
 			if list, ok := o.isConst(la.List); ok {
 				if index, ok := o.isConst(la.Index); ok {
-					v, err := o.g.listHandler.AccessList(list, index)
+					v, err := o.g.listHandler.AccessList(o.stack(), list, index)
 					if err != nil {
 						return ast
 					}
@@ -122,7 +146,17 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 			}
 		} else if ma, ok := ast.(*parser2.MapAccess); ok {
 			if v, ok := o.isConst(ma.MapValue); ok {
-				val, err := o.g.mapHandler.AccessMap(v, ma.Key)
+				var val V
+				var err error
+				if ma.Optional {
+					nh, ok := o.g.mapHandler.(NilHandler[V])
+					if !ok {
+						return ast
+					}
+					val, err = accessOptional(o.g.mapHandler, nh, v, ma.Key)
+				} else {
+					val, err = o.g.mapHandler.AccessMap(v, ma.Key)
+				}
 				if err != nil {
 					return ast
 				}
@@ -131,15 +165,30 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 		}
 	}
 
+	// evaluate const string interpolations like "a${1+2}"
+	if in, ok := ast.(*parser2.Interpolation); ok {
+		if joiner, ok := o.g.stringHandler.(StringJoiner[V]); ok {
+			if c, ok := o.allConst(in.Values); ok {
+				parts := make([]V, 0, len(in.Strings)+len(c))
+				parts = append(parts, o.g.stringHandler.FromString(in.Strings[0]))
+				for i, v := range c {
+					parts = append(parts, v, o.g.stringHandler.FromString(in.Strings[i+1]))
+				}
+				v, err := joiner.JoinStrings(o.stack(), parts)
+				if err != nil {
+					return ast
+				}
+				return &parser2.Const[V]{Value: v, Line: in.Line}
+			}
+		}
+	}
+
 	// evaluate const static function calls like sqrt(2)
 	if fc, ok := ast.(*parser2.FunctionCall); ok {
 		if ident, ok := fc.Func.(*parser2.Ident); ok {
 			if fu, ok := o.g.staticFunctions[ident.Name]; ok && fu.IsPure {
-				if fu.argsNumberNotMatching(len(fc.Args)) {
-					return ast
-				}
 				if c, ok := o.allConst(fc.Args); ok {
-					v, err := fu.Func(NewStack[V](c...), nil)
+					v, err := callConst(o.stack(), fu, fc.ArgNames, c)
 					if err != nil {
 						return ast
 					}
@@ -151,11 +200,8 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 			if o.g.closureHandler != nil {
 				if closure, ok := o.g.closureHandler.ToClosure(con.Value); ok {
 					if closure.IsPure {
-						if closure.Args != -1 && closure.Args != len(fc.Args) {
-							return ast
-						}
 						if c, ok := o.allConst(fc.Args); ok {
-							v, err := closure.Func(NewStack[V](c...), nil)
+							v, err := callConst(o.stack(), closure, fc.ArgNames, c)
 							if err != nil {
 								return ast
 							}
@@ -170,6 +216,15 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 	// evaluate const method calls like c.conj()
 	if mc, ok := ast.(*parser2.MethodCall); ok {
 		if con, ok := mc.Value.(*parser2.Const[V]); ok {
+			if mc.Optional {
+				nh, ok := o.g.mapHandler.(NilHandler[V])
+				if !ok {
+					return ast
+				}
+				if nh.IsNil(con.Value) {
+					return con
+				}
+			}
 			if c, ok := o.allConst(mc.Args); ok {
 				if o.g.methodHandler != nil {
 					fu, err := o.g.methodHandler.GetMethod(con.Value, mc.Name)
@@ -183,7 +238,7 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 						args := make([]V, len(c)+1)
 						args[0] = con.Value
 						copy(args[1:], c)
-						v, err := fu.Func(NewStack[V](args...), nil)
+						v, err := fu.Func(o.stack().Init(args...), nil)
 						if err != nil {
 							return ast
 						}
@@ -195,16 +250,21 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 	}
 
 	if o.g.closureHandler != nil {
-		if cl, ok := ast.(*parser2.ClosureLiteral); ok && len(cl.OuterIdents) == 0 && !cl.Recursive {
-			closureFunc, pure, err := o.g.GenerateFunc(cl.Func, GeneratorContext{am: cl.Names})
+		if cl, ok := ast.(*parser2.ClosureLiteral); ok && len(cl.OuterIdents) == 0 && !cl.Recursive && !containsImport(cl.Func) {
+			closureGc := GeneratorContext{am: cl.Names}
+			closureFunc, pure, err := o.g.GenerateFunc(cl.Func, closureGc)
 			if err != nil || !pure {
 				return ast
 			}
-			v := o.g.closureHandler.FromClosure(Function[V]{
-				Func:   closureFunc,
-				Args:   len(cl.Names),
-				IsPure: true,
-			})
+			closureFunc = o.g.debugClosure(cl, closureGc, closureFunc)
+			defaults, err := o.g.closureDefaults(cl)
+			if err != nil {
+				return ast
+			}
+			fu := closureFunction(cl, closureFunc, defaults)
+			fu.IsPure = true
+			fu.Source = cl
+			v := o.g.closureHandler.FromClosure(fu)
 
 			if o.g.GetParser().IsDebug() {
 				log.Println("AST pre eval. closure:\n" + parser2.PrettyPrint[V](ast))
@@ -217,6 +277,40 @@ func (o optimizer[V]) Optimize(ast parser2.AST) parser2.AST {
 	return ast
 }
 
+// containsImport returns true if the given AST contains an import.
+// Closures containing an import are not created by the optimizer, because
+// the optimizer does not know the modules which are imported at the moment,
+// so it is not able to detect import cycles.
+func containsImport(ast parser2.AST) bool {
+	found := false
+	ast.Traverse(parser2.VisitorFunc(func(a parser2.AST) bool {
+		if _, ok := a.(*parser2.Import); ok {
+			found = true
+		}
+		return !found
+	}))
+	return found
+}
+
+// callConst calls the given function with the given constant arguments
+// using the given empty stack. The names are the names of the arguments
+// if there are named arguments.
+func callConst[V any](st Stack[V], fu Function[V], names []string, args []V) (V, error) {
+	var zero V
+	if names == nil {
+		if fu.argsNumberNotMatching(len(args)) {
+			return zero, errors.New("wrong number of arguments")
+		}
+		return fu.Func(st.Init(args...), nil)
+	}
+	pos, err := fu.arrangeArgs(names)
+	if err != nil {
+		return zero, err
+	}
+	fu.pushArranged(&st, pos, args)
+	return fu.Func(st, nil)
+}
+
 func (o optimizer[V]) allConst(asts []parser2.AST) ([]V, bool) {
 	con := make([]V, len(asts))
 	for i, ast := range asts {
@@ -0,0 +1,119 @@
+package funcGen
+
+import (
+	"github.com/hneemann/parser2"
+)
+
+// matcher checks if the given value matches a pattern. If the value
+// matches, the values bound by the pattern are pushed to the stack in
+// the order given by parser2.PatternVars. If the value does not match,
+// some values may already have been pushed to the stack.
+type matcher[V any] func(st *Stack[V], cs []V, v V) (bool, error)
+
+// compilePattern creates the matcher of the given pattern
+func (g *FunctionGenerator[V]) compilePattern(p parser2.Pattern, gc GeneratorContext) (matcher[V], bool, error) {
+	switch pa := p.(type) {
+	case *parser2.BindPattern:
+		if pa.Name == "_" {
+			return func(st *Stack[V], cs []V, v V) (bool, error) {
+				return true, nil
+			}, true, nil
+		}
+		return func(st *Stack[V], cs []V, v V) (bool, error) {
+			st.Push(v)
+			return true, nil
+		}, true, nil
+	case *parser2.TypePattern:
+		test, ok := g.typeTests[pa.Type]
+		if !ok {
+			return nil, false, pa.Errorf("unknown type '%s'", pa.Type)
+		}
+		return func(st *Stack[V], cs []V, v V) (bool, error) {
+			return test(v), nil
+		}, true, nil
+	case *parser2.ValuePattern:
+		valueFunc, pure, err := g.GenerateFunc(pa.Value, gc)
+		if err != nil {
+			return nil, false, err
+		}
+		return func(st *Stack[V], cs []V, v V) (bool, error) {
+			value, err := valueFunc(*st, cs)
+			if err != nil {
+				return false, err
+			}
+			return g.isEqual(*st, v, value)
+		}, pure, nil
+	case *parser2.ListPattern:
+		destructor, ok := g.listHandler.(ListDestructor[V])
+		if !ok {
+			return nil, false, pa.Errorf("list patterns not supported")
+		}
+		items, pure, err := g.compilePatterns(pa.Items, gc)
+		if err != nil {
+			return nil, false, err
+		}
+		bindRest := pa.HasRest && pa.Rest != "" && pa.Rest != "_"
+		return func(st *Stack[V], cs []V, v V) (bool, error) {
+			list, ok, err := destructor.ListItems(*st, v)
+			if err != nil || !ok {
+				return false, err
+			}
+			if len(list) < len(items) || !pa.HasRest && len(list) != len(items) {
+				return false, nil
+			}
+			for i, item := range items {
+				if ok, err := item(st, cs, list[i]); !ok || err != nil {
+					return false, err
+				}
+			}
+			if bindRest {
+				st.Push(g.listHandler.FromList(list[len(items):]))
+			}
+			return true, nil
+		}, pure, nil
+	case *parser2.MapPattern:
+		if g.mapHandler == nil {
+			return nil, false, pa.Errorf("map patterns not supported")
+		}
+		patterns := make([]parser2.Pattern, len(pa.Entries))
+		for i, e := range pa.Entries {
+			patterns[i] = e.Pattern
+		}
+		entries, pure, err := g.compilePatterns(patterns, gc)
+		if err != nil {
+			return nil, false, err
+		}
+		return func(st *Stack[V], cs []V, v V) (bool, error) {
+			if !g.mapHandler.IsMap(v) {
+				return false, nil
+			}
+			for i, e := range pa.Entries {
+				value, err := g.mapHandler.AccessMap(v, e.Key)
+				if err != nil {
+					// the key is not present in the map
+					return false, nil
+				}
+				if ok, err := entries[i](st, cs, value); !ok || err != nil {
+					return false, err
+				}
+			}
+			return true, nil
+		}, pure, nil
+	default:
+		return nil, false, p.GetLine().Errorf("unsupported pattern %v", p)
+	}
+}
+
+func (g *FunctionGenerator[V]) compilePatterns(patterns []parser2.Pattern, gc GeneratorContext) ([]matcher[V], bool, error) {
+	matchers := make([]matcher[V], len(patterns))
+	pure := true
+	for i, p := range patterns {
+		m, mp, err := g.compilePattern(p, gc)
+		if err != nil {
+			return nil, false, err
+		}
+		matchers[i] = m
+		pure = pure && mp
+	}
+	return matchers, pure, nil
+}
@@ -0,0 +1,151 @@
+package funcGen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WritePprof writes the recorded call tree as a gzip compressed profile.proto
+// which can be read by the pprof tool. Every node of the AST, every static
+// function and every method is a function of the profile. The nodes are
+// located in the file "script" at the line they start in. Each path of the
+// call tree is a sample containing the number of calls and the time spent
+// in the innermost function without the time of its callees. So the
+// cumulative times are computed by pprof from the call stacks.
+// The system name of a function is its quoted name. Since it differs from
+// the name, pprof does not try to demangle the name, which would remove
+// the parentheses and brackets from the source code contained in it.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	samples := p.samples()
+
+	index := map[string]int64{"": 0}
+	stringTable := []string{""}
+	str := func(s string) int64 {
+		if i, ok := index[s]; ok {
+			return i
+		}
+		i := int64(len(stringTable))
+		index[s] = i
+		stringTable = append(stringTable, s)
+		return i
+	}
+
+	var pb protoBuffer
+	valueType := func(field int, typ, unit string) {
+		pb.message(field, func(m *protoBuffer) {
+			m.int(1, str(typ))
+			m.int(2, str(unit))
+		})
+	}
+	valueType(1, "calls", "count")
+	valueType(1, "time", "nanoseconds")
+
+	ids := map[*profileCounter]uint64{}
+	var entries []ProfileEntry
+	for _, s := range samples {
+		stack := make([]uint64, len(s.stack))
+		for i, c := range s.stack {
+			// pprof expects the innermost function first
+			i = len(stack) - 1 - i
+			id, ok := ids[c]
+			if !ok {
+				entries = append(entries, c.entry)
+				id = uint64(len(entries))
+				ids[c] = id
+			}
+			stack[i] = id
+		}
+		pb.message(2, func(m *protoBuffer) {
+			m.packed(1, stack)
+			m.packed(2, []uint64{uint64(s.calls), uint64(s.self)})
+		})
+	}
+	for i, e := range entries {
+		id := int64(i + 1)
+		pb.message(4, func(m *protoBuffer) {
+			m.int(1, id)
+			m.message(4, func(l *protoBuffer) {
+				l.int(1, id)
+				l.int(2, int64(e.Num))
+			})
+		})
+	}
+	for i, e := range entries {
+		id := int64(i + 1)
+		name := e.Name
+		file := "script"
+		switch e.Kind {
+		case ProfileFunction:
+			file = "static function"
+		case ProfileMethod:
+			name = "." + name
+			file = "method"
+		}
+		pb.message(5, func(m *protoBuffer) {
+			m.int(1, id)
+			m.int(2, str(name))
+			m.int(3, str(strconv.Quote(name)))
+			m.int(4, str(file))
+			m.int(5, int64(e.Num))
+		})
+	}
+	for _, s := range stringTable {
+		pb.bytes(6, []byte(s))
+	}
+	pb.int(9, p.created.UnixNano())
+	pb.int(10, int64(time.Since(p.created)))
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(pb.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// protoBuffer is a minimal protocol buffers encoder
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		b.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	b.WriteByte(byte(v))
+}
+
+func (b *protoBuffer) tag(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// int writes an int field, zero values are omitted
+func (b *protoBuffer) int(field int, v int64) {
+	if v != 0 {
+		b.tag(field, 0)
+		b.varint(uint64(v))
+	}
+}
+
+func (b *protoBuffer) bytes(field int, data []byte) {
+	b.tag(field, 2)
+	b.varint(uint64(len(data)))
+	b.Write(data)
+}
+
+func (b *protoBuffer) packed(field int, values []uint64) {
+	var p protoBuffer
+	for _, v := range values {
+		p.varint(v)
+	}
+	b.bytes(field, p.Bytes())
+}
+
+func (b *protoBuffer) message(field int, m func(m *protoBuffer)) {
+	var p protoBuffer
+	m(&p)
+	b.bytes(field, p.Bytes())
+}
@@ -0,0 +1,311 @@
+package funcGen
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/hneemann/parser2"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProfileKind is the kind of profile entry
+type ProfileKind int
+
+const (
+	// ProfileNode is used for the nodes of the AST
+	ProfileNode ProfileKind = iota
+	// ProfileFunction is used for static functions
+	ProfileFunction
+	// ProfileMethod is used for methods
+	ProfileMethod
+)
+
+// ProfileEntry contains the number of calls and the
+// cumulative wall time of a node, a static function or a method.
+type ProfileEntry struct {
+	Kind ProfileKind
+	// Name is the name of the function or method, or a short
+	// description of the node
+	Name string
+	// Line is the position of the node in the source code
+	parser2.Line
+	Calls int64
+	Time  time.Duration
+}
+
+type profileKey struct {
+	kind       ProfileKind
+	name       string
+	start, end int
+}
+
+type profileCounter struct {
+	entry    ProfileEntry
+	profiler *Profiler
+	calls    atomic.Int64
+	nanos    atomic.Int64
+}
+
+// profileNode is a node of the call tree. It records the calls of its
+// counter made via the path of counters leading from the root to this node.
+type profileNode struct {
+	counter  *profileCounter
+	children map[*profileCounter]*profileNode
+	calls    atomic.Int64
+	nanos    atomic.Int64
+}
+
+// measure calls the given function and records the call. The node of the
+// call tree the function is called from is stored in the stack.
+func measure[V any](c *profileCounter, f ParserFunc[V], st Stack[V], cs []V) (V, error) {
+	parent := st.storage.profile
+	if parent == nil {
+		parent = &c.profiler.root
+	}
+	node := c.profiler.child(parent, c)
+	st.storage.profile = node
+	start := time.Now()
+	v, err := f(st, cs)
+	d := int64(time.Since(start))
+	st.storage.profile = parent
+	c.nanos.Add(d)
+	c.calls.Add(1)
+	node.nanos.Add(d)
+	node.calls.Add(1)
+	return v, err
+}
+
+// Profiler records how often the nodes of the AST, the static functions and
+// the methods are evaluated and how much wall time this takes. The time of a
+// node includes the time of all nodes it contains, and the time of recursive
+// calls is counted several times. In addition, the call tree is recorded which
+// is written by WritePprof. A Profiler can be used by several goroutines
+// concurrently.
+type Profiler struct {
+	mutex    sync.Mutex
+	counters map[profileKey]*profileCounter
+	root     profileNode
+	created  time.Time
+}
+
+// NewProfiler creates a new profiler
+func NewProfiler() *Profiler {
+	return &Profiler{counters: map[profileKey]*profileCounter{}, created: time.Now()}
+}
+
+// SetProfiler enables the profiling mode. The profiler requires the tree
+// backend and can not be combined with the debugger, because the measured
+// times would contain the time the evaluation is stopped by the debugger.
+// Generating a function fails in both cases. Functions generated before
+// the profiler is set are not profiled.
+func (g *FunctionGenerator[V]) SetProfiler(profiler *Profiler) *FunctionGenerator[V] {
+	g.profiler = profiler
+	return g
+}
+
+func (p *Profiler) counter(key profileKey, line parser2.Line) *profileCounter {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	c, ok := p.counters[key]
+	if !ok {
+		c = &profileCounter{entry: ProfileEntry{Kind: key.kind, Name: key.name, Line: line}, profiler: p}
+		p.counters[key] = c
+	}
+	return c
+}
+
+// child returns the node of the call tree which records the
+// calls of the given counter made from the given node
+func (p *Profiler) child(parent *profileNode, c *profileCounter) *profileNode {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	n, ok := parent.children[c]
+	if !ok {
+		if parent.children == nil {
+			parent.children = map[*profileCounter]*profileNode{}
+		}
+		n = &profileNode{counter: c}
+		parent.children[c] = n
+	}
+	return n
+}
+
+// profileSample is a path of the call tree
+type profileSample struct {
+	// stack contains the counters of the path, the outermost first
+	stack []*profileCounter
+	calls int64
+	// self is the time spent in the innermost counter without its callees
+	self time.Duration
+}
+
+// samples returns a sample for every node of the call tree which was called
+// at least once. Since the time of the callees is subtracted, the total time
+// of all samples does not exceed the wall time, as long as the functions are
+// not evaluated in parallel.
+func (p *Profiler) samples() []profileSample {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var samples []profileSample
+	var walk func(n *profileNode, stack []*profileCounter)
+	walk = func(n *profileNode, stack []*profileCounter) {
+		children := make([]*profileNode, 0, len(n.children))
+		self := n.nanos.Load()
+		for _, c := range n.children {
+			children = append(children, c)
+			self -= c.nanos.Load()
+		}
+		if n.counter != nil {
+			stack = append(stack[:len(stack):len(stack)], n.counter)
+			if calls := n.calls.Load(); calls > 0 {
+				samples = append(samples, profileSample{stack: stack, calls: calls, self: time.Duration(max(self, 0))})
+			}
+		}
+		sort.Slice(children, func(i, j int) bool {
+			ei, ej := children[i].counter.entry, children[j].counter.entry
+			if ei.Start != ej.Start {
+				return ei.Start < ej.Start
+			}
+			return ei.Name < ej.Name
+		})
+		for _, c := range children {
+			walk(c, stack)
+		}
+	}
+	walk(&p.root, nil)
+	return samples
+}
+
+// nodeName returns a short description of the given node
+func nodeName(ast parser2.AST) string {
+	typ := fmt.Sprintf("%T", ast)
+	typ = strings.TrimPrefix(typ, "*parser2.")
+	if i := strings.IndexByte(typ, '['); i >= 0 {
+		typ = typ[:i]
+	}
+	src := strings.Join(strings.Fields(ast.GetLine().Source()), " ")
+	if len(src) > 40 {
+		src = src[:37] + "..."
+	}
+	if src == "" {
+		return typ
+	}
+	return typ + ": " + src
+}
+
+// profileGenerate creates the function of the given AST and records its calls
+func (g *FunctionGenerator[V]) profileGenerate(ast parser2.AST, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	fu, pure, err := g.generateTree(ast, gc)
+	if err != nil {
+		return nil, false, err
+	}
+	switch ast.(type) {
+	case *parser2.Const[V], *parser2.Ident:
+		// too cheap to be measured
+		return fu, pure, nil
+	case *parser2.Let, *parser2.ConstDef, *parser2.FuncGroup, *parser2.Import:
+		// the time would contain the time of all the code following the definition
+		return fu, pure, nil
+	}
+	line := ast.GetLine()
+	c := g.profiler.counter(profileKey{kind: ProfileNode, name: nodeName(ast), start: line.Start, end: line.End}, line)
+	return func(st Stack[V], cs []V) (V, error) {
+		return measure(c, fu, st, cs)
+	}, pure, nil
+}
+
+// profileFunction returns the given static function which
+// records its calls if the profiling mode is enabled
+func (g *FunctionGenerator[V]) profileFunction(fun Function[V], name string) Function[V] {
+	if g.profiler == nil {
+		return fun
+	}
+	c := g.profiler.counter(profileKey{kind: ProfileFunction, name: name}, parser2.Line{})
+	f := fun.Func
+	fun.Func = func(st Stack[V], cs []V) (V, error) {
+		return measure(c, f, st, cs)
+	}
+	return fun
+}
+
+// methodCounter returns the counter of the method with the given
+// name, or nil if the profiling mode is disabled
+func (g *FunctionGenerator[V]) methodCounter(name string) *profileCounter {
+	if g.profiler == nil {
+		return nil
+	}
+	return g.profiler.counter(profileKey{kind: ProfileMethod, name: name}, parser2.Line{})
+}
+
+// Entries returns the entries recorded so far which were called at least
+// once. The entries are ordered by decreasing time.
+func (p *Profiler) Entries() []ProfileEntry {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var entries []ProfileEntry
+	for _, c := range p.counters {
+		e := c.entry
+		e.Calls = c.calls.Load()
+		e.Time = time.Duration(c.nanos.Load())
+		if e.Calls > 0 {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Time != entries[j].Time {
+			return entries[i].Time > entries[j].Time
+		}
+		if entries[i].Start != entries[j].Start {
+			return entries[i].Start < entries[j].Start
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Annotate returns the given source code annotated by the number of calls
+// and the time of the slowest node which is contained in each line. Nodes
+// spanning several lines are not taken into account. The source code needs
+// to be the source code the profiled functions are generated from.
+// The static functions and methods are listed below the source code.
+func (p *Profiler) Annotate(src string) string {
+	entries := p.Entries()
+	byLine := map[int]ProfileEntry{}
+	var funcs []ProfileEntry
+	for _, e := range entries {
+		if e.Kind != ProfileNode {
+			funcs = append(funcs, e)
+			continue
+		}
+		if strings.Contains(e.Source(), "\n") {
+			continue
+		}
+		if o, ok := byLine[e.Num]; !ok || e.Time > o.Time {
+			byLine[e.Num] = e
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%10s %12s  %s\n", "calls", "time", "source")
+	for i, l := range strings.Split(src, "\n") {
+		if e, ok := byLine[i+1]; ok {
+			fmt.Fprintf(&b, "%10d %12v  %s\n", e.Calls, e.Time, l)
+		} else {
+			fmt.Fprintf(&b, "%10s %12s  %s\n", "", "", l)
+		}
+	}
+	if len(funcs) > 0 {
+		fmt.Fprintf(&b, "\n%10s %12s  %s\n", "calls", "time", "function")
+		for _, e := range funcs {
+			name := e.Name
+			if e.Kind == ProfileMethod {
+				name = "." + name
+			}
+			fmt.Fprintf(&b, "%10d %12v  %s\n", e.Calls, e.Time, name)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package funcGen
+
+import (
+	"errors"
+	"github.com/hneemann/parser2"
+)
+
+// errTailCall is returned by a self tail call after the new arguments
+// are stored in the frame of the function. It is never seen outside
+// the function because it is caught by the loop created by tailCallLoop.
+var errTailCall = errors.New("tail call")
+
+// replaceArgs replaces the first n values of the frame by the n values
+// on top of the stack. This way the frame of a function is reused at a
+// tail call.
+func (s *Stack[V]) replaceArgs(n int) {
+	d := s.storage.data
+	copy(d[s.offs:s.offs+n], d[s.offs+s.size-n:s.offs+s.size])
+	s.size -= n
+}
+
+// findTailCalls returns the calls of a recursive closure to itself which
+// are in tail position. Such a call is the last thing the function does,
+// so the frame of the function is not needed anymore and can be reused.
+// Only calls with all arguments given by position are taken into account.
+func findTailCalls[V any](a *parser2.ClosureLiteral) map[*parser2.FunctionCall]bool {
+	if !a.Recursive {
+		return nil
+	}
+	calls := map[*parser2.FunctionCall]bool{}
+	collectTailCalls[V](a.Func, a.ThisName, len(a.Names), calls)
+	return calls
+}
+
+func collectTailCalls[V any](ast parser2.AST, name string, args int, calls map[*parser2.FunctionCall]bool) {
+	switch a := ast.(type) {
+	case *parser2.FunctionCall:
+		if id, ok := a.Func.(*parser2.Ident); ok && id.Name == name && a.ArgNames == nil && len(a.Args) == args {
+			calls[a] = true
+		}
+	case *parser2.If:
+		collectTailCalls[V](a.Then, name, args, calls)
+		collectTailCalls[V](a.Else, name, args, calls)
+	case *parser2.Switch[V]:
+		for _, c := range a.Cases {
+			collectTailCalls[V](c.Value, name, args, calls)
+		}
+		collectTailCalls[V](a.Default, name, args, calls)
+	case *parser2.Let:
+		collectTailCalls[V](a.Inner, name, args, calls)
+	case *parser2.FuncGroup:
+		collectTailCalls[V](a.Inner, name, args, calls)
+	case *parser2.Import:
+		collectTailCalls[V](a.Inner, name, args, calls)
+	case *parser2.ConstDef:
+		collectTailCalls[V](a.Inner, name, args, calls)
+	}
+}
+
+// tailCallLoop executes the function of a closure as long as it ends
+// with a self tail call.
+func tailCallLoop[V any](f ParserFunc[V]) ParserFunc[V] {
+	return func(st Stack[V], cs []V) (V, error) {
+		for {
+			v, err := f(st, cs)
+			if err != errTailCall {
+				return v, err
+			}
+			if err := st.Step(); err != nil {
+				return v, err
+			}
+		}
+	}
+}
+
+// generateTailCall creates a self tail call. The arguments are evaluated and
+// stored in the frame of the function, after that errTailCall is returned to
+// restart the function.
+func (g *FunctionGenerator[V]) generateTailCall(a *parser2.FunctionCall, gc GeneratorContext) (ParserFunc[V], bool, error) {
+	argsFuncList, pure, err := g.genFuncList(a.Args, gc)
+	if err != nil {
+		return nil, false, err
+	}
+	return func(st Stack[V], cs []V) (V, error) {
+		var zero V
+		for _, argFunc := range argsFuncList {
+			v, err := argFunc(st, cs)
+			if err != nil {
+				return zero, a.EnhanceErrorf(err, "error in arguments in function call to %v", a.Func)
+			}
+			st.Push(v)
+		}
+		st.replaceArgs(len(argsFuncList))
+		return zero, errTailCall
+	}, pure, nil
+}
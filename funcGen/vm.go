@@ -0,0 +1,320 @@
+package funcGen
+
+import (
+	"fmt"
+	"github.com/hneemann/parser2"
+)
+
+// pendingCall is a function resolved by opCheckFunc or opMethod which is
+// called by opCall or opInvoke. If closure is true, the method is a closure
+// stored in a map, so the map is not passed to the function.
+type pendingCall[V any] struct {
+	fu      ParserFunc[V]
+	closure bool
+}
+
+// run executes the program. The local variables are stored in the given
+// stack, the temporary values in the array t and the pending calls in the array f.
+func (p *program[V]) run(st Stack[V], cs []V) (V, error) {
+	var zero V
+	g := p.g
+	st.size = p.locals
+	var t [maxTemps]V
+	sp := 0
+	var f [maxCalls]pendingCall[V]
+	fp := 0
+	code := p.code
+	for pc := 0; pc < len(code); pc++ {
+		in := code[pc]
+		switch in.op {
+		case opConst:
+			t[sp] = p.consts[in.a]
+			sp++
+		case opLoad:
+			t[sp] = st.storage.data[st.offs+int(in.a)]
+			sp++
+		case opLoadCtx:
+			t[sp] = cs[in.a]
+			sp++
+		case opLet:
+			sp--
+			st.Push(t[sp])
+		case opDropLocals:
+			st.size -= int(in.a)
+		case opJump:
+			pc = int(in.a) - 1
+		case opJumpIfFalse:
+			sp--
+			cond, ok := g.toBool(t[sp])
+			if !ok {
+				return zero, p.wrapError(pc, p.nodes[in.b].GetLine().Errorf("if condition is not a bool"))
+			}
+			if !cond {
+				pc = int(in.a) - 1
+			}
+		case opUnary:
+			v, err := p.unary[in.a].Calc(t[sp-1])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp-1] = v
+		case opOperate:
+			sp--
+			v, err := p.ops[in.a].Calc(st, t[sp-1], t[sp])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp-1] = v
+		case opOperateRight:
+			o := &p.opers[in.a]
+			b, err := p.operand(st, &o.b)
+			if err != nil {
+				return zero, p.wrapError(pc, o.node.EnhanceErrorf(err, "error in operation %v", o.node.Operator))
+			}
+			v, err := o.impl.Calc(st, t[sp-1], b)
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp-1] = v
+		case opOperateLeft:
+			o := &p.opers[in.a]
+			a, err := p.operand(st, &o.a)
+			if err != nil {
+				return zero, p.wrapError(pc, o.node.EnhanceErrorf(err, "error in operation %v", o.node.Operator))
+			}
+			v, err := o.impl.Calc(st, a, t[sp-1])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp-1] = v
+		case opOperateBoth:
+			v, err := p.operate(st, &p.opers[in.a])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp] = v
+			sp++
+		case opJumpIfFalseOperate:
+			o := &p.opers[in.b]
+			v, err := p.operate(st, o)
+			if err != nil {
+				return zero, p.wrapError(pc, o.cond.EnhanceErrorf(err, "error in if"))
+			}
+			cond, ok := g.toBool(v)
+			if !ok {
+				return zero, p.wrapError(pc, o.cond.Errorf("if condition is not a bool"))
+			}
+			if !cond {
+				pc = int(in.a) - 1
+			}
+		case opList:
+			n := int(in.a)
+			items := make([]V, n)
+			sp -= n
+			copy(items, t[sp:sp+n])
+			t[sp] = g.listHandler.FromList(items)
+			sp++
+		case opMap:
+			ml := &p.maps[in.a]
+			sp -= ml.pushed
+			m, err := p.createMap(st, ml, t[sp:sp+ml.pushed])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp] = g.mapHandler.FromMap(m)
+			sp++
+		case opMapAccess:
+			v, err := g.mapHandler.AccessMap(t[sp-1], p.keys[in.a])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp-1] = v
+		case opLoadMapAccess:
+			v, err := g.mapHandler.AccessMap(st.storage.data[st.offs+int(in.a)], p.keys[in.b])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp] = v
+			sp++
+		case opMapAccessOptional:
+			v, err := accessOptional(g.mapHandler, p.nh, t[sp-1], p.keys[in.a])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp-1] = v
+		case opListAccess:
+			sp--
+			v, err := g.listHandler.AccessList(st, t[sp], t[sp-1])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp-1] = v
+		case opCallStatic:
+			if err := st.Step(); err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			n := int(in.b)
+			sp -= n
+			for _, v := range t[sp : sp+n] {
+				st.Push(v)
+			}
+			v, err := p.static[in.a].Func(st.CreateFrame(n), nil)
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp] = v
+			sp++
+		case opCheckFunc:
+			sp--
+			theFunc, ok := g.ExtractFunction(t[sp])
+			if !ok || theFunc.argsNumberNotMatching(int(in.a)) {
+				return zero, p.callError(pc, in, theFunc, ok)
+			}
+			f[fp] = pendingCall[V]{fu: theFunc.Func}
+			fp++
+		case opCall:
+			n := int(in.a)
+			sp -= n
+			for _, v := range t[sp : sp+n] {
+				st.Push(v)
+			}
+			fp--
+			v, err := f[fp].fu(st.CreateFrame(n), cs)
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp] = v
+			sp++
+		case opTailCall:
+			n := int(in.a)
+			for _, v := range t[sp-n : sp] {
+				st.Push(v)
+			}
+			st.replaceArgs(n)
+			return zero, errTailCall
+		case opMethod:
+			r, ok, err := p.resolveMethod(&p.methods[in.a], t[sp-1])
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			if !ok {
+				// optional chaining on nil
+				pc = int(in.b) - 1
+				continue
+			}
+			f[fp] = r
+			fp++
+		case opInvoke:
+			m := &p.methods[in.a]
+			fp--
+			r := f[fp]
+			sp -= m.args + 1
+			if r.closure {
+				for _, v := range t[sp+1 : sp+1+m.args] {
+					st.Push(v)
+				}
+				v, err := r.fu(st.CreateFrame(m.args), cs)
+				if err != nil {
+					return zero, p.wrapError(pc, fmt.Errorf("error in call of closure %s: %w", m.node.Name, err))
+				}
+				t[sp] = v
+			} else {
+				if err := st.Step(); err != nil {
+					return zero, p.wrapError(pc, err)
+				}
+				for _, v := range t[sp : sp+1+m.args] {
+					st.Push(v)
+				}
+				v, err := r.fu(st.CreateFrame(m.args+1), nil)
+				if err != nil {
+					return zero, p.wrapError(pc, fmt.Errorf("error in method %s: %w", m.node.Name, err))
+				}
+				t[sp] = v
+			}
+			sp++
+		case opTree:
+			v, err := p.trees[in.a](st, cs)
+			if err != nil {
+				return zero, p.wrapError(pc, err)
+			}
+			t[sp] = v
+			sp++
+		}
+	}
+	return t[sp-1], nil
+}
+
+// operand returns the value of an operand which is read directly
+func (p *program[V]) operand(st Stack[V], o *operand) (V, error) {
+	switch o.kind {
+	case operandConst:
+		return p.consts[o.index], nil
+	case operandLocal:
+		return st.storage.data[st.offs+o.index], nil
+	}
+	return p.localKey(st, o)
+}
+
+// localKey returns the value of an operand of kind operandLocalKey
+func (p *program[V]) localKey(st Stack[V], o *operand) (V, error) {
+	return p.g.mapHandler.AccessMap(st.storage.data[st.offs+o.index], o.key)
+}
+
+// operate executes an operation which reads both operands directly
+func (p *program[V]) operate(st Stack[V], o *operation[V]) (V, error) {
+	a, err := p.operand(st, &o.a)
+	if err != nil {
+		return a, o.node.EnhanceErrorf(err, "error in operation %v", o.node.Operator)
+	}
+	b, err := p.operand(st, &o.b)
+	if err != nil {
+		return b, o.node.EnhanceErrorf(err, "error in operation %v", o.node.Operator)
+	}
+	return o.impl.Calc(st, a, b)
+}
+
+// resolveMethod finds the function called by a method call on the given value.
+// If the method call uses optional chaining and the value is nil, false is returned.
+func (p *program[V]) resolveMethod(m *methodCall[V], value V) (pendingCall[V], bool, error) {
+	g := p.g
+	a := m.node
+	if m.nh != nil && m.nh.IsNil(value) {
+		return pendingCall[V]{}, false, nil
+	}
+	// name could be a method, but it could also be the name of a field which stores a closure
+	if g.mapHandler != nil && g.mapHandler.IsMap(value) {
+		if va, err := g.mapHandler.AccessMap(value, a.Name); err == nil {
+			if theFunc, ok := g.ExtractFunction(va); ok {
+				if theFunc.argsNumberNotMatching(m.args) {
+					return pendingCall[V]{}, false, a.Error(theFunc.argsNumberNotMatchingError(a.Name, m.args))
+				}
+				return pendingCall[V]{fu: theFunc.Func, closure: true}, true, nil
+			}
+		}
+	}
+	if g.methodHandler != nil {
+		me, err := g.methodHandler.GetMethod(value, a.Name)
+		if err != nil {
+			return pendingCall[V]{}, false, a.EnhanceErrorf(err, "error accessing method %s", a.Name)
+		}
+		if me.Args > 0 && me.Args != m.args+1 {
+			return pendingCall[V]{}, false, a.Errorf("wrong number of arguments at call of \"%s\", required %d, found %d", me.Description.String(a.Name), me.Args-1, m.args)
+		}
+		return pendingCall[V]{fu: me.Func}, true, nil
+	}
+	return pendingCall[V]{}, false, parser2.NewNotFoundError(a.Name, a.Errorf("method %s not found", a.Name))
+}
+
+// callError creates the error of a call checked by opCheckFunc which is
+// either not a function or called with the wrong number of arguments.
+func (p *program[V]) callError(pc int, in instr, theFunc Function[V], isFunc bool) error {
+	a := p.nodes[in.b].(*parser2.FunctionCall)
+	if !isFunc {
+		return p.wrapError(pc, parser2.NewNotAFunction(a.String(), a.Errorf("not a function: %v", a.Func)))
+	}
+	n := int(in.a)
+	if len(theFunc.Defaults) > 0 {
+		return p.wrapError(pc, a.Errorf("wrong number of arguments at call of function, required %d to %d, found %d", theFunc.minArgs(), theFunc.Args, n))
+	}
+	return p.wrapError(pc, a.Errorf("wrong number of arguments at call of function, required %d, found %d", theFunc.Args, n))
+}
@@ -0,0 +1,217 @@
+package parser2
+
+// FuncGroup is a group of consecutive func definitions which call each
+// other like in "func isEven(n) ...; func isOdd(n) ...;". A group is only
+// created if a func calls a func defined after it. All functions of the
+// group are created together and share the captured environment.
+type FuncGroup struct {
+	Funcs []*ClosureLiteral
+	Inner AST
+	Line
+}
+
+func (f *FuncGroup) Traverse(visitor Visitor) {
+	if visitor.Visit(f) {
+		for _, cl := range f.Funcs {
+			cl.Traverse(visitor)
+		}
+		f.Inner.Traverse(visitor)
+	}
+}
+
+func (f *FuncGroup) Optimize(optimizer Optimizer) {
+	// the functions are already optimized
+	f.Inner = opt(f.Inner, optimizer)
+}
+
+func (f *FuncGroup) String() string {
+	s := ""
+	for _, cl := range f.Funcs {
+		s += "let " + cl.ThisName + "=" + cl.String() + "; "
+	}
+	return s + f.Inner.String()
+}
+
+// Names returns the names of the functions of the group
+func (f *FuncGroup) Names() []string {
+	names := make([]string, len(f.Funcs))
+	for i, cl := range f.Funcs {
+		names[i] = cl.ThisName
+	}
+	return names
+}
+
+// parseFunc parses a func definition like "func f(x) x*x;". If the function
+// calls a function defined by one of the following func definitions, all
+// functions up to this one are parsed and a FuncGroup is returned.
+func (p *Parser[V]) parseFunc(tokenizer *Tokenizer, idents Identifiers[V]) (AST, error) {
+	run := p.funcRunNames(tokenizer)
+	var forward []string
+	if len(run) > 0 {
+		forward = run[1:]
+	}
+	used := make([]bool, len(forward))
+	cl, err := p.parseFuncDef(tokenizer, idents, forward, used)
+	if err != nil {
+		return nil, err
+	}
+
+	last := lastUsed(used, -1)
+	if last < 0 {
+		var clo AST = cl
+		if p.optimizer != nil {
+			clo = Optimize(clo, p.optimizer)
+		}
+
+		name := cl.ThisName
+		innerIdents := idents.Add(name)
+		if c, ok := clo.(*Const[V]); ok && !p.formatting {
+			if !p.module {
+				return p.parseLet(tokenizer, idents.AddConst(name, c.Value))
+			}
+			// the let is required to export the function
+			innerIdents = idents.AddConst(name, c.Value)
+		}
+
+		inner, err := p.parseLet(tokenizer, innerIdents)
+		if err != nil {
+			return nil, err
+		}
+		return &Let{
+			Name:  name,
+			Value: clo,
+			Inner: inner,
+			Line:  cl.Line,
+		}, nil
+	}
+
+	funcs := []*ClosureLiteral{cl}
+	idents = idents.Add(cl.ThisName)
+	for i := 0; i <= last; i++ {
+		cl, err = p.parseFuncDef(tokenizer, idents, forward[i+1:], used[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		funcs = append(funcs, cl)
+		idents = idents.Add(cl.ThisName)
+		last = lastUsed(used, last)
+	}
+
+	if p.optimizer != nil {
+		for _, cl := range funcs {
+			cl.Optimize(p.optimizer)
+		}
+	}
+
+	inner, err := p.parseLet(tokenizer, idents)
+	if err != nil {
+		return nil, err
+	}
+	return &FuncGroup{
+		Funcs: funcs,
+		Inner: inner,
+		Line:  funcs[0].Line.span(funcs[0].Line, cl.End),
+	}, nil
+}
+
+// lastUsed returns the index of the last used function,
+// but at least the given index
+func lastUsed(used []bool, last int) int {
+	for i := len(used) - 1; i > last; i-- {
+		if used[i] {
+			return i
+		}
+	}
+	return last
+}
+
+// parseFuncDef parses a single func definition. The names in forward are
+// the functions defined by the following func definitions. If one of them
+// is used, the corresponding entry in used is set.
+func (p *Parser[V]) parseFuncDef(tokenizer *Tokenizer, idents Identifiers[V], forward []string, used []bool) (*ClosureLiteral, error) {
+	start := tokenizer.Next().Line
+	t := tokenizer.Next()
+	if t.typ != tIdent {
+		return nil, t.Errorf("no identifier followed by func")
+	}
+	name := t.image
+	line := t.GetLine()
+	if t := tokenizer.Next(); t.typ != tOpen {
+		return nil, unexpected("(", t)
+	}
+	names, patterns, defaults, types, err := p.parseIdentList(tokenizer, idents)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range forward {
+		idents = idents.AddThis(f, &used[i])
+	}
+	recursive := false
+	var outersUsed []string
+	bodyStart := tokenizer.Peek().Line
+	bodyIdents := addDestructured(idents.AddArgs(names, &outersUsed).AddThis(name, &recursive), patterns)
+	exp, err := p.parseLet(tokenizer, bodyIdents)
+	if err != nil {
+		if exp, err = recoverFrom(tokenizer, err, bodyStart); err != nil {
+			return nil, err
+		}
+	}
+	exp = wrapDestructured(exp, names, patterns)
+	if err := expect(tokenizer, tSemicolon, ";"); err != nil {
+		return nil, err
+	}
+
+	return &ClosureLiteral{
+		Names:       names,
+		Defaults:    defaults,
+		Types:       types,
+		Func:        exp,
+		Line:        line.span(start, tokenizer.Last().End),
+		OuterIdents: outersUsed,
+		Recursive:   recursive,
+		ThisName:    name,
+	}, nil
+}
+
+// funcRunNames returns the names of the consecutive func definitions
+// starting at the current token. The definitions are parsed by a copy
+// of the tokenizer, so no tokens are consumed. Since the names only
+// depend on the source code, they are cached to parse every definition
+// only once in advance.
+func (p *Parser[V]) funcRunNames(tokenizer *Tokenizer) []string {
+	key := tokenizer.Peek().Start
+	if names, ok := tokenizer.funcRuns[key]; ok {
+		return names
+	}
+	if tokenizer.funcRuns == nil {
+		tokenizer.funcRuns = map[int][]string{}
+	}
+
+	np := *p
+	np.optimizer = nil
+	np.formatting = true
+	np.module = false
+	ahead := tokenizer.clone()
+	anyIdent := Identifiers[V](nil).AddMap("")
+
+	var names []string
+	var starts []int
+	for {
+		t := ahead.Peek()
+		if t.typ != tKeyWord || t.image != "func" {
+			break
+		}
+		cl, err := np.parseFuncDef(ahead, anyIdent, nil, nil)
+		if err != nil {
+			break
+		}
+		names = append(names, cl.ThisName)
+		starts = append(starts, t.Start)
+	}
+
+	tokenizer.funcRuns[key] = names
+	for i, s := range starts {
+		tokenizer.funcRuns[s] = names[i:]
+	}
+	return names
+}
@@ -0,0 +1,536 @@
+package parser2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hneemann/parser2/listMap"
+)
+
+// JSONVersion is the version of the JSON encoding of an AST.
+// It is increased every time the encoding is changed. Version 2 added
+// patterns, function groups, constant definitions, imports, ranges, list
+// comprehensions, string interpolations, typed and default arguments, named
+// and piped calls and optional accesses. Documents of older versions are
+// still decoded, documents of newer versions are rejected.
+const JSONVersion = 2
+
+// jsonMinVersion is the oldest version which can be decoded
+const jsonMinVersion = 1
+
+// ConstCodec is used to encode and decode the values of Const nodes
+type ConstCodec[V any] interface {
+	// EncodeConst encodes the given value to JSON
+	EncodeConst(v V) (json.RawMessage, error)
+	// DecodeConst decodes a value created by EncodeConst
+	DecodeConst(data json.RawMessage) (V, error)
+}
+
+type jsonAST struct {
+	Version int       `json:"version"`
+	AST     *jsonNode `json:"ast"`
+}
+
+type jsonLine struct {
+	Num   int `json:"line"`
+	Pos   int `json:"pos"`
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type jsonEntry struct {
+	Key   string    `json:"key"`
+	Value *jsonNode `json:"value"`
+}
+
+type jsonCase struct {
+	Const *jsonNode  `json:"const,omitempty"`
+	Match *jsonMatch `json:"match,omitempty"`
+	Guard *jsonNode  `json:"guard,omitempty"`
+	Value *jsonNode  `json:"value"`
+}
+
+type jsonMatch struct {
+	Kind    string       `json:"kind"`
+	Line    *jsonLine    `json:"line,omitempty"`
+	Name    string       `json:"name,omitempty"`
+	Value   *jsonNode    `json:"value,omitempty"`
+	Keys    []string     `json:"keys,omitempty"`
+	Items   []*jsonMatch `json:"items,omitempty"`
+	HasRest bool         `json:"hasRest,omitempty"`
+}
+
+type jsonGenerator struct {
+	Name string    `json:"name"`
+	List *jsonNode `json:"list"`
+	Cond *jsonNode `json:"cond,omitempty"`
+}
+
+type jsonPattern struct {
+	IsMap bool      `json:"isMap,omitempty"`
+	Names []string  `json:"names"`
+	Line  *jsonLine `json:"line,omitempty"`
+}
+
+type jsonNode struct {
+	Type          string          `json:"type"`
+	Line          *jsonLine       `json:"line,omitempty"`
+	Name          string          `json:"name,omitempty"`
+	Operator      string          `json:"op,omitempty"`
+	Priority      int             `json:"priority,omitempty"`
+	Associativity Associativity   `json:"assoc,omitempty"`
+	Names         []string        `json:"names,omitempty"`
+	Types         []string        `json:"types,omitempty"`
+	OuterIdents   []string        `json:"outer,omitempty"`
+	Recursive     bool            `json:"recursive,omitempty"`
+	Piped         bool            `json:"piped,omitempty"`
+	Optional      bool            `json:"optional,omitempty"`
+	Module        string          `json:"module,omitempty"`
+	ThisName      string          `json:"this,omitempty"`
+	Const         json.RawMessage `json:"const,omitempty"`
+	Expr          *jsonNode       `json:"expr,omitempty"`
+	A             *jsonNode       `json:"a,omitempty"`
+	B             *jsonNode       `json:"b,omitempty"`
+	Cond          *jsonNode       `json:"cond,omitempty"`
+	Then          *jsonNode       `json:"then,omitempty"`
+	Else          *jsonNode       `json:"else,omitempty"`
+	Inner         *jsonNode       `json:"inner,omitempty"`
+	Func          *jsonNode       `json:"func,omitempty"`
+	Index         *jsonNode       `json:"index,omitempty"`
+	Args          []*jsonNode     `json:"args,omitempty"`
+	Defaults      []*jsonNode     `json:"defaults,omitempty"`
+	Entries       []jsonEntry     `json:"entries,omitempty"`
+	Cases         []jsonCase      `json:"cases,omitempty"`
+	Pattern       *jsonPattern    `json:"pattern,omitempty"`
+	Generators    []jsonGenerator `json:"generators,omitempty"`
+}
+
+// EncodeJSON encodes the given AST to JSON.
+// The values of constants are encoded by the given codec.
+// The source code itself is not stored, so the positions of
+// the decoded AST are not able to create an excerpt.
+func EncodeJSON[V any](ast AST, codec ConstCodec[V]) ([]byte, error) {
+	e := encoder[V]{codec: codec}
+	n := e.node(ast)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return json.Marshal(jsonAST{Version: JSONVersion, AST: n})
+}
+
+// DecodeJSON decodes an AST created by EncodeJSON.
+// The values of constants are decoded by the given codec.
+func DecodeJSON[V any](data []byte, codec ConstCodec[V]) (AST, error) {
+	var j jsonAST
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AST encoding: %w", err)
+	}
+	if j.Version < jsonMinVersion || j.Version > JSONVersion {
+		return nil, fmt.Errorf("unsupported AST encoding version %d, supported are %d to %d", j.Version, jsonMinVersion, JSONVersion)
+	}
+	d := decoder[V]{codec: codec}
+	ast := d.node(j.AST)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return ast, nil
+}
+
+func encodeLine(l Line) *jsonLine {
+	if l.Num <= 0 {
+		return nil
+	}
+	return &jsonLine{Num: l.Num, Pos: l.Pos, Start: l.Start, End: l.End}
+}
+
+func decodeLine(l *jsonLine) Line {
+	if l == nil {
+		return Line{}
+	}
+	return Line{Num: l.Num, Pos: l.Pos, Start: l.Start, End: l.End}
+}
+
+// encoder encodes an AST. The first error that occurs is stored,
+// all further encoding steps are skipped.
+type encoder[V any] struct {
+	codec ConstCodec[V]
+	err   error
+}
+
+func (e *encoder[V]) nodes(asts []AST) []*jsonNode {
+	nodes := make([]*jsonNode, len(asts))
+	for i, a := range asts {
+		nodes[i] = e.node(a)
+	}
+	return nodes
+}
+
+func (e *encoder[V]) node(ast AST) *jsonNode {
+	if ast == nil || e.err != nil {
+		return nil
+	}
+	n := &jsonNode{Line: encodeLine(ast.GetLine())}
+	switch a := ast.(type) {
+	case *Let:
+		n.Type = "let"
+		n.Name = a.Name
+		if d := a.Destructuring; d != nil {
+			n.Pattern = &jsonPattern{IsMap: d.IsMap, Names: d.Names, Line: encodeLine(d.Line)}
+		}
+		n.Expr = e.node(a.Value)
+		n.Inner = e.node(a.Inner)
+	case *FuncGroup:
+		n.Type = "funcGroup"
+		n.Args = make([]*jsonNode, len(a.Funcs))
+		for i, cl := range a.Funcs {
+			n.Args[i] = e.node(cl)
+		}
+		n.Inner = e.node(a.Inner)
+	case *ConstDef:
+		n.Type = "constDef"
+		n.Name = a.Name
+		n.Expr = e.node(a.Value)
+		n.Inner = e.node(a.Inner)
+	case *Import:
+		n.Type = "import"
+		n.Name = a.Name
+		n.Module = a.Module
+		n.Inner = e.node(a.Inner)
+	case *If:
+		n.Type = "if"
+		n.Cond = e.node(a.Cond)
+		n.Then = e.node(a.Then)
+		n.Else = e.node(a.Else)
+	case *TryCatch:
+		n.Type = "try"
+		n.A = e.node(a.Try)
+		n.B = e.node(a.Catch)
+	case *Switch[V]:
+		n.Type = "switch"
+		n.Expr = e.node(a.SwitchValue)
+		n.Cases = make([]jsonCase, len(a.Cases))
+		for i, c := range a.Cases {
+			if c.Pattern != nil {
+				n.Cases[i] = jsonCase{Match: e.pattern(c.Pattern), Guard: e.node(c.Guard), Value: e.node(c.Value)}
+			} else {
+				n.Cases[i] = jsonCase{Const: e.node(c.CaseConst), Value: e.node(c.Value)}
+			}
+		}
+		n.Else = e.node(a.Default)
+	case *Operate:
+		n.Type = "op"
+		n.Operator = a.Operator
+		n.Priority = a.Priority
+		n.Associativity = a.Associativity
+		n.A = e.node(a.A)
+		n.B = e.node(a.B)
+	case *Unary:
+		n.Type = "unary"
+		n.Operator = a.Operator
+		n.Expr = e.node(a.Value)
+	case *MapAccess:
+		n.Type = "mapAccess"
+		n.Name = a.Key
+		n.Optional = a.Optional
+		n.Expr = e.node(a.MapValue)
+	case *MethodCall:
+		n.Type = "methodCall"
+		n.Name = a.Name
+		n.Optional = a.Optional
+		n.Expr = e.node(a.Value)
+		n.Args = e.nodes(a.Args)
+	case *ListAccess:
+		n.Type = "listAccess"
+		n.Expr = e.node(a.List)
+		n.Index = e.node(a.Index)
+	case *ClosureLiteral:
+		n.Type = "closure"
+		n.Names = a.Names
+		n.Types = a.Types
+		if len(a.Defaults) > 0 {
+			n.Defaults = e.nodes(a.Defaults)
+		}
+		n.Func = e.node(a.Func)
+		n.OuterIdents = a.OuterIdents
+		n.Recursive = a.Recursive
+		n.ThisName = a.ThisName
+	case *Range:
+		n.Type = "range"
+		n.A = e.node(a.From)
+		n.B = e.node(a.To)
+		n.Expr = e.node(a.Step)
+	case *ListComprehension:
+		n.Type = "comprehension"
+		n.Expr = e.node(a.Value)
+		n.OuterIdents = a.OuterIdents
+		n.Generators = make([]jsonGenerator, len(a.Generators))
+		for i, g := range a.Generators {
+			n.Generators[i] = jsonGenerator{Name: g.Name, List: e.node(g.List), Cond: e.node(g.Cond)}
+		}
+	case *MapLiteral:
+		n.Type = "map"
+		n.Entries = make([]jsonEntry, 0, a.Map.Size())
+		a.Map.Iter(func(key string, v AST) bool {
+			n.Entries = append(n.Entries, jsonEntry{Key: key, Value: e.node(v)})
+			return true
+		})
+	case *ListLiteral:
+		n.Type = "list"
+		n.Args = e.nodes(a.List)
+	case *Interpolation:
+		n.Type = "interpolation"
+		n.Names = a.Strings
+		n.Args = e.nodes(a.Values)
+	case *Ident:
+		n.Type = "ident"
+		n.Name = a.Name
+	case *Const[V]:
+		n.Type = "const"
+		c, err := e.codec.EncodeConst(a.Value)
+		if err != nil {
+			e.err = a.EnhanceErrorf(err, "could not encode constant")
+		}
+		n.Const = c
+	case *FunctionCall:
+		n.Type = "call"
+		n.Names = a.ArgNames
+		n.Piped = a.Piped
+		n.Func = e.node(a.Func)
+		n.Args = e.nodes(a.Args)
+	case *ErrorNode:
+		n.Type = "error"
+		n.Name = a.Err.Error()
+	default:
+		e.err = fmt.Errorf("AST node %T can not be encoded", ast)
+	}
+	return n
+}
+
+func (e *encoder[V]) pattern(p Pattern) *jsonMatch {
+	m := &jsonMatch{Line: encodeLine(p.GetLine())}
+	switch pa := p.(type) {
+	case *BindPattern:
+		m.Kind = "bind"
+		m.Name = pa.Name
+	case *TypePattern:
+		m.Kind = "type"
+		m.Name = pa.Type
+	case *ValuePattern:
+		m.Kind = "value"
+		m.Value = e.node(pa.Value)
+	case *ListPattern:
+		m.Kind = "list"
+		for _, item := range pa.Items {
+			m.Items = append(m.Items, e.pattern(item))
+		}
+		m.HasRest = pa.HasRest
+		m.Name = pa.Rest
+	case *MapPattern:
+		m.Kind = "map"
+		for _, en := range pa.Entries {
+			m.Keys = append(m.Keys, en.Key)
+			m.Items = append(m.Items, e.pattern(en.Pattern))
+		}
+	default:
+		e.err = fmt.Errorf("pattern %T can not be encoded", p)
+	}
+	return m
+}
+
+// decoder decodes an AST. The first error that occurs is stored,
+// all further decoding steps are skipped.
+type decoder[V any] struct {
+	codec ConstCodec[V]
+	err   error
+}
+
+func (d *decoder[V]) nodes(nodes []*jsonNode) []AST {
+	asts := make([]AST, len(nodes))
+	for i, n := range nodes {
+		asts[i] = d.node(n)
+	}
+	return asts
+}
+
+func (d *decoder[V]) node(n *jsonNode) AST {
+	if d.err != nil {
+		return nil
+	}
+	if n == nil {
+		d.err = errors.New("missing AST node")
+		return nil
+	}
+	line := decodeLine(n.Line)
+	switch n.Type {
+	case "let":
+		l := &Let{Name: n.Name, Value: d.node(n.Expr), Inner: d.node(n.Inner), Line: line}
+		if p := n.Pattern; p != nil {
+			l.Destructuring = &Destructuring{IsMap: p.IsMap, Names: p.Names, Line: decodeLine(p.Line)}
+		}
+		return l
+	case "funcGroup":
+		g := &FuncGroup{Line: line}
+		for _, a := range d.nodes(n.Args) {
+			cl, ok := a.(*ClosureLiteral)
+			if !ok {
+				if d.err == nil {
+					d.err = errors.New("invalid func group")
+				}
+				return nil
+			}
+			g.Funcs = append(g.Funcs, cl)
+		}
+		if len(g.Funcs) == 0 {
+			d.err = errors.New("invalid func group")
+			return nil
+		}
+		g.Inner = d.node(n.Inner)
+		return g
+	case "constDef":
+		return &ConstDef{Name: n.Name, Value: d.node(n.Expr), Inner: d.node(n.Inner), Line: line}
+	case "import":
+		return &Import{Module: n.Module, Name: n.Name, Inner: d.node(n.Inner), Line: line}
+	case "if":
+		return &If{Cond: d.node(n.Cond), Then: d.node(n.Then), Else: d.node(n.Else), Line: line}
+	case "try":
+		return &TryCatch{Try: d.node(n.A), Catch: d.node(n.B), Line: line}
+	case "switch":
+		s := &Switch[V]{SwitchValue: d.node(n.Expr), Default: d.node(n.Else), Line: line}
+		for _, c := range n.Cases {
+			if c.Match != nil {
+				var guard AST
+				if c.Guard != nil {
+					guard = d.node(c.Guard)
+				}
+				s.Cases = append(s.Cases, Case[V]{Pattern: d.pattern(c.Match), Guard: guard, Value: d.node(c.Value)})
+			} else {
+				s.Cases = append(s.Cases, Case[V]{CaseConst: d.node(c.Const), Value: d.node(c.Value)})
+			}
+		}
+		return s
+	case "op":
+		return &Operate{
+			Operator:      n.Operator,
+			Priority:      n.Priority,
+			Associativity: n.Associativity,
+			A:             d.node(n.A),
+			B:             d.node(n.B),
+			Line:          line,
+		}
+	case "unary":
+		return &Unary{Operator: n.Operator, Value: d.node(n.Expr), Line: line}
+	case "mapAccess":
+		return &MapAccess{Key: n.Name, MapValue: d.node(n.Expr), Optional: n.Optional, Line: line}
+	case "methodCall":
+		return &MethodCall{Name: n.Name, Args: d.nodes(n.Args), Value: d.node(n.Expr), Optional: n.Optional, Line: line}
+	case "listAccess":
+		return &ListAccess{Index: d.node(n.Index), List: d.node(n.Expr), Line: line}
+	case "closure":
+		var defaults []AST
+		if len(n.Defaults) > 0 {
+			defaults = d.nodes(n.Defaults)
+		}
+		return &ClosureLiteral{
+			Names:       n.Names,
+			Defaults:    defaults,
+			Types:       n.Types,
+			Func:        d.node(n.Func),
+			Line:        line,
+			OuterIdents: n.OuterIdents,
+			Recursive:   n.Recursive,
+			ThisName:    n.ThisName,
+		}
+	case "range":
+		r := &Range{From: d.node(n.A), To: d.node(n.B), Line: line}
+		if n.Expr != nil {
+			r.Step = d.node(n.Expr)
+		}
+		return r
+	case "comprehension":
+		if len(n.Generators) == 0 {
+			d.err = errors.New("invalid list comprehension")
+			return nil
+		}
+		l := &ListComprehension{Value: d.node(n.Expr), OuterIdents: n.OuterIdents, Line: line}
+		for _, g := range n.Generators {
+			gen := &Generator{Name: g.Name, List: d.node(g.List)}
+			if g.Cond != nil {
+				gen.Cond = d.node(g.Cond)
+			}
+			l.Generators = append(l.Generators, gen)
+		}
+		return l
+	case "map":
+		m := listMap.New[AST](len(n.Entries))
+		for _, e := range n.Entries {
+			m = m.Append(e.Key, d.node(e.Value))
+		}
+		return &MapLiteral{Map: m, Line: line}
+	case "list":
+		return &ListLiteral{List: d.nodes(n.Args), Line: line}
+	case "interpolation":
+		if len(n.Names) != len(n.Args)+1 {
+			d.err = errors.New("invalid string interpolation")
+			return nil
+		}
+		return &Interpolation{Strings: n.Names, Values: d.nodes(n.Args), Line: line}
+	case "ident":
+		return &Ident{Name: n.Name, Line: line}
+	case "const":
+		v, err := d.codec.DecodeConst(n.Const)
+		if err != nil {
+			d.err = line.EnhanceErrorf(err, "could not decode constant")
+		}
+		return &Const[V]{Value: v, Line: line}
+	case "call":
+		if n.Names != nil && len(n.Names) != len(n.Args) {
+			d.err = errors.New("invalid argument names")
+			return nil
+		}
+		return &FunctionCall{Func: d.node(n.Func), Args: d.nodes(n.Args), ArgNames: n.Names, Piped: n.Piped, Line: line}
+	case "error":
+		return &ErrorNode{Err: errors.New(n.Name), Line: line}
+	default:
+		d.err = fmt.Errorf("unknown AST node type '%s'", n.Type)
+		return nil
+	}
+}
+
+func (d *decoder[V]) pattern(m *jsonMatch) Pattern {
+	if d.err != nil {
+		return nil
+	}
+	if m == nil {
+		d.err = errors.New("missing pattern")
+		return nil
+	}
+	line := decodeLine(m.Line)
+	switch m.Kind {
+	case "bind":
+		return &BindPattern{Name: m.Name, Line: line}
+	case "type":
+		return &TypePattern{Type: m.Name, Line: line}
+	case "value":
+		return &ValuePattern{Value: d.node(m.Value), Line: line}
+	case "list":
+		l := &ListPattern{HasRest: m.HasRest, Rest: m.Name, Line: line}
+		for _, item := range m.Items {
+			l.Items = append(l.Items, d.pattern(item))
+		}
+		return l
+	case "map":
+		if len(m.Keys) != len(m.Items) {
+			d.err = errors.New("invalid map pattern")
+			return nil
+		}
+		p := &MapPattern{Line: line}
+		for i, key := range m.Keys {
+			p.Entries = append(p.Entries, MapPatternEntry{Key: key, Pattern: d.pattern(m.Items[i])})
+		}
+		return p
+	default:
+		d.err = fmt.Errorf("unknown pattern kind '%s'", m.Kind)
+		return nil
+	}
+}
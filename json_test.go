@@ -0,0 +1,122 @@
+package parser2
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type intCodec struct{}
+
+func (intCodec) EncodeConst(v int) (json.RawMessage, error) {
+	return json.Marshal(v)
+}
+
+func (intCodec) DecodeConst(data json.RawMessage) (int, error) {
+	var i int
+	err := json.Unmarshal(data, &i)
+	return i, err
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		exp  string
+		args []string
+	}{
+		{exp: "-x*(-2)", args: []string{"x"}},
+		{exp: "(a,b)->a*b*(1+1)", args: []string{"a", "b"}},
+		{exp: "a[1+1](2+2)", args: []string{"a"}},
+		{exp: "a.m(x,2).n[x]", args: []string{"a", "x"}},
+		{exp: "[x,2+2,3]", args: []string{"x"}},
+		{exp: "{a:x, b:2*2}", args: []string{"x"}},
+		{exp: "let v=x+1; 2+v", args: []string{"x"}},
+		{exp: "if x<1 then 1 else x", args: []string{"x"}},
+		{exp: "try x catch 2", args: []string{"x"}},
+		{exp: "switch a case 0:1 case 1:10 default 100", args: []string{"a"}},
+		{exp: "func fac(n) if n<2 then 1 else n*fac(n-1); fac(x)", args: []string{"x"}},
+		{exp: "x |> a(2)", args: []string{"a", "x"}},
+		{exp: "a?.b?.m(x)", args: []string{"a", "x"}},
+		{exp: "import \"m\" as m; m.f(x)", args: []string{"x"}},
+		{exp: "const c=2; c*x", args: []string{"x"}},
+		{exp: "func f(n) g(n); func g(n) if n<1 then 0 else f(n-1); f(x)", args: []string{"x"}},
+		{exp: "[a*b for a in x for b in 1..a step 2 if b>1]", args: []string{"x"}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.exp, func(t *testing.T) {
+			var idents Identifiers[int]
+			for _, arg := range test.args {
+				idents = idents.Add(arg)
+			}
+			ast, err := parser.Parse(test.exp, idents)
+			assert.NoError(t, err, test.exp)
+
+			data, err := EncodeJSON[int](ast, intCodec{})
+			assert.NoError(t, err, test.exp)
+
+			decoded, err := DecodeJSON[int](data, intCodec{})
+			assert.NoError(t, err, test.exp)
+			assert.EqualValues(t, ast.String(), decoded.String())
+			assert.EqualValues(t, ast.GetLine().Num, decoded.GetLine().Num)
+			assert.EqualValues(t, ast.GetLine().Pos, decoded.GetLine().Pos)
+			assert.EqualValues(t, ast.GetLine().Start, decoded.GetLine().Start)
+			assert.EqualValues(t, ast.GetLine().End, decoded.GetLine().End)
+		})
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{name: "version", json: `{"version":3,"ast":{"type":"ident","name":"a"}}`, err: "version 3"},
+		{name: "noVersion", json: `{"ast":{"type":"ident","name":"a"}}`, err: "version 0"},
+		{name: "type", json: `{"version":1,"ast":{"type":"unknown"}}`, err: "unknown AST node type"},
+		{name: "missing", json: `{"version":1,"ast":{"type":"op","op":"+","a":{"type":"ident","name":"a"}}}`, err: "missing AST node"},
+		{name: "const", json: `{"version":1,"ast":{"type":"const","const":"x"}}`, err: "could not decode constant"},
+		{name: "invalid", json: `{"version":1`, err: "invalid AST encoding"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecodeJSON[int]([]byte(test.json), intCodec{})
+			assert.Error(t, err)
+			if err != nil {
+				assert.Contains(t, err.Error(), test.err)
+			}
+		})
+	}
+}
+
+// jsonVersion1 is the encoding of "let f=a->a*x; switch f(2) case 4: 1 default -1"
+// created by the first version of the encoder
+const jsonVersion1 = `{"version":1,"ast":{"type":"let","line":{"line":1,"pos":4,"start":0,"end":13},"name":"f",` +
+	`"expr":{"type":"closure","line":{"line":1,"pos":6,"start":6,"end":12},"names":["a"],"outer":["x"],` +
+	`"func":{"type":"op","line":{"line":1,"pos":10,"start":9,"end":12},"op":"*","priority":5,` +
+	`"a":{"type":"ident","line":{"line":1,"pos":9,"start":9,"end":10},"name":"a"},` +
+	`"b":{"type":"ident","line":{"line":1,"pos":11,"start":11,"end":12},"name":"x"}}},` +
+	`"inner":{"type":"switch","line":{"line":1,"pos":36,"start":14,"end":46},` +
+	`"expr":{"type":"call","line":{"line":1,"pos":22,"start":21,"end":25},` +
+	`"func":{"type":"ident","line":{"line":1,"pos":21,"start":21,"end":22},"name":"f"},` +
+	`"args":[{"type":"const","line":{"line":1,"pos":23,"start":23,"end":24},"const":2}]},` +
+	`"else":{"type":"unary","line":{"line":1,"pos":44,"start":44,"end":46},"op":"-",` +
+	`"expr":{"type":"const","line":{"line":1,"pos":45,"start":45,"end":46},"const":1}},` +
+	`"cases":[{"const":{"type":"const","line":{"line":1,"pos":31,"start":31,"end":32},"const":4},` +
+	`"value":{"type":"const","line":{"line":1,"pos":34,"start":34,"end":35},"const":1}}]}}}`
+
+func TestJSONVersion1(t *testing.T) {
+	const exp = "let f=a->a*x; switch f(2) case 4: 1 default -1"
+	var idents Identifiers[int]
+	ast, err := parser.Parse(exp, idents.Add("x"))
+	assert.NoError(t, err)
+
+	decoded, err := DecodeJSON[int]([]byte(jsonVersion1), intCodec{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, ast.String(), decoded.String())
+
+	data, err := EncodeJSON[int](decoded, intCodec{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"version":2`)
+}
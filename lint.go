@@ -0,0 +1,340 @@
+package parser2
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LintRule identifies a rule checked by the linter
+type LintRule string
+
+const (
+	// LintUnusedLet reports let, func, const and import definitions which are never used
+	LintUnusedLet LintRule = "unused-let"
+	// LintUnusedParam reports closure parameters which are never used
+	LintUnusedParam LintRule = "unused-param"
+	// LintShadowing reports names which shadow a static function or a constant
+	LintShadowing LintRule = "shadowing"
+	// LintConstCondition reports if conditions which the optimizer folds to a constant
+	LintConstCondition LintRule = "const-condition"
+	// LintUnreachableCase reports switch cases which can never match
+	LintUnreachableCase LintRule = "unreachable-case"
+)
+
+// Warning is a problem found by the linter
+type Warning struct {
+	Rule    LintRule
+	Message string
+	Line
+}
+
+func (w Warning) String() string {
+	m := w.Message
+	if w.Num > 0 {
+		m += " in line " + strconv.Itoa(w.Num)
+	}
+	return m + " [" + string(w.Rule) + "]"
+}
+
+// Lint checks the given source code for problems which do not prevent the
+// code from being executed, but which are probably mistakes. The warnings are
+// ordered by their position in the source code. The given rules are not
+// checked. Names starting with an underscore are never reported as unused.
+// An error is returned if the source code can not be parsed.
+func (p *Parser[V]) Lint(src string, idents Identifiers[V], disabled ...LintRule) ([]Warning, error) {
+	if err := p.checkParse(src, idents); err != nil {
+		return nil, err
+	}
+	l := &linter[V]{idents: idents, disabled: disabled}
+
+	np := *p
+	np.optimizer = nil
+	np.formatting = true
+	tokenizer := np.newTokenizer(src)
+	ast, err := np.parseLet(tokenizer, idents)
+	if err != nil {
+		return nil, err
+	}
+	if t := tokenizer.Next(); t.typ != tEof {
+		return nil, unexpected("EOF", t)
+	}
+	ast.Traverse(lintVisitor[V]{l: l})
+
+	if p.optimizer != nil && l.enabled(LintConstCondition) {
+		// The conditions are detected by the optimizer itself,
+		// so the constants used in the condition are taken into account.
+		reported := map[int]bool{}
+		np := *p
+		np.debug = false
+		np.optimizer = OptimizerFunc(func(ast AST) AST {
+			if i, ok := ast.(*If); ok {
+				if c, ok := i.Cond.(*Const[V]); ok && !reported[c.Start] {
+					reported[c.Start] = true
+					l.warn(LintConstCondition, c.Line, "condition '%s' is constant", c.Source())
+				}
+			}
+			return p.optimizer.Optimize(ast)
+		})
+		if _, err := np.Parse(src, idents); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(l.warnings, func(i, j int) bool {
+		return l.warnings[i].Start < l.warnings[j].Start
+	})
+	return l.warnings, nil
+}
+
+type linter[V any] struct {
+	idents   Identifiers[V]
+	disabled []LintRule
+	warnings []Warning
+}
+
+func (l *linter[V]) enabled(rule LintRule) bool {
+	for _, d := range l.disabled {
+		if d == rule {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *linter[V]) warn(rule LintRule, line Line, m string, a ...any) {
+	if l.enabled(rule) {
+		l.warnings = append(l.warnings, Warning{
+			Rule:    rule,
+			Message: fmt.Sprintf(m, a...),
+			Line:    line,
+		})
+	}
+}
+
+type bindingKind int
+
+const (
+	bindLet bindingKind = iota
+	bindConst
+	bindParam
+	// bindOther is used for names which are never reported as unused
+	// like the variables of patterns or the name of a function in its body
+	bindOther
+)
+
+// binding is a name defined in the source code
+type binding struct {
+	name   string
+	kind   bindingKind
+	line   Line
+	used   bool
+	parent *binding
+}
+
+// lintVisitor traverses the AST and keeps track of the names in scope
+type lintVisitor[V any] struct {
+	l     *linter[V]
+	scope *binding
+}
+
+func (v lintVisitor[V]) lookup(name string) *binding {
+	for b := v.scope; b != nil; b = b.parent {
+		if b.name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// define adds the given name to the scope. A warning is created if the
+// name shadows a static function or a constant.
+func (v lintVisitor[V]) define(name string, kind bindingKind, line Line) lintVisitor[V] {
+	if kind != bindOther {
+		if b := v.lookup(name); b != nil {
+			if b.kind == bindConst {
+				v.l.warn(LintShadowing, line, "'%s' shadows a constant", name)
+			}
+		} else if v.l.idents != nil {
+			if i, ok := v.l.idents(name); ok && i.IsConst {
+				if i.IsFunc {
+					v.l.warn(LintShadowing, line, "'%s' shadows a static function", name)
+				} else {
+					v.l.warn(LintShadowing, line, "'%s' shadows a constant", name)
+				}
+			}
+		}
+	}
+	v.scope = &binding{name: name, kind: kind, line: line, parent: v.scope}
+	return v
+}
+
+// checkUsed reports all unused names defined in the scope of the
+// given visitor which are not defined in the outer scope.
+func (v lintVisitor[V]) checkUsed(outer lintVisitor[V]) {
+	for b := v.scope; b != nil && b != outer.scope; b = b.parent {
+		if b.used || strings.HasPrefix(b.name, "_") {
+			continue
+		}
+		switch b.kind {
+		case bindLet, bindConst:
+			v.l.warn(LintUnusedLet, b.line, "'%s' is never used", b.name)
+		case bindParam:
+			if !isDestructuredArg(b.name) {
+				v.l.warn(LintUnusedParam, b.line, "parameter '%s' is never used", b.name)
+			}
+		}
+	}
+}
+
+func (v lintVisitor[V]) Visit(ast AST) bool {
+	switch a := ast.(type) {
+	case *Ident:
+		if b := v.lookup(a.Name); b != nil {
+			b.used = true
+		}
+	case *Let:
+		a.Value.Traverse(v)
+		inner := v
+		if a.Destructuring != nil {
+			kind := bindLet
+			if isDestructuredArgLet(a) {
+				kind = bindParam
+			}
+			for _, n := range a.Destructuring.Names {
+				inner = inner.define(n, kind, a.Destructuring.Line)
+			}
+		} else {
+			inner = inner.define(a.Name, bindLet, a.Line)
+		}
+		a.Inner.Traverse(inner)
+		inner.checkUsed(v)
+	case *ConstDef:
+		a.Value.Traverse(v)
+		inner := v.define(a.Name, bindConst, a.Line)
+		a.Inner.Traverse(inner)
+		inner.checkUsed(v)
+	case *Import:
+		inner := v.define(a.Name, bindLet, a.Line)
+		a.Inner.Traverse(inner)
+		inner.checkUsed(v)
+	case *FuncGroup:
+		inner := v
+		for _, cl := range a.Funcs {
+			inner = inner.define(cl.ThisName, bindLet, cl.Line)
+		}
+		for _, cl := range a.Funcs {
+			cl.Traverse(inner)
+		}
+		a.Inner.Traverse(inner)
+		inner.checkUsed(v)
+	case *ClosureLiteral:
+		for _, d := range a.Defaults {
+			d.Traverse(v)
+		}
+		inner := v
+		for _, n := range a.Names {
+			inner = inner.define(n, bindParam, a.Line)
+		}
+		if a.ThisName != "" {
+			inner = inner.define(a.ThisName, bindOther, a.Line)
+		}
+		a.Func.Traverse(inner)
+		inner.checkUsed(v)
+	case *Switch[V]:
+		v.visitSwitch(a)
+	case *ListComprehension:
+		a.Generators[0].List.Traverse(v)
+		inner := v
+		for i, g := range a.Generators {
+			if i > 0 {
+				g.List.Traverse(inner)
+			}
+			inner = inner.define(g.Name, bindOther, a.Line)
+			if g.Cond != nil {
+				g.Cond.Traverse(inner)
+			}
+		}
+		a.Value.Traverse(inner)
+	default:
+		return true
+	}
+	return false
+}
+
+// visitSwitch traverses a switch and reports the cases which can never
+// match because an earlier case without a guard matches the same values.
+func (v lintVisitor[V]) visitSwitch(s *Switch[V]) {
+	s.SwitchValue.Traverse(v)
+	matched := map[string]bool{}
+	matchesAll := false
+	for _, c := range s.Cases {
+		var line Line
+		var key string
+		if c.Pattern != nil {
+			line = c.Pattern.GetLine()
+			key = patternKey(c.Pattern)
+		} else {
+			line = c.CaseConst.GetLine()
+			key = "value " + c.CaseConst.String()
+			c.CaseConst.Traverse(v)
+		}
+		if matchesAll {
+			v.l.warn(LintUnreachableCase, line, "case is never reached because an earlier case matches all values")
+		} else if key != "" && matched[key] {
+			v.l.warn(LintUnreachableCase, line, "case is never reached because an earlier case matches the same values")
+		}
+		if c.Guard == nil {
+			if key == "all" {
+				matchesAll = true
+			} else if key != "" {
+				matched[key] = true
+			}
+		}
+
+		inner := v
+		if c.Pattern != nil {
+			traversePatternValues(c.Pattern, v)
+			for _, n := range PatternVars(c.Pattern) {
+				inner = inner.define(n, bindOther, line)
+			}
+		}
+		if c.Guard != nil {
+			c.Guard.Traverse(inner)
+		}
+		c.Value.Traverse(inner)
+	}
+	s.Default.Traverse(v)
+}
+
+// patternKey returns a key which is equal for patterns matching the same
+// values. If the pattern matches all values, "all" is returned. If
+// the values matched by the pattern are not obvious, "" is returned.
+func patternKey(p Pattern) string {
+	switch pa := p.(type) {
+	case *BindPattern:
+		return "all"
+	case *TypePattern:
+		return "type " + pa.Type
+	case *ValuePattern:
+		return "value " + pa.Value.String()
+	}
+	return ""
+}
+
+// traversePatternValues traverses the values used by a pattern
+func traversePatternValues(p Pattern, visitor Visitor) {
+	switch pa := p.(type) {
+	case *ValuePattern:
+		pa.Value.Traverse(visitor)
+	case *ListPattern:
+		for _, item := range pa.Items {
+			traversePatternValues(item, visitor)
+		}
+	case *MapPattern:
+		for _, e := range pa.Entries {
+			traversePatternValues(e.Pattern, visitor)
+		}
+	}
+}
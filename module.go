@@ -0,0 +1,120 @@
+package parser2
+
+import (
+	"github.com/hneemann/parser2/listMap"
+	"strconv"
+)
+
+// Import imports a module like in 'import "stats" as s;'. The module
+// is resolved by the generator. The top-level definitions of the module
+// are available in Inner as a map stored in the variable Name.
+type Import struct {
+	Module string
+	Name   string
+	Inner  AST
+	Line
+}
+
+func (i *Import) Traverse(visitor Visitor) {
+	if visitor.Visit(i) {
+		i.Inner.Traverse(visitor)
+	}
+}
+
+func (i *Import) Optimize(optimizer Optimizer) {
+	i.Inner = opt(i.Inner, optimizer)
+}
+
+func (i *Import) String() string {
+	return "import " + strconv.Quote(i.Module) + " as " + i.Name + "; " + i.Inner.String()
+}
+
+// parseImport parses an import like 'import "stats" as s;'.
+// The keyword "import" is already consumed.
+func (p *Parser[V]) parseImport(tokenizer *Tokenizer, start Line, idents Identifiers[V]) (AST, error) {
+	t := tokenizer.Next()
+	if t.typ != tString {
+		return nil, t.Errorf("no module name followed by import")
+	}
+	module := t.image
+	line := t.GetLine()
+	if t := tokenizer.Next(); t.typ != tIdent || t.image != "as" {
+		return nil, unexpected("as", t)
+	}
+	t = tokenizer.Next()
+	if t.typ != tIdent {
+		return nil, t.Errorf("no identifier followed by as")
+	}
+	name := t.image
+	if err := expect(tokenizer, tSemicolon, ";"); err != nil {
+		return nil, err
+	}
+	line = line.span(start, tokenizer.Last().End)
+
+	inner, err := p.parseLet(tokenizer, idents.Add(name))
+	if err != nil {
+		return nil, err
+	}
+	return &Import{
+		Module: module,
+		Name:   name,
+		Inner:  inner,
+		Line:   line,
+	}, nil
+}
+
+// ParseModule parses the source code of a module. A module contains only
+// func, let, const and import definitions. The returned AST evaluates to a
+// map which contains the values of the top-level funcs, lets and consts of
+// the module.
+// Since modules are usually stored in files, comments are always allowed.
+func (p *Parser[V]) ParseModule(str string, idents Identifiers[V]) (AST, error) {
+	np := *p
+	np.module = true
+	np.allowComments = true
+	tokenizer := np.newTokenizer(str)
+
+	ast, err := np.parseLet(tokenizer, idents)
+	if err != nil {
+		return nil, err
+	}
+	if t := tokenizer.Next(); t.typ != tEof {
+		return nil, unexpected("EOF", t)
+	}
+
+	exports := listMap.New[AST](10)
+	last := &ast
+	for {
+		if l, ok := (*last).(*Let); ok {
+			if l.Destructuring != nil {
+				for _, name := range l.Destructuring.Names {
+					exports = exports.Append(name, &Ident{Name: name, Line: l.Line})
+				}
+			} else {
+				exports = exports.Append(l.Name, &Ident{Name: l.Name, Line: l.Line})
+			}
+			last = &l.Inner
+		} else if g, ok := (*last).(*FuncGroup); ok {
+			for _, cl := range g.Funcs {
+				exports = exports.Append(cl.ThisName, &Ident{Name: cl.ThisName, Line: cl.Line})
+			}
+			last = &g.Inner
+		} else if i, ok := (*last).(*Import); ok {
+			last = &i.Inner
+		} else if c, ok := (*last).(*ConstDef); ok {
+			exports = exports.Append(c.Name, c.Value)
+			last = &c.Inner
+		} else {
+			break
+		}
+	}
+	if *last != np.moduleEnd {
+		return nil, (*last).GetLine().Errorf("a module may only contain func, let, const and import definitions")
+	}
+	np.moduleEnd.Map = exports
+
+	if np.optimizer != nil {
+		ast = Optimize(ast, np.optimizer)
+	}
+	return ast, nil
+}
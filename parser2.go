@@ -3,12 +3,14 @@ package parser2
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/hneemann/parser2/listMap"
 	"log"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type NotFoundError struct {
@@ -122,12 +124,88 @@ func Optimize(ast AST, optimizer Optimizer) (astRet AST) {
 	return optimizer.Optimize(ast)
 }
 
-type Line int
+// Line describes the position of a token or an AST node in the source code.
+// Num is the line number and Pos is the byte offset the node is anchored at,
+// e.g. the operator of an operation or the name of a method call.
+// Start and End are the byte offsets of the complete source range covered
+// by the node. A Line with Num <= 0 carries no position.
+type Line struct {
+	Num   int
+	Pos   int
+	Start int
+	End   int
+	src   string
+}
 
 func (l Line) GetLine() Line {
 	return l
 }
 
+// Column returns the column of the anchor position.
+// The first column is 1. If not known, zero is returned.
+func (l Line) Column() int {
+	if l.Num <= 0 || l.Pos > len(l.src) {
+		return 0
+	}
+	return utf8.RuneCountInString(l.src[lineStart(l.src, l.Pos):l.Pos]) + 1
+}
+
+// Source returns the part of the source code covered by the node.
+func (l Line) Source() string {
+	if l.Start < 0 || l.End > len(l.src) || l.Start > l.End {
+		return ""
+	}
+	return l.src[l.Start:l.End]
+}
+
+// Excerpt returns the source line containing the anchor position
+// followed by a line of carets marking the range covered by the node.
+// If the source is not known, an empty string is returned.
+func (l Line) Excerpt() string {
+	if l.Num <= 0 || l.src == "" || l.Pos > len(l.src) {
+		return ""
+	}
+	ls := lineStart(l.src, l.Pos)
+	le := strings.IndexByte(l.src[ls:], '\n')
+	if le < 0 {
+		le = len(l.src)
+	} else {
+		le += ls
+	}
+	line := strings.TrimRight(l.src[ls:le], "\r")
+
+	start := max(l.Start, ls)
+	end := min(l.End, ls+len(line))
+	if start > l.Pos || end <= start {
+		start = l.Pos
+		end = l.Pos + 1
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.ReplaceAll(line, "\t", " "))
+	b.WriteString("\n")
+	b.WriteString(strings.Repeat(" ", utf8.RuneCountInString(l.src[ls:min(start, le)])))
+	b.WriteString(strings.Repeat("^", max(1, utf8.RuneCountInString(l.src[min(start, le):min(end, le)]))))
+	return b.String()
+}
+
+// lineStart returns the offset of the first byte of the line containing pos
+func lineStart(src string, pos int) int {
+	return strings.LastIndexByte(src[:pos], '\n') + 1
+}
+
+// span returns a copy of the line which covers the source range
+// from the start of the given node to the given end offset.
+func (l Line) span(from Line, end int) Line {
+	if from.Num > 0 && from.Start < l.Start {
+		l.Start = from.Start
+	}
+	if end > l.End {
+		l.End = end
+	}
+	return l
+}
+
 type errorWithLine struct {
 	message string
 	line    Line
@@ -136,15 +214,41 @@ type errorWithLine struct {
 
 func (e errorWithLine) Error() string {
 	m := e.message
-	if e.line > 0 {
-		m += " in line " + strconv.Itoa(int(e.line))
+	if e.line.Num > 0 {
+		m += " in line " + strconv.Itoa(e.line.Num)
 	}
 	if e.cause != nil {
 		m += ";\n cause: " + e.cause.Error()
 	}
+	if e.line.Num > 0 && !hasPositionedCause(e.cause) {
+		// only the innermost error shows the excerpt
+		if ex := e.line.Excerpt(); ex != "" {
+			m += "\n" + ex
+		}
+	}
 	return m
 }
 
+func hasPositionedCause(err error) bool {
+	_, ok := GetErrorLine(err)
+	return ok
+}
+
+// GetErrorLine returns the innermost source position found in the
+// given error chain. If there is no position, false is returned.
+func GetErrorLine(err error) (Line, bool) {
+	var line Line
+	found := false
+	for err != nil {
+		if e, ok := err.(errorWithLine); ok && e.line.Num > 0 {
+			line = e.line
+			found = true
+		}
+		err = errors.Unwrap(err)
+	}
+	return line, found
+}
+
 func (e errorWithLine) Unwrap() error {
 	return e.cause
 }
@@ -181,9 +285,13 @@ func (l Line) EnhanceErrorf(cause any, m string, a ...any) error {
 }
 
 type Let struct {
-	Name  string
-	Value AST
-	Inner AST
+	Name string
+	// Destructuring is set if the value is a list or a map
+	// which is split into several variables. Name is empty
+	// in this case.
+	Destructuring *Destructuring
+	Value         AST
+	Inner         AST
 	Line
 }
 
@@ -195,7 +303,87 @@ func (l *Let) Traverse(visitor Visitor) {
 }
 
 func (l *Let) String() string {
-	return "let " + l.Name + "=" + l.Value.String() + "; " + l.Inner.String()
+	return "let " + l.VarName() + "=" + l.Value.String() + "; " + l.Inner.String()
+}
+
+// VarName returns the name of the variable or the
+// destructuring pattern if the value is destructured.
+func (l *Let) VarName() string {
+	if l.Destructuring != nil {
+		return l.Destructuring.String()
+	}
+	return l.Name
+}
+
+// Destructuring describes how a list or a map is split into several
+// variables, like in "let [a, b] = list;" or "let {a, b} = map;".
+type Destructuring struct {
+	// IsMap is set if a map is destructured
+	IsMap bool
+	// Names are the names of the variables which are also
+	// the keys of the map entries if a map is destructured
+	Names []string
+	Line
+}
+
+func (d *Destructuring) String() string {
+	if d.IsMap {
+		return "{" + stringsToString(d.Names) + "}"
+	}
+	return "[" + stringsToString(d.Names) + "]"
+}
+
+// ConstDef defines a constant like in "const WIDTH = 800;". The value is
+// folded to a constant during parsing and all usages of the constant
+// are replaced by its value. Value is a *Const[V] unless the source
+// code is formatted.
+type ConstDef struct {
+	Name  string
+	Value AST
+	Inner AST
+	Line
+}
+
+func (c *ConstDef) Traverse(visitor Visitor) {
+	if visitor.Visit(c) {
+		c.Value.Traverse(visitor)
+		c.Inner.Traverse(visitor)
+	}
+}
+
+func (c *ConstDef) Optimize(optimizer Optimizer) {
+	c.Inner = opt(c.Inner, optimizer)
+}
+
+func (c *ConstDef) String() string {
+	return "const " + c.Name + "=" + c.Value.String() + "; " + c.Inner.String()
+}
+
+// GetConstants returns the constants defined by "const" at
+// the top level of the given AST in the order of their definition.
+// An error is returned if the value of a const definition is not
+// folded to a constant, like in an AST created for formatting.
+func GetConstants[V any](ast AST) (listMap.ListMap[V], error) {
+	constants := listMap.New[V](4)
+	for {
+		switch a := ast.(type) {
+		case *ConstDef:
+			c, ok := a.Value.(*Const[V])
+			if !ok {
+				return nil, a.Errorf("value of const '%s' is not a constant: %v", a.Name, a.Value)
+			}
+			constants = constants.Append(a.Name, c.Value)
+			ast = a.Inner
+		case *Let:
+			ast = a.Inner
+		case *FuncGroup:
+			ast = a.Inner
+		case *Import:
+			ast = a.Inner
+		default:
+			return constants, nil
+		}
+	}
 }
 
 func opt(a AST, optimizer Optimizer) AST {
@@ -260,8 +448,13 @@ func (t *TryCatch) String() string {
 	return "try " + t.Try.String() + " catch " + t.Catch.String()
 }
 
+// Case is a case of a switch. The switch value is either compared to
+// CaseConst or, if Pattern is set, matched against the pattern. The
+// optional Guard has to be true for a matching case to be selected.
 type Case[V any] struct {
 	CaseConst AST
+	Pattern   Pattern
+	Guard     AST
 	Value     AST
 }
 
@@ -276,6 +469,9 @@ func (s *Switch[V]) Traverse(visitor Visitor) {
 	if visitor.Visit(s) {
 		s.SwitchValue.Traverse(visitor)
 		for _, c := range s.Cases {
+			if c.Guard != nil {
+				c.Guard.Traverse(visitor)
+			}
 			c.Value.Traverse(visitor)
 		}
 		s.Default.Traverse(visitor)
@@ -285,6 +481,9 @@ func (s *Switch[V]) Traverse(visitor Visitor) {
 func (s *Switch[V]) Optimize(o Optimizer) {
 	s.SwitchValue = opt(s.SwitchValue, o)
 	for i := range s.Cases {
+		if s.Cases[i].Guard != nil {
+			s.Cases[i].Guard = opt(s.Cases[i].Guard, o)
+		}
 		s.Cases[i].Value = opt(s.Cases[i].Value, o)
 	}
 	s.Default = opt(s.Default, o)
@@ -295,8 +494,17 @@ func (s *Switch[V]) String() string {
 	b.WriteString("switch ")
 	b.WriteString(s.SwitchValue.String())
 	for _, c := range s.Cases {
-		b.WriteString(" case ")
-		b.WriteString(fmt.Sprint(c.CaseConst))
+		if c.Pattern != nil {
+			b.WriteString(" match ")
+			b.WriteString(c.Pattern.String())
+			if c.Guard != nil {
+				b.WriteString(" if ")
+				b.WriteString(c.Guard.String())
+			}
+		} else {
+			b.WriteString(" case ")
+			b.WriteString(fmt.Sprint(c.CaseConst))
+		}
 		b.WriteString(" : ")
 		b.WriteString(c.Value.String())
 	}
@@ -306,9 +514,10 @@ func (s *Switch[V]) String() string {
 }
 
 type Operate struct {
-	Operator string
-	A, B     AST
-	Priority int
+	Operator      string
+	A, B          AST
+	Priority      int
+	Associativity Associativity
 	Line
 }
 
@@ -358,6 +567,9 @@ func (u *Unary) String() string {
 type MapAccess struct {
 	Key      string
 	MapValue AST
+	// Optional is set if the access is written as "m?.a". Such an
+	// access returns nil if the map is nil or the key is not present.
+	Optional bool
 	Line
 }
 
@@ -372,13 +584,25 @@ func (m *MapAccess) Optimize(optimizer Optimizer) {
 }
 
 func (m *MapAccess) String() string {
-	return braceStr(m.MapValue) + "." + m.Key
+	return braceStr(m.MapValue) + dotStr(m.Optional) + m.Key
+}
+
+// dotStr returns the operator used to access a map value
+// or to call a method
+func dotStr(optional bool) string {
+	if optional {
+		return "?."
+	}
+	return "."
 }
 
 type MethodCall struct {
 	Name  string
 	Args  []AST
 	Value AST
+	// Optional is set if the call is written as "v?.m()". Such a
+	// call returns nil without calling the method if v is nil.
+	Optional bool
 	Line
 }
 
@@ -399,7 +623,7 @@ func (m *MethodCall) Optimize(optimizer Optimizer) {
 }
 
 func (m *MethodCall) String() string {
-	return braceStr(m.Value) + "." + m.Name + "(" + sliceToString(m.Args) + ")"
+	return braceStr(m.Value) + dotStr(m.Optional) + m.Name + "(" + sliceToString(m.Args) + ")"
 }
 
 func sliceToString[V fmt.Stringer](items []V) string {
@@ -448,6 +672,12 @@ func (a *ListAccess) String() string {
 
 type ClosureLiteral struct {
 	Names []string
+	// Defaults are the default values of the last arguments
+	Defaults []AST
+	// Types are the type annotations of the arguments like in "(p: map) -> ...".
+	// It is nil if there are no annotations. Arguments without an annotation
+	// have an empty type.
+	Types []string
 	Func  AST
 	Line
 	OuterIdents []string
@@ -457,6 +687,9 @@ type ClosureLiteral struct {
 
 func (c *ClosureLiteral) Traverse(visitor Visitor) {
 	if visitor.Visit(c) {
+		for _, d := range c.Defaults {
+			d.Traverse(visitor)
+		}
 		c.Func.Traverse(visitor)
 	}
 }
@@ -466,10 +699,54 @@ func (c *ClosureLiteral) Optimize(optimizer Optimizer) {
 }
 
 func (c *ClosureLiteral) String() string {
-	if len(c.Names) == 1 {
-		return c.Names[0] + "->" + c.Func.String()
+	if c.isShort() {
+		return c.Names[0] + "->" + c.Body().String()
+	}
+	return "(" + stringsToString(c.ArgStrings()) + ")->" + c.Body().String()
+}
+
+// isShort returns true if the closure can be written
+// without parentheses like "x->x*x".
+func (c *ClosureLiteral) isShort() bool {
+	return len(c.Names) == 1 && len(c.Defaults) == 0 && c.Type(0) == "" && !isDestructuredArg(c.Names[0])
+}
+
+// Type returns the annotated type of the i-th argument
+// or an empty string if there is no annotation.
+func (c *ClosureLiteral) Type(i int) string {
+	if i < len(c.Types) {
+		return c.Types[i]
+	}
+	return ""
+}
+
+// Default returns the default value of the i-th argument
+// or nil if there is no default value.
+func (c *ClosureLiteral) Default(i int) AST {
+	d := i - (len(c.Names) - len(c.Defaults))
+	if d < 0 {
+		return nil
+	}
+	return c.Defaults[d]
+}
+
+// ArgStrings returns the arguments of the closure together
+// with their types and default values like "scale: int=1".
+func (c *ClosureLiteral) ArgStrings() []string {
+	if len(c.Defaults) == 0 && c.Types == nil {
+		return c.Names
 	}
-	return "(" + stringsToString(c.Names) + ")->" + c.Func.String()
+	args := make([]string, len(c.Names))
+	for i, n := range c.Names {
+		if t := c.Type(i); t != "" {
+			n += ": " + t
+		}
+		if d := c.Default(i); d != nil {
+			n += "=" + d.String()
+		}
+		args[i] = n
+	}
+	return args
 }
 
 type MapLiteral struct {
@@ -537,6 +814,44 @@ func (al *ListLiteral) String() string {
 	return "[" + sliceToString(al.List) + "]"
 }
 
+// Interpolation is a string containing interpolated expressions like "a=${a}".
+// The Strings are the literal parts of the string in between the
+// interpolated Values, so there is always one more string than values.
+type Interpolation struct {
+	Strings []string
+	Values  []AST
+	Line
+}
+
+func (s *Interpolation) Traverse(visitor Visitor) {
+	if visitor.Visit(s) {
+		for _, v := range s.Values {
+			v.Traverse(visitor)
+		}
+	}
+}
+
+func (s *Interpolation) Optimize(optimizer Optimizer) {
+	for i := range s.Values {
+		s.Values[i] = opt(s.Values[i], optimizer)
+	}
+}
+
+func (s *Interpolation) String() string {
+	var b strings.Builder
+	b.WriteString("\"")
+	for i, str := range s.Strings {
+		if i > 0 {
+			b.WriteString("${" + s.Values[i-1].String() + "}")
+		}
+		b.WriteString(interpolationEscaper.Replace(str))
+	}
+	b.WriteString("\"")
+	return b.String()
+}
+
+var interpolationEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "$", "\\$", "\n", "\\n", "\r", "\\r", "\t", "\\t")
+
 type Ident struct {
 	Name string
 	Line
@@ -572,6 +887,13 @@ func (n *Const[V]) String() string {
 type FunctionCall struct {
 	Func AST
 	Args []AST
+	// ArgNames are the names of the arguments if there are named
+	// arguments. Positional arguments have an empty name.
+	// It is nil if there are no named arguments.
+	ArgNames []string
+	// Piped is set if the first argument is given by the
+	// pipe operator like in "x |> f(y)"
+	Piped bool
 	Line
 }
 
@@ -592,7 +914,20 @@ func (f *FunctionCall) Optimize(optimizer Optimizer) {
 }
 
 func (f *FunctionCall) String() string {
-	return braceStr(f.Func) + "(" + sliceToString(f.Args) + ")"
+	if f.ArgNames == nil {
+		return braceStr(f.Func) + "(" + sliceToString(f.Args) + ")"
+	}
+	b := bytes.Buffer{}
+	for i, a := range f.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if f.ArgNames[i] != "" {
+			b.WriteString(f.ArgNames[i] + ": ")
+		}
+		b.WriteString(a.String())
+	}
+	return braceStr(f.Func) + "(" + b.String() + ")"
 }
 
 func simpleNumber(r rune) (func(r rune) bool, bool) {
@@ -608,6 +943,54 @@ func simpleNumber(r rune) (func(r rune) bool, bool) {
 	}
 }
 
+// SIPrefixes are the SI prefixes accepted by EngineeringNumber as
+// the suffix of a number. Both the micro sign and the greek mu can be
+// used for micro, and 'u' can be used if neither is available.
+const SIPrefixes = "fpnuµμmkMGTP"
+
+// EngineeringNumber is a number Matcher which, in addition to the numbers
+// accepted by default, accepts hexadecimal and binary numbers like "0xFF"
+// and "0b1010", underscores as digit separators like "1_000_000" and a
+// single SI prefix as a suffix like "4.7k" or "3µ". The NumberParser used
+// needs to be able to handle these numbers.
+// If it is used, "2m" is no longer read as an implicit multiplication.
+func EngineeringNumber(r rune) (func(r rune) bool, bool) {
+	if !unicode.IsNumber(r) || strings.ContainsRune("⁰¹²³⁴⁵⁶⁷⁸⁹", r) {
+		return nil, false
+	}
+	first := r
+	last := r
+	n := 0
+	radix := false
+	suffix := false
+	return func(r rune) bool {
+		var ok bool
+		switch {
+		case suffix:
+			ok = false
+		case n == 1 && first == '0' && strings.ContainsRune("xXbB", r):
+			radix = true
+			ok = true
+		case radix:
+			ok = strings.ContainsRune("0123456789abcdefABCDEF_", r)
+		case unicode.IsNumber(r) && !strings.ContainsRune("⁰¹²³⁴⁵⁶⁷⁸⁹", r):
+			ok = true
+		case r == '.' || r == 'e' || r == '_':
+			ok = true
+		case last == 'e' && (r == '-' || r == '+'):
+			ok = true
+		case strings.ContainsRune(SIPrefixes, r):
+			suffix = true
+			ok = true
+		}
+		if ok {
+			last = r
+			n++
+		}
+		return ok
+	}, true
+}
+
 func simpleIdentifier(r rune) (func(r rune) bool, bool) {
 	if unicode.IsLetter(r) || r == '_' {
 		return func(r rune) bool {
@@ -639,6 +1022,19 @@ func (shf StringConverterFunc[V]) FromString(s string) V {
 	return shf(s)
 }
 
+// Associativity describes how a chain of equal operators is grouped
+type Associativity int
+
+const (
+	// LeftAssociative groups a-b-c as (a-b)-c
+	LeftAssociative Associativity = iota
+	// RightAssociative groups a^b^c as a^(b^c)
+	RightAssociative
+	// NonAssociative rejects chains like a<b<c, also if different
+	// non-associative operators like in a<b>c are chained
+	NonAssociative
+)
+
 type unaryEntry struct {
 	// Used to handle operator priority if there is an operator that
 	// is also an unary. In most cases just the "-"
@@ -648,6 +1044,7 @@ type unaryEntry struct {
 // Parser is the base class of the parser
 type Parser[V any] struct {
 	operators      []string
+	associativity  map[string]Associativity
 	unary          map[string]*unaryEntry
 	textOperators  map[string]string
 	keyWords       []string
@@ -660,14 +1057,21 @@ type Parser[V any] struct {
 	operatorDetect OperatorDetector
 	comfort        bool
 	debug          bool
+	formatting     bool
+	isType         func(name string) bool
+	// module is set if a module is parsed, moduleEnd is the
+	// map of the exported definitions created at the end of the module
+	module    bool
+	moduleEnd *MapLiteral
 }
 
 // NewParser creates a new Parser
 func NewParser[V any]() *Parser[V] {
 	return &Parser[V]{
-		unary:      map[string]*unaryEntry{},
-		number:     simpleNumber,
-		identifier: simpleIdentifier,
+		unary:         map[string]*unaryEntry{},
+		associativity: map[string]Associativity{},
+		number:        simpleNumber,
+		identifier:    simpleIdentifier,
 	}
 }
 
@@ -675,12 +1079,23 @@ func NewParser[V any]() *Parser[V] {
 // The name gives the operations name e.g."+"
 // The operation with the lowest priority needs to be added first.
 // The operation with the highest priority needs to be added last.
+// All these operators are left associative.
 func (p *Parser[V]) Op(name ...string) *Parser[V] {
+	return p.OpAssoc(LeftAssociative, name...)
+}
+
+// OpAssoc adds operators with the given associativity to the parser.
+// The operation with the lowest priority needs to be added first.
+// The operation with the highest priority needs to be added last.
+func (p *Parser[V]) OpAssoc(associativity Associativity, name ...string) *Parser[V] {
 	if len(p.operators) == 0 {
 		p.operators = name
 	} else {
 		p.operators = append(p.operators, name...)
 	}
+	for _, n := range name {
+		p.associativity[n] = associativity
+	}
 	return p
 }
 
@@ -736,6 +1151,13 @@ func (p *Parser[V]) SetKeyWords(keyWords ...string) *Parser[V] {
 	return p
 }
 
+// SetTypeNames sets the function used to decide if an identifier
+// in a match pattern is a type name like "int" or a variable.
+func (p *Parser[V]) SetTypeNames(isType func(name string) bool) *Parser[V] {
+	p.isType = isType
+	return p
+}
+
 // TextOperator sets a map of text aliases for operators.
 // Allows setting e.g. "plus" as an alias for "+"
 func (p *Parser[V]) TextOperator(textOperators map[string]string) *Parser[V] {
@@ -757,10 +1179,33 @@ func (p *Parser[V]) AllowComments() *Parser[V] {
 
 // Parse parses the given string and returns an ast
 func (p *Parser[V]) Parse(str string, idents Identifiers[V]) (ast AST, err error) {
+	tokenizer := p.newTokenizer(str)
+
+	ast, err = p.parseLet(tokenizer, idents)
+	if err != nil {
+		return nil, err
+	}
+	t := tokenizer.Next()
+	if t.typ != tEof {
+		return nil, unexpected("EOF", t)
+	}
+
+	if p.optimizer != nil {
+		ast = Optimize(ast, p.optimizer)
+	}
+
+	if p.debug {
+		log.Println("AST main:\n" + PrettyPrint[V](ast))
+	}
+
+	return ast, nil
+}
+
+func (p *Parser[V]) newTokenizer(str string) *Tokenizer {
 	if p.operatorDetect == nil {
 		var op []string
 		op = append(op, p.operators...)
-		op = append(op, "=", "->")
+		op = append(op, "=", "->", "|>", "?.")
 		for u := range p.unary {
 			op = append(op, u)
 		}
@@ -778,32 +1223,12 @@ func (p *Parser[V]) Parse(str string, idents Identifiers[V]) (ast AST, err error
 
 	}
 
-	tokenizer :=
-		NewTokenizer(str, p.number, p.identifier, p.operatorDetect).
-			SetTextOperators(p.textOperators).
-			SetKeyWords(p.keyWords).
-			SetComments(p.allowComments).
-			SetComfort(p.comfort).
-			Start()
-
-	ast, err = p.parseLet(tokenizer, idents)
-	if err != nil {
-		return nil, err
-	}
-	t := tokenizer.Next()
-	if t.typ != tEof {
-		return nil, unexpected("EOF", t)
-	}
-
-	if p.optimizer != nil {
-		ast = Optimize(ast, p.optimizer)
-	}
-
-	if p.debug {
-		log.Println("AST main:\n" + PrettyPrint[V](ast))
-	}
-
-	return ast, nil
+	return NewTokenizer(str, p.number, p.identifier, p.operatorDetect).
+		SetTextOperators(p.textOperators).
+		SetKeyWords(p.keyWords).
+		SetComments(p.allowComments).
+		SetComfort(p.comfort).
+		Start()
 }
 
 type parserFunc[V any] func(tokenizer *Tokenizer, constants Identifiers[V]) (AST, error)
@@ -930,9 +1355,17 @@ func (c Identifiers[V]) AddArgs(names []string, outersUsed *[]string) Identifier
 
 func (p *Parser[V]) parseLet(tokenizer *Tokenizer, idents Identifiers[V]) (AST, error) {
 	t := tokenizer.Peek()
+	if p.module && t.typ == tEof {
+		p.moduleEnd = &MapLiteral{Line: t.Line}
+		return p.moduleEnd, nil
+	}
 	if t.typ == tKeyWord {
+		start := t.Line
 		if t.image == "let" {
 			tokenizer.Next()
+			if t := tokenizer.Peek(); t.typ == tOpenBracket || t.typ == tOpenCurly {
+				return p.parseDestructuringLet(tokenizer, start, idents)
+			}
 			t = tokenizer.Next()
 			if t.typ != tIdent {
 				return nil, t.Errorf("no identifier followed by let")
@@ -943,23 +1376,32 @@ func (p *Parser[V]) parseLet(tokenizer *Tokenizer, idents Identifiers[V]) (AST,
 			if t := tokenizer.Next(); t.typ != tOperate || t.image != "=" {
 				return nil, unexpected("=", t)
 			}
+			valueStart := tokenizer.Peek().Line
 			exp, err := p.parseExpression(tokenizer, idents)
 			if err != nil {
-				return nil, err
+				if exp, err = recoverFrom(tokenizer, err, valueStart); err != nil {
+					return nil, err
+				}
 			}
-			if t := tokenizer.Next(); t.typ != tSemicolon || t.image != ";" {
-				return nil, unexpected(";", t)
+			if err := expect(tokenizer, tSemicolon, ";"); err != nil {
+				return nil, err
 			}
+			line = line.span(start, tokenizer.Last().End)
 
 			if p.optimizer != nil {
 				exp = Optimize(exp, p.optimizer)
 			}
 
-			if c, ok := exp.(*Const[V]); ok {
-				return p.parseLet(tokenizer, idents.AddConst(name, c.Value))
+			innerIdents := idents.Add(name)
+			if c, ok := exp.(*Const[V]); ok && !p.formatting {
+				if !p.module {
+					return p.parseLet(tokenizer, idents.AddConst(name, c.Value))
+				}
+				// the let is required to export the constant
+				innerIdents = idents.AddConst(name, c.Value)
 			}
 
-			inner, err := p.parseLet(tokenizer, idents.Add(name))
+			inner, err := p.parseLet(tokenizer, innerIdents)
 			if err != nil {
 				return nil, err
 			}
@@ -970,69 +1412,207 @@ func (p *Parser[V]) parseLet(tokenizer *Tokenizer, idents Identifiers[V]) (AST,
 				Line:  line,
 			}, nil
 		} else if t.image == "func" {
+			return p.parseFunc(tokenizer, idents)
+		} else if t.image == "import" {
 			tokenizer.Next()
-			t = tokenizer.Next()
-			if t.typ != tIdent {
-				return nil, t.Errorf("no identifier followed by func")
-			}
-			name := t.image
-			line := t.GetLine()
-			if t := tokenizer.Next(); t.typ != tOpen {
-				return nil, unexpected("(", t)
-			}
-			names, err := p.parseIdentList(tokenizer)
-			if err != nil {
-				return nil, err
-			}
-			recursive := false
-			var outersUsed []string
-			exp, err := p.parseLet(tokenizer, idents.AddArgs(names, &outersUsed).AddThis(name, &recursive))
-			if err != nil {
-				return nil, err
-			}
-			if t := tokenizer.Next(); t.typ != tSemicolon || t.image != ";" {
-				return nil, unexpected(";", t)
-			}
+			return p.parseImport(tokenizer, start, idents)
+		} else if t.image == "const" {
+			tokenizer.Next()
+			return p.parseConst(tokenizer, start, idents)
+		}
+	}
+	return p.parseExpression(tokenizer, idents)
+}
 
-			var clo AST = &ClosureLiteral{
-				Names:       names,
-				Func:        exp,
-				Line:        line,
-				OuterIdents: outersUsed,
-				Recursive:   recursive,
-				ThisName:    name,
-			}
+// parseConst parses a constant definition like "const WIDTH = 800;".
+// The keyword "const" is already consumed.
+func (p *Parser[V]) parseConst(tokenizer *Tokenizer, start Line, idents Identifiers[V]) (AST, error) {
+	t := tokenizer.Next()
+	if t.typ != tIdent {
+		return nil, t.Errorf("no identifier followed by const")
+	}
+	name := t.image
+	line := t.GetLine()
+	if t := tokenizer.Next(); t.typ != tOperate || t.image != "=" {
+		return nil, unexpected("=", t)
+	}
+	exp, err := p.parseExpression(tokenizer, idents)
+	if err != nil {
+		return nil, err
+	}
+	if err := expect(tokenizer, tSemicolon, ";"); err != nil {
+		return nil, err
+	}
+	line = line.span(start, tokenizer.Last().End)
 
-			if p.optimizer != nil {
-				clo = Optimize(clo, p.optimizer)
-			}
+	if p.optimizer != nil {
+		exp = Optimize(exp, p.optimizer)
+	}
 
-			if c, ok := clo.(*Const[V]); ok {
-				return p.parseLet(tokenizer, idents.AddConst(name, c.Value))
-			}
+	var inner AST
+	if p.formatting {
+		// the constants are not folded if formatting, so the value
+		// is checked by Format parsing the code the normal way
+		inner, err = p.parseLet(tokenizer, idents.Add(name))
+	} else {
+		c, ok := exp.(*Const[V])
+		if !ok {
+			return nil, line.Errorf("value of const '%s' is not a constant: %v", name, exp)
+		}
+		inner, err = p.parseLet(tokenizer, idents.AddConst(name, c.Value))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ConstDef{
+		Name:  name,
+		Value: exp,
+		Inner: inner,
+		Line:  line,
+	}, nil
+}
+
+// parseDestructuringLet parses a let which splits a list
+// or a map into several variables like "let [a, b] = list;"
+func (p *Parser[V]) parseDestructuringLet(tokenizer *Tokenizer, start Line, idents Identifiers[V]) (AST, error) {
+	d, err := p.parseDestructuring(tokenizer)
+	if err != nil {
+		return nil, err
+	}
+	if t := tokenizer.Next(); t.typ != tOperate || t.image != "=" {
+		return nil, unexpected("=", t)
+	}
+	valueStart := tokenizer.Peek().Line
+	exp, err := p.parseExpression(tokenizer, idents)
+	if err != nil {
+		if exp, err = recoverFrom(tokenizer, err, valueStart); err != nil {
+			return nil, err
+		}
+	}
+	if err := expect(tokenizer, tSemicolon, ";"); err != nil {
+		return nil, err
+	}
+	line := d.Line.span(start, tokenizer.Last().End)
 
-			inner, err := p.parseLet(tokenizer, idents.Add(name))
-			if err != nil {
-				return nil, err
+	if p.optimizer != nil {
+		exp = Optimize(exp, p.optimizer)
+	}
+
+	for _, name := range d.Names {
+		idents = idents.Add(name)
+	}
+	inner, err := p.parseLet(tokenizer, idents)
+	if err != nil {
+		return nil, err
+	}
+	return &Let{
+		Destructuring: d,
+		Value:         exp,
+		Inner:         inner,
+		Line:          line,
+	}, nil
+}
+
+// parseDestructuring parses a destructuring pattern like "[a, b]" or "{a, b}"
+func (p *Parser[V]) parseDestructuring(tokenizer *Tokenizer) (*Destructuring, error) {
+	t := tokenizer.Next()
+	d := &Destructuring{Line: t.Line}
+	closeType := tCloseBracket
+	closeStr := "]"
+	if t.typ == tOpenCurly {
+		d.IsMap = true
+		closeType = tCloseCurly
+		closeStr = "}"
+	}
+	for {
+		t = tokenizer.Next()
+		if t.typ != tIdent {
+			return nil, t.Errorf("expected identifier in destructuring, found %v", t)
+		}
+		for _, n := range d.Names {
+			if n == t.image {
+				return nil, t.Errorf("'%s' used twice in destructuring", t.image)
 			}
-			return &Let{
-				Name:  name,
-				Value: clo,
-				Inner: inner,
-				Line:  line,
-			}, nil
+		}
+		d.Names = append(d.Names, t.image)
+		t = tokenizer.Next()
+		switch t.typ {
+		case closeType:
+			d.Line = d.Line.span(d.Line, t.End)
+			return d, nil
+		case tComma:
+		default:
+			return nil, t.Errorf("expected ',' or '%s', found %v", closeStr, t)
 		}
 	}
-	return p.parseExpression(tokenizer, idents)
 }
 
 func (p *Parser[V]) parseExpression(tokenizer *Tokenizer, constants Identifiers[V]) (AST, error) {
-	return p.parseOp(tokenizer, 0, constants)
+	start := tokenizer.Peek().Line
+	expression, err := p.parseOp(tokenizer, 0, constants)
+	if err != nil {
+		return nil, err
+	}
+	if isRange(tokenizer) {
+		expression, err = p.parseRange(tokenizer, expression, start, constants)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for {
+		t := tokenizer.Peek()
+		if !(t.typ == tOperate && t.image == "|>") {
+			return expression, nil
+		}
+		tokenizer.Next()
+		expression, err = p.parsePipe(tokenizer, expression, start, constants)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parsePipe parses the function on the right side of the pipe operator.
+// The expression "x |> f(y)" becomes the function call "f(x, y)". If the
+// function is not called like in "x |> f" it is called with x as the only
+// argument. The operator itself is already consumed.
+func (p *Parser[V]) parsePipe(tokenizer *Tokenizer, value AST, start Line, constants Identifiers[V]) (AST, error) {
+	funcStart := tokenizer.Peek().Line
+	f, err := p.parseNonOperator(tokenizer, constants)
+	if err != nil {
+		return nil, err
+	}
+	line := funcStart.span(start, tokenizer.Last().End)
+	switch fe := f.(type) {
+	case *FunctionCall:
+		if fe.Line.Start == funcStart.Start {
+			// a call like "x |> f(y)" and not "x |> (f(y))"
+			fe.Args = append([]AST{value}, fe.Args...)
+			if fe.ArgNames != nil {
+				fe.ArgNames = append([]string{""}, fe.ArgNames...)
+			}
+			fe.Piped = true
+			fe.Line = line
+			return fe, nil
+		}
+	case *MethodCall:
+		if fe.Line.Start == funcStart.Start {
+			return nil, fe.Errorf("a method call can not be the target of a pipe")
+		}
+	}
+	return &FunctionCall{
+		Func:  f,
+		Args:  []AST{value},
+		Piped: true,
+		Line:  line,
+	}, nil
 }
 
 func (p *Parser[V]) parseOp(tokenizer *Tokenizer, op int, constants Identifiers[V]) (AST, error) {
 	next := p.nextParserCall(op)
 	operator := p.operators[op]
+	associativity := p.associativity[operator]
+	start := tokenizer.Peek().Line
 	a, err := next(tokenizer, constants)
 	if err != nil {
 		return nil, err
@@ -1042,16 +1622,36 @@ func (p *Parser[V]) parseOp(tokenizer *Tokenizer, op int, constants Identifiers[
 		if t.typ == tOperate && t.image == operator {
 			tokenizer.Next()
 			aa := a
-			bb, err := next(tokenizer, constants)
+			bStart := tokenizer.Peek().Start
+			var bb AST
+			if associativity == RightAssociative {
+				bb, err = p.parseOp(tokenizer, op, constants)
+			} else {
+				bb, err = next(tokenizer, constants)
+			}
 			if err != nil {
 				return nil, err
 			}
+			if associativity == NonAssociative {
+				if o, ok := chainedNonAssociative(aa, start.Start); ok {
+					return nil, t.Errorf("operators '%s' and '%s' are not associative, use parentheses", o, operator)
+				}
+				if o, ok := chainedNonAssociative(bb, bStart); ok {
+					return nil, t.Errorf("operators '%s' and '%s' are not associative, use parentheses", operator, o)
+				}
+			}
 			a = &Operate{
-				Operator: operator,
-				Priority: op,
-				A:        aa,
-				B:        bb,
-				Line:     t.Line,
+				Operator:      operator,
+				Priority:      op,
+				Associativity: associativity,
+				A:             aa,
+				B:             bb,
+				Line:          t.Line.span(start, tokenizer.Last().End),
+			}
+			if associativity == NonAssociative {
+				if n := tokenizer.Peek(); n.typ == tOperate && n.image == operator {
+					return nil, n.Errorf("operator '%s' is not associative, use parentheses", operator)
+				}
 			}
 		} else {
 			return a, nil
@@ -1059,6 +1659,17 @@ func (p *Parser[V]) parseOp(tokenizer *Tokenizer, op int, constants Identifiers[
 	}
 }
 
+// chainedNonAssociative returns the operator if the given operand is a
+// non-associative operation which is not enclosed in parentheses.
+// The operand is enclosed in parentheses if it does not start at the
+// position the parsing of the operand has started.
+func chainedNonAssociative(operand AST, start int) (string, bool) {
+	if o, ok := operand.(*Operate); ok && o.Associativity == NonAssociative && o.Line.Start == start {
+		return o.Operator, true
+	}
+	return "", false
+}
+
 func (p *Parser[V]) nextParserCall(op int) parserFunc[V] {
 	if op+1 < len(p.operators) {
 		return func(tokenizer *Tokenizer, constants Identifiers[V]) (AST, error) {
@@ -1087,7 +1698,7 @@ func (p *Parser[V]) parseUnary(tokenizer *Tokenizer, constants Identifiers[V]) (
 			return &Unary{
 				Operator: t.image,
 				Value:    inner,
-				Line:     t.Line,
+				Line:     t.Line.span(t.Line, tokenizer.Last().End),
 			}, nil
 		}
 	}
@@ -1095,14 +1706,23 @@ func (p *Parser[V]) parseUnary(tokenizer *Tokenizer, constants Identifiers[V]) (
 }
 
 func (p *Parser[V]) parseNonOperator(tokenizer *Tokenizer, constants Identifiers[V]) (AST, error) {
+	start := tokenizer.Peek().Line
 	expression, err := p.parseLiteral(tokenizer, constants)
 	if err != nil {
 		return nil, err
 	}
 	for {
+		if isRange(tokenizer) {
+			return expression, nil
+		}
 		switch tokenizer.Peek().typ {
+		case tOperate:
+			if tokenizer.Peek().image != "?." {
+				return expression, nil
+			}
+			fallthrough
 		case tDot:
-			tokenizer.Next()
+			optional := tokenizer.Next().typ == tOperate
 			t := tokenizer.Next()
 			if t.typ != tIdent {
 				return nil, unexpected("ident", t)
@@ -1112,32 +1732,40 @@ func (p *Parser[V]) parseNonOperator(tokenizer *Tokenizer, constants Identifiers
 				expression = &MapAccess{
 					Key:      name,
 					MapValue: expression,
-					Line:     t.Line,
+					Optional: optional,
+					Line:     t.Line.span(start, t.End),
 				}
 			} else {
 				//Method call
 				tokenizer.Next()
-				args, err := p.parseArgs(tokenizer, tClose, constants)
+				var argNames []string
+				args, err := p.parseArgs(tokenizer, tClose, constants, &argNames)
 				if err != nil {
 					return nil, err
 				}
+				if argNames != nil {
+					return nil, t.Errorf("named arguments are not supported in method calls")
+				}
 				expression = &MethodCall{
-					Name:  name,
-					Args:  args,
-					Value: expression,
-					Line:  t.Line,
+					Name:     name,
+					Args:     args,
+					Value:    expression,
+					Optional: optional,
+					Line:     t.Line.span(start, tokenizer.Last().End),
 				}
 			}
 		case tOpen:
 			t := tokenizer.Next()
-			args, err := p.parseArgs(tokenizer, tClose, constants)
+			var argNames []string
+			args, err := p.parseArgs(tokenizer, tClose, constants, &argNames)
 			if err != nil {
 				return nil, err
 			}
 			expression = &FunctionCall{
-				Func: expression,
-				Args: args,
-				Line: t.Line,
+				Func:     expression,
+				Args:     args,
+				ArgNames: argNames,
+				Line:     t.Line.span(start, tokenizer.Last().End),
 			}
 
 		case tOpenBracket:
@@ -1153,7 +1781,7 @@ func (p *Parser[V]) parseNonOperator(tokenizer *Tokenizer, constants Identifiers
 			expression = &ListAccess{
 				Index: indexExpr,
 				List:  expression,
-				Line:  t.Line,
+				Line:  t.Line.span(start, t.End),
 			}
 		default:
 			return expression, nil
@@ -1162,7 +1790,12 @@ func (p *Parser[V]) parseNonOperator(tokenizer *Tokenizer, constants Identifiers
 }
 
 func (p *Parser[V]) parseLiteral(tokenizer *Tokenizer, idents Identifiers[V]) (AST, error) {
+	if t := tokenizer.Peek(); isSyncToken(t) {
+		// don't consume the token, so that it is available for error recovery
+		return nil, t.Errorf("unexpected token type: %v", t.image)
+	}
 	t := tokenizer.Next()
+	start := t.Line
 	switch t.typ {
 	case tIdent:
 		name := t.image
@@ -1177,7 +1810,7 @@ func (p *Parser[V]) parseLiteral(tokenizer *Tokenizer, idents Identifiers[V]) (A
 			return &ClosureLiteral{
 				Names:       []string{name},
 				Func:        e,
-				Line:        t.Line,
+				Line:        t.Line.span(t.Line, tokenizer.Last().End),
 				OuterIdents: outersUsed,
 			}, nil
 		} else {
@@ -1200,7 +1833,7 @@ func (p *Parser[V]) parseLiteral(tokenizer *Tokenizer, idents Identifiers[V]) (A
 					}
 				}
 			}
-			return nil, t.Errorf("identifier '%s' not found", name)
+			return recoverNode(tokenizer, t.Errorf("identifier '%s' not found", name), t.Line)
 		}
 	case tKeyWord:
 		name := t.image
@@ -1220,7 +1853,7 @@ func (p *Parser[V]) parseLiteral(tokenizer *Tokenizer, idents Identifiers[V]) (A
 			return &TryCatch{
 				Try:   tryExp,
 				Catch: catchExp,
-				Line:  t.Line,
+				Line:  t.Line.span(start, tokenizer.Last().End),
 			}, nil
 		} else if name == "if" {
 			cond, err := p.parseExpression(tokenizer, idents)
@@ -1247,7 +1880,7 @@ func (p *Parser[V]) parseLiteral(tokenizer *Tokenizer, idents Identifiers[V]) (A
 				Cond: cond,
 				Then: thenExp,
 				Else: elseExp,
-				Line: t.Line,
+				Line: t.Line.span(start, tokenizer.Last().End),
 			}, nil
 		} else if name == "switch" {
 			switchValue, err := p.parseExpression(tokenizer, idents)
@@ -1275,6 +1908,12 @@ func (p *Parser[V]) parseLiteral(tokenizer *Tokenizer, idents Identifiers[V]) (A
 							CaseConst: constFunc,
 							Value:     resultExp,
 						})
+					} else if t.image == "match" {
+						c, err := p.parseMatchCase(tokenizer, idents)
+						if err != nil {
+							return nil, err
+						}
+						cases = append(cases, c)
 					} else if t.image == "default" {
 						resultExp, err := p.parseLet(tokenizer, idents)
 						if err != nil {
@@ -1284,41 +1923,53 @@ func (p *Parser[V]) parseLiteral(tokenizer *Tokenizer, idents Identifiers[V]) (A
 							SwitchValue: switchValue,
 							Cases:       cases,
 							Default:     resultExp,
-							Line:        t.Line,
+							Line:        t.Line.span(start, tokenizer.Last().End),
 						}, nil
 					} else {
-						return nil, unexpected("case or default", t)
+						return nil, unexpected("case, match or default", t)
 					}
 				} else {
-					return nil, unexpected("case or default", t)
+					return nil, unexpected("case, match or default", t)
 				}
 			}
 		} else {
 			return nil, t.Errorf("expected keyword, found %v", t)
 		}
 	case tOpenCurly:
-		return p.parseMap(tokenizer, idents)
+		m, err := p.parseMap(tokenizer, idents)
+		if err != nil {
+			return nil, err
+		}
+		m.Line = m.Line.span(start, m.End)
+		return m, nil
 	case tOpenBracket:
-		args, err := p.parseArgs(tokenizer, tCloseBracket, idents)
+		if names := comprehensionNames(tokenizer); names != nil {
+			return p.parseComprehension(tokenizer, t.Line, names, idents)
+		}
+		args, err := p.parseArgs(tokenizer, tCloseBracket, idents, nil)
 		if err != nil {
 			return nil, err
 		}
-		return &ListLiteral{args, t.Line}, nil
+		return &ListLiteral{args, t.Line.span(t.Line, tokenizer.Last().End)}, nil
 	case tNumber:
 		if p.numberParser != nil {
 			if number, err := p.numberParser.ParseNumber(t.image); err == nil {
 				return &Const[V]{number, t.Line}, nil
 			} else {
-				return nil, t.EnhanceErrorf(err, "not a number")
+				return recoverNode(tokenizer, t.EnhanceErrorf(err, "not a number"), t.Line)
 			}
 		}
 	case tString:
 		if p.stringHandler != nil {
 			return &Const[V]{p.stringHandler.FromString(t.image), t.Line}, nil
 		}
+	case tInterpolStart:
+		if p.stringHandler != nil {
+			return p.parseInterpolation(tokenizer, t, idents)
+		}
 	case tOpen:
-		if tokenizer.Peek().typ == tIdent && tokenizer.PeekPeek().typ == tComma {
-			names, err := p.parseIdentList(tokenizer)
+		if isClosureArgs(tokenizer) {
+			names, patterns, defaults, types, err := p.parseIdentList(tokenizer, idents)
 			if err != nil {
 				return nil, err
 			}
@@ -1327,24 +1978,28 @@ func (p *Parser[V]) parseLiteral(tokenizer *Tokenizer, idents Identifiers[V]) (A
 				return nil, unexpected("->", t)
 			}
 			var outersUsed []string
-			e, err := p.parseLet(tokenizer, idents.AddArgs(names, &outersUsed))
+			e, err := p.parseLet(tokenizer, addDestructured(idents.AddArgs(names, &outersUsed), patterns))
 			if err != nil {
 				return nil, err
 			}
 			return &ClosureLiteral{
 				Names:       names,
-				Func:        e,
-				Line:        t.Line,
+				Defaults:    defaults,
+				Types:       types,
+				Func:        wrapDestructured(e, names, patterns),
+				Line:        t.Line.span(start, tokenizer.Last().End),
 				OuterIdents: outersUsed,
 			}, nil
 		} else {
+			innerStart := tokenizer.Peek().Line
 			e, err := p.parseExpression(tokenizer, idents)
 			if err != nil {
-				return nil, err
+				if e, err = recoverFrom(tokenizer, err, innerStart); err != nil {
+					return nil, err
+				}
 			}
-			t := tokenizer.Next()
-			if t.typ != tClose {
-				return nil, unexpected(")", t)
+			if err := expect(tokenizer, tClose, ")"); err != nil {
+				return nil, err
 			}
 			return e, nil
 		}
@@ -1352,16 +2007,67 @@ func (p *Parser[V]) parseLiteral(tokenizer *Tokenizer, idents Identifiers[V]) (A
 	return nil, t.Errorf("unexpected token type: %v", t.image)
 }
 
-func (p *Parser[V]) parseArgs(tokenizer *Tokenizer, closeList TokenType, constants Identifiers[V]) ([]AST, error) {
+// parseInterpolation parses the interpolated expressions of a string.
+// The tInterpolStart token containing the first part of the string
+// is already consumed.
+func (p *Parser[V]) parseInterpolation(tokenizer *Tokenizer, t Token, idents Identifiers[V]) (AST, error) {
+	in := &Interpolation{Strings: []string{t.image}}
+	for {
+		valueStart := tokenizer.Peek().Line
+		value, err := p.parseExpression(tokenizer, idents)
+		if err != nil {
+			if value, err = recoverFrom(tokenizer, err, valueStart); err != nil {
+				return nil, err
+			}
+		}
+		in.Values = append(in.Values, value)
+		switch n := tokenizer.Next(); n.typ {
+		case tInterpolMid:
+			in.Strings = append(in.Strings, n.image)
+		case tInterpolEnd:
+			in.Strings = append(in.Strings, n.image)
+			in.Line = t.Line.span(t.Line, n.End)
+			return in, nil
+		default:
+			return nil, unexpected("}", n)
+		}
+	}
+}
+
+// parseArgs parses a list of arguments. If names is not nil, named arguments
+// like "scale: 2" are allowed. In this case names is set to the names of the
+// arguments with an empty string for the positional arguments, but only if
+// there is at least one named argument.
+func (p *Parser[V]) parseArgs(tokenizer *Tokenizer, closeList TokenType, constants Identifiers[V], names *[]string) ([]AST, error) {
 	var args []AST
 	if tokenizer.Peek().typ == closeList {
 		tokenizer.Next()
 		return args, nil
 	}
 	for {
+		if names != nil {
+			if t := tokenizer.Peek(); t.typ == tIdent && tokenizer.PeekPeek().typ == tColon {
+				tokenizer.Next()
+				tokenizer.Next()
+				for _, n := range *names {
+					if n == t.image {
+						return nil, t.Errorf("argument '%s' given twice", t.image)
+					}
+				}
+				if *names == nil {
+					*names = make([]string, len(args), len(args)+1)
+				}
+				*names = append(*names, t.image)
+			} else if *names != nil {
+				return nil, t.Errorf("positional argument follows named argument")
+			}
+		}
+		elementStart := tokenizer.Peek().Line
 		element, err := p.parseLet(tokenizer, constants)
 		if err != nil {
-			return nil, err
+			if element, err = recoverFrom(tokenizer, err, elementStart); err != nil {
+				return nil, err
+			}
 		}
 		args = append(args, element)
 		t := tokenizer.Next()
@@ -1391,9 +2097,12 @@ func (p *Parser[V]) parseMap(tokenizer *Tokenizer, constants Identifiers[V]) (*M
 			if c := tokenizer.Next(); c.typ != tColon {
 				return nil, unexpected(":", c)
 			}
+			entryStart := tokenizer.Peek().Line
 			entryAst, err := p.parseLet(tokenizer, constants)
 			if err != nil {
-				return nil, err
+				if entryAst, err = recoverFrom(tokenizer, err, entryStart); err != nil {
+					return nil, err
+				}
 			}
 			m = m.Append(t.image, entryAst)
 			if tokenizer.Peek().typ == tComma {
@@ -1410,27 +2119,182 @@ func (p *Parser[V]) parseMap(tokenizer *Tokenizer, constants Identifiers[V]) (*M
 	}
 }
 
-func (p *Parser[V]) parseIdentList(tokenizer *Tokenizer) ([]string, error) {
+// parseIdentList parses the argument list of a function. Arguments which
+// are destructured are named by their pattern like "[a, b]". The patterns
+// are returned in a separate list which contains nil for simple arguments.
+// The last returned list contains the default values of the last arguments.
+func (p *Parser[V]) parseIdentList(tokenizer *Tokenizer, idents Identifiers[V]) ([]string, []*Destructuring, []AST, []string, error) {
 	var names []string
+	var patterns []*Destructuring
+	var defaults []AST
+	var types []string
+	var used []string
 	for {
-		t := tokenizer.Next()
-		if t.typ == tIdent {
-			for _, n := range names {
-				if n == t.image {
-					return nil, t.Errorf("'%s' used twice in functions argument list", t.image)
+		t := tokenizer.Peek()
+		var pattern *Destructuring
+		var vars []string
+		switch t.typ {
+		case tIdent:
+			tokenizer.Next()
+			names = append(names, t.image)
+			vars = []string{t.image}
+		case tOpenBracket, tOpenCurly:
+			var err error
+			pattern, err = p.parseDestructuring(tokenizer)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			names = append(names, pattern.String())
+			vars = pattern.Names
+		default:
+			tokenizer.Next()
+			return nil, nil, nil, nil, t.Errorf("expected identifier, found %v", t)
+		}
+		patterns = append(patterns, pattern)
+		if c := tokenizer.Peek(); c.typ == tColon {
+			tokenizer.Next()
+			typ := tokenizer.Next()
+			if typ.typ != tIdent || p.isType == nil || !p.isType(typ.image) {
+				return nil, nil, nil, nil, typ.Errorf("unknown type '%s'", typ.image)
+			}
+			for len(types) < len(names)-1 {
+				types = append(types, "")
+			}
+			types = append(types, typ.image)
+		}
+		for _, v := range vars {
+			for _, n := range used {
+				if n == v {
+					return nil, nil, nil, nil, t.Errorf("'%s' used twice in functions argument list", v)
 				}
 			}
-			names = append(names, t.image)
-			t = tokenizer.Next()
-			switch t.typ {
-			case tClose:
-				return names, nil
-			case tComma:
-			default:
-				return nil, t.Errorf("expected ',' or ')', found %v", t)
+			used = append(used, v)
+		}
+		if d := tokenizer.Peek(); d.typ == tOperate && d.image == "=" {
+			tokenizer.Next()
+			def, err := p.parseDefault(tokenizer, idents)
+			if err != nil {
+				return nil, nil, nil, nil, err
 			}
-		} else {
-			return nil, t.Errorf("expected identifier, found %v", t)
+			defaults = append(defaults, def)
+		} else if len(defaults) > 0 {
+			return nil, nil, nil, nil, t.Errorf("argument '%s' requires a default value, since it follows an argument with a default value", names[len(names)-1])
+		}
+		t = tokenizer.Next()
+		switch t.typ {
+		case tClose:
+			for types != nil && len(types) < len(names) {
+				types = append(types, "")
+			}
+			return names, patterns, defaults, types, nil
+		case tComma:
+		default:
+			return nil, nil, nil, nil, t.Errorf("expected ',' or ')', found %v", t)
+		}
+	}
+}
+
+// parseDefault parses the default value of an argument
+// which has to be a constant.
+func (p *Parser[V]) parseDefault(tokenizer *Tokenizer, idents Identifiers[V]) (AST, error) {
+	start := tokenizer.Peek()
+	def, err := p.parseExpression(tokenizer, idents)
+	if err != nil {
+		return nil, err
+	}
+	if p.optimizer != nil {
+		def = Optimize(def, p.optimizer)
+		if _, ok := def.(*Const[V]); !ok && !p.formatting {
+			return nil, start.Errorf("default value is not a constant: %v", def)
+		}
+	}
+	return def, nil
+}
+
+// addDestructured adds the variables of the destructured arguments
+func addDestructured[V any](idents Identifiers[V], patterns []*Destructuring) Identifiers[V] {
+	for _, d := range patterns {
+		if d != nil {
+			for _, name := range d.Names {
+				idents = idents.Add(name)
+			}
+		}
+	}
+	return idents
+}
+
+// wrapDestructured wraps the body of a function in lets which
+// destructure the arguments given by a pattern.
+func wrapDestructured(body AST, names []string, patterns []*Destructuring) AST {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if d := patterns[i]; d != nil {
+			body = &Let{
+				Destructuring: d,
+				Value:         &Ident{Name: names[i], Line: d.Line},
+				Inner:         body,
+				Line:          d.Line,
+			}
+		}
+	}
+	return body
+}
+
+// isDestructuredArg returns true if the given argument name is
+// a destructuring pattern created by parseIdentList.
+func isDestructuredArg(name string) bool {
+	return strings.HasPrefix(name, "[") || strings.HasPrefix(name, "{")
+}
+
+// isDestructuredArgLet returns true if the given node is a let created
+// by wrapDestructured to destructure an argument of a function.
+func isDestructuredArgLet(ast AST) bool {
+	if l, ok := ast.(*Let); ok && l.Destructuring != nil {
+		if i, ok := l.Value.(*Ident); ok {
+			return isDestructuredArg(i.Name)
+		}
+	}
+	return false
+}
+
+// Body returns the function body without the lets which are
+// used to destructure the arguments of the function.
+func (c *ClosureLiteral) Body() AST {
+	body := c.Func
+	for isDestructuredArgLet(body) {
+		body = body.(*Let).Inner
+	}
+	return body
+}
+
+// isClosureArgs returns true if the tokens following an opening
+// parenthesis are the argument list of a closure.
+func isClosureArgs(tokenizer *Tokenizer) bool {
+	first := tokenizer.Peek()
+	if first.typ == tIdent {
+		second := tokenizer.PeekPeek()
+		if second.typ == tComma {
+			return true
+		}
+		if !(second.typ == tOperate && second.image == "=") && second.typ != tColon {
+			return false
+		}
+	} else if first.typ != tOpenBracket && first.typ != tOpenCurly {
+		return false
+	}
+	// search the closing parenthesis and check if it is followed by '->'
+	depth := 0
+	for n := 1; ; n++ {
+		switch tokenizer.peekN(n).typ {
+		case tOpen, tOpenBracket, tOpenCurly:
+			depth++
+		case tClose, tCloseBracket, tCloseCurly:
+			if depth == 0 {
+				t := tokenizer.peekN(n + 1)
+				return t.typ == tOperate && t.image == "->"
+			}
+			depth--
+		case tEof, tSemicolon:
+			return false
 		}
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -37,7 +38,7 @@ func (np numberParser) ParseNumber(n string) (int, error) {
 }
 
 var parser = NewParser[int]().
-	SetKeyWords("let", "switch", "case", "default", "func", "if", "then", "else", "try", "catch").
+	SetKeyWords("let", "switch", "case", "match", "default", "func", "if", "then", "else", "try", "catch", "import", "const", "for").
 	SetNumberParser(numberParser{}).
 	SetOptimizer(&simpleOptimizer{}).
 	Op("<", ">", "=", "+", "-", "*", "/", "^").
@@ -85,6 +86,24 @@ func TestParser(t *testing.T) {
 		{exp: "x^3", opt: "x^3", args: []string{"x"}},
 		{exp: "xˆ3", opt: "x^3", args: []string{"x"}},
 		{exp: "xˆ3+xˆ2", opt: "(x^3)+(x^2)", args: []string{"x"}},
+		{exp: "import \"m\" as m; m.f(1+1)", opt: "import \"m\" as m; m.f(2)"},
+		{exp: "const c=1+1; c*x", opt: "const c=2; 2*x", args: []string{"x"}},
+		{exp: "a?.b?.c", opt: "a?.b?.c", args: []string{"a"}},
+		{exp: "a?.m(1+1).n()", opt: "a?.m(2).n()", args: []string{"a"}},
+		{exp: "x |> f", opt: "f(x)", args: []string{"x", "f"}},
+		{exp: "x+1 |> f(1+1)", opt: "f(x+1, 2)", args: []string{"x", "f"}},
+		{exp: "x |> f |> g(2)", opt: "g(f(x), 2)", args: []string{"x", "f", "g"}},
+		{exp: "x |> (f(2))", opt: "f(2)(x)", args: []string{"x", "f"}},
+		{exp: "x |> (f)(2)", opt: "f(x, 2)", args: []string{"x", "f"}},
+		{exp: "x |> a.f", opt: "a.f(x)", args: []string{"x", "a"}},
+		{exp: "x |> f(b: 2)", opt: "f(x, b: 2)", args: []string{"x", "f"}},
+		{exp: "1..x+1", opt: "1..x+1", args: []string{"x"}},
+		{exp: "0..10*10 step 1+4", opt: "0..100 step 5"},
+		{exp: "x..x.b", opt: "x..x.b", args: []string{"x"}},
+		{exp: "1..x |> f", opt: "f(1..x)", args: []string{"x", "f"}},
+		{exp: "[a*a for a in l if a>1+1]", opt: "[a*a for a in l if a>2]", args: []string{"l"}},
+		{exp: "[[a, b] for a in l for b in a..3]", opt: "[[a, b] for a in l for b in a..3]", args: []string{"l"}},
+		{exp: "[[a for a in l], 1]", opt: "[[a for a in l], 1]", args: []string{"l"}},
 	}
 
 	for _, test := range tests {
@@ -278,3 +297,161 @@ func TestCodeGen(t *testing.T) {
 		})
 	}
 }
+
+func TestParserSpan(t *testing.T) {
+	tests := []struct {
+		exp  string
+		args []string
+		src  string
+		col  int
+	}{
+		{exp: "a+b*c", args: []string{"a", "b", "c"}, src: "a+b*c", col: 2},
+		{exp: "a.m(b, c)", args: []string{"a", "b", "c"}, src: "a.m(b, c)", col: 3},
+		{exp: "a.m.n", args: []string{"a"}, src: "a.m.n", col: 5},
+		{exp: "f(a)[b]", args: []string{"f", "a", "b"}, src: "f(a)[b]", col: 7},
+		{exp: "  -a ", args: []string{"a"}, src: "-a", col: 3},
+		{exp: "(a,b)->a*b", src: "(a,b)->a*b", col: 6},
+		{exp: "x->x*x", src: "x->x*x", col: 1},
+		{exp: "if a then b else c", args: []string{"a", "b", "c"}, src: "if a then b else c", col: 13},
+		{exp: "[a, b]", args: []string{"a", "b"}, src: "[a, b]", col: 1},
+		{exp: "{x:a}", args: []string{"a"}, src: "{x:a}", col: 5},
+		{exp: "let x=a; x", args: []string{"a"}, src: "let x=a;", col: 5},
+		{exp: "(a+b)*\nc", args: []string{"a", "b", "c"}, src: "(a+b)*\nc", col: 6},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.exp, func(t *testing.T) {
+			var idents Identifiers[int]
+			for _, arg := range test.args {
+				idents = idents.Add(arg)
+			}
+			ast, err := parser.Parse(test.exp, idents)
+			assert.NoError(t, err, test.exp)
+			if ast != nil {
+				assert.Equal(t, test.src, ast.GetLine().Source())
+				assert.Equal(t, test.col, ast.GetLine().Column())
+			}
+		})
+	}
+}
+
+func TestErrorExcerpt(t *testing.T) {
+	var idents Identifiers[int]
+	ast, err := parser.Parse("let y=1;\n  x+a.m(y, 2)", idents.Add("a").Add("x"))
+	assert.NoError(t, err)
+	m := ast.(*Operate).B.(*MethodCall)
+	err = m.EnhanceErrorf(m.Args[1].GetLine().Errorf("inner"), "outer")
+
+	assert.Equal(t, "outer in line 2;\n cause: inner in line 2\n  x+a.m(y, 2)\n           ^", err.Error())
+	l, ok := GetErrorLine(err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, l.Num)
+	assert.Equal(t, 12, l.Column())
+
+	err = m.Errorf("method failed")
+	assert.Equal(t, "method failed in line 2\n  x+a.m(y, 2)\n    ^^^^^^^^^", err.Error())
+
+	_, ok = GetErrorLine(fmt.Errorf("no position"))
+	assert.False(t, ok)
+}
+
+func TestParseRecover(t *testing.T) {
+	tests := []struct {
+		name  string
+		exp   string
+		args  []string
+		ast   string
+		diags []string
+	}{
+		{name: "ok", exp: "let a=x; a+1", args: []string{"x"}, ast: "let a=x; a+1"},
+		{name: "let", exp: "let a=1+;\nlet b=(2*);\nlet c=[a,,b];\nc", ast: "let a=<error>; let b=<error>; let c=[a, <error>, b]; c",
+			diags: []string{
+				"1:9: unexpected token type: ; in line 1",
+				"2:10: unexpected token type: ) in line 2",
+				"3:10: unexpected token type: , in line 3",
+			}},
+		{name: "missing semicolon", exp: "let a=1 let b=2; a+b", ast: "3",
+			diags: []string{"1:9: unexpected token, expected ';', found 'let' in line 1"}},
+		{name: "unknown ident", exp: "f(x, y, 2)", args: []string{"f"}, ast: "f(<error>, <error>, 2)",
+			diags: []string{"1:3: identifier 'x' not found in line 1", "1:6: identifier 'y' not found in line 1"}},
+		{name: "map", exp: "{a:1+, b:2, c:(}", ast: "{a:<error>, b:2, c:<error>}",
+			diags: []string{"1:6: unexpected token type: , in line 1", "1:16: unexpected token type: } in line 1"}},
+		{name: "func", exp: "func f(a) a+; f(1)", ast: "let f=a-><error>; f(1)",
+			diags: []string{"1:13: unexpected token type: ; in line 1"}},
+		{name: "trailing", exp: "1+2)", ast: "3",
+			diags: []string{"1:4: unexpected token, expected 'EOF', found ')' in line 1"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var idents Identifiers[int]
+			for _, arg := range test.args {
+				idents = idents.Add(arg)
+			}
+			ast, diags := parser.ParseRecover(test.exp, idents)
+			assert.Equal(t, test.ast, ast.String())
+			var ds []string
+			for _, d := range diags {
+				ds = append(ds, strings.Split(d.String(), "\n")[0])
+			}
+			assert.EqualValues(t, test.diags, ds)
+		})
+	}
+}
+
+func TestParseFailFast(t *testing.T) {
+	_, err := parser.Parse("let a=1+;\nlet b=(2*);\na+b", nil)
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "unexpected token type: ; in line 1"))
+}
+
+func TestAssociativity(t *testing.T) {
+	assocParser := NewParser[int]().
+		SetNumberParser(numberParser{}).
+		OpAssoc(NonAssociative, "<").
+		OpAssoc(NonAssociative, ">").
+		Op("+", "-").
+		OpAssoc(RightAssociative, "^")
+
+	tests := []struct {
+		exp    string
+		str    string
+		pretty string
+		err    string
+	}{
+		{exp: "a-b-c", str: "(a-b)-c", pretty: "a-b-c"},
+		{exp: "a-(b-c)", str: "a-(b-c)", pretty: "a-(b-c)"},
+		{exp: "a^b^c", str: "a^(b^c)", pretty: "a^b^c"},
+		{exp: "(a^b)^c", str: "(a^b)^c", pretty: "(a^b)^c"},
+		{exp: "a^b^c-a", str: "(a^(b^c))-a", pretty: "a^b^c-a"},
+		{exp: "a<b", str: "a<b", pretty: "a<b"},
+		{exp: "(a<b)<c", str: "(a<b)<c", pretty: "(a<b)<c"},
+		{exp: "a<b<c", err: "operator '<' is not associative"},
+		{exp: "a<b>c", err: "operators '<' and '>' are not associative"},
+		{exp: "a>b<c", err: "operators '>' and '<' are not associative"},
+		{exp: "(a)<b>c", err: "operators '<' and '>' are not associative"},
+		{exp: "a<b>c+a", err: "operators '<' and '>' are not associative"},
+		{exp: "a<(b>c)", str: "a<(b>c)", pretty: "a<(b>c)"},
+		{exp: "(a>b)<c", str: "(a>b)<c", pretty: "(a>b)<c"},
+		{exp: "a<b+c", str: "a<(b+c)", pretty: "a<b+c"},
+	}
+
+	var idents Identifiers[int]
+	idents = idents.Add("a").Add("b").Add("c")
+	for _, test := range tests {
+		test := test
+		t.Run(test.exp, func(t *testing.T) {
+			ast, err := assocParser.Parse(test.exp, idents)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.str, ast.String())
+				assert.Equal(t, test.pretty, PrettyPrint[int](ast))
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package parser2
+
+import (
+	"bytes"
+)
+
+// Pattern is a structural pattern used in a match case of a switch
+// like "switch l match [first, ...rest]: first default 0".
+// The variables bound by a pattern are returned by PatternVars.
+type Pattern interface {
+	String() string
+	GetLine() Line
+}
+
+// BindPattern matches every value and binds it to the given name.
+// The name "_" matches every value without binding it.
+type BindPattern struct {
+	Name string
+	Line
+}
+
+func (b *BindPattern) String() string {
+	return b.Name
+}
+
+// TypePattern matches all values of the given type
+type TypePattern struct {
+	Type string
+	Line
+}
+
+func (t *TypePattern) String() string {
+	return t.Type
+}
+
+// ValuePattern matches all values which are equal to the given value
+type ValuePattern struct {
+	Value AST
+	Line
+}
+
+func (v *ValuePattern) String() string {
+	return v.Value.String()
+}
+
+// ListPattern matches lists. If HasRest is set, the list may contain
+// more items than patterns are given. The remaining items are bound
+// to Rest as a list if Rest is not empty or "_".
+type ListPattern struct {
+	Items   []Pattern
+	HasRest bool
+	Rest    string
+	Line
+}
+
+func (l *ListPattern) String() string {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for i, item := range l.Items {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(item.String())
+	}
+	if l.HasRest {
+		if len(l.Items) > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("..." + l.Rest)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// MapPatternEntry is a key of a map pattern and the
+// pattern the value of this key has to match
+type MapPatternEntry struct {
+	Key     string
+	Pattern Pattern
+}
+
+// IsShort returns true if the entry binds the value to
+// a variable with the same name as the key like in "{r}".
+func (e MapPatternEntry) IsShort() bool {
+	b, ok := e.Pattern.(*BindPattern)
+	return ok && b.Name == e.Key
+}
+
+// MapPattern matches maps which contain all the given keys
+type MapPattern struct {
+	Entries []MapPatternEntry
+	Line
+}
+
+func (m *MapPattern) String() string {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, e := range m.Entries {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if e.IsShort() {
+			b.WriteString(e.Key)
+		} else {
+			b.WriteString(e.Key + ": " + e.Pattern.String())
+		}
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// isBound returns true if the given name binds a value
+func isBound(name string) bool {
+	return name != "" && name != "_"
+}
+
+// PatternVars returns the names of the variables bound by the given
+// pattern. The variables are ordered depth first from left to right.
+// The rest of a list pattern is bound after the items of the list.
+func PatternVars(p Pattern) []string {
+	return appendPatternVars(nil, p)
+}
+
+func appendPatternVars(vars []string, p Pattern) []string {
+	switch pa := p.(type) {
+	case *BindPattern:
+		if isBound(pa.Name) {
+			vars = append(vars, pa.Name)
+		}
+	case *ListPattern:
+		for _, item := range pa.Items {
+			vars = appendPatternVars(vars, item)
+		}
+		if pa.HasRest && isBound(pa.Rest) {
+			vars = append(vars, pa.Rest)
+		}
+	case *MapPattern:
+		for _, e := range pa.Entries {
+			vars = appendPatternVars(vars, e.Pattern)
+		}
+	}
+	return vars
+}
+
+// parseMatchCase parses a match case of a switch. The keyword
+// "match" is already consumed.
+func (p *Parser[V]) parseMatchCase(tokenizer *Tokenizer, idents Identifiers[V]) (Case[V], error) {
+	pattern, err := p.parsePattern(tokenizer, idents)
+	if err != nil {
+		return Case[V]{}, err
+	}
+	vars := PatternVars(pattern)
+	for i, v := range vars {
+		for _, o := range vars[:i] {
+			if o == v {
+				return Case[V]{}, pattern.GetLine().Errorf("'%s' used twice in pattern", v)
+			}
+		}
+		idents = idents.Add(v)
+	}
+
+	var guard AST
+	if t := tokenizer.Peek(); t.typ == tKeyWord && t.image == "if" {
+		tokenizer.Next()
+		guard, err = p.parseExpression(tokenizer, idents)
+		if err != nil {
+			return Case[V]{}, err
+		}
+	}
+	if t := tokenizer.Next(); t.typ != tColon {
+		return Case[V]{}, unexpected(":", t)
+	}
+	resultExp, err := p.parseLet(tokenizer, idents)
+	if err != nil {
+		return Case[V]{}, err
+	}
+	return Case[V]{Pattern: pattern, Guard: guard, Value: resultExp}, nil
+}
+
+// parsePattern parses a pattern of a match case
+func (p *Parser[V]) parsePattern(tokenizer *Tokenizer, idents Identifiers[V]) (Pattern, error) {
+	t := tokenizer.Peek()
+	switch t.typ {
+	case tOpenBracket:
+		return p.parseListPattern(tokenizer, idents)
+	case tOpenCurly:
+		return p.parseMapPattern(tokenizer, idents)
+	case tIdent:
+		if p.isType != nil && p.isType(t.image) {
+			tokenizer.Next()
+			return &TypePattern{Type: t.image, Line: t.Line}, nil
+		}
+		if idents != nil {
+			if i, ok := idents(t.image); ok && i.IsConst && !i.IsFunc {
+				return p.parseValuePattern(tokenizer, idents)
+			}
+		}
+		tokenizer.Next()
+		return &BindPattern{Name: t.image, Line: t.Line}, nil
+	case tNumber, tString, tOperate:
+		return p.parseValuePattern(tokenizer, idents)
+	default:
+		tokenizer.Next()
+		return nil, t.Errorf("expected pattern, found %v", t)
+	}
+}
+
+func (p *Parser[V]) parseValuePattern(tokenizer *Tokenizer, idents Identifiers[V]) (Pattern, error) {
+	start := tokenizer.Peek().Line
+	value, err := p.parseUnary(tokenizer, idents)
+	if err != nil {
+		return nil, err
+	}
+	if p.optimizer != nil {
+		value = Optimize(value, p.optimizer)
+	}
+	return &ValuePattern{Value: value, Line: start.span(start, tokenizer.Last().End)}, nil
+}
+
+func (p *Parser[V]) parseListPattern(tokenizer *Tokenizer, idents Identifiers[V]) (Pattern, error) {
+	t := tokenizer.Next()
+	l := &ListPattern{Line: t.Line}
+	if t := tokenizer.Peek(); t.typ == tCloseBracket {
+		tokenizer.Next()
+		l.Line = l.Line.span(l.Line, t.End)
+		return l, nil
+	}
+	for {
+		if tokenizer.Peek().typ == tDot {
+			for i := 0; i < 3; i++ {
+				if t := tokenizer.Next(); t.typ != tDot {
+					return nil, unexpected("...", t)
+				}
+			}
+			if t := tokenizer.Peek(); t.typ == tIdent {
+				tokenizer.Next()
+				l.Rest = t.image
+			}
+			l.HasRest = true
+			t = tokenizer.Next()
+			if t.typ != tCloseBracket {
+				return nil, t.Errorf("the rest of a list pattern has to be the last item, found %v", t)
+			}
+			l.Line = l.Line.span(l.Line, t.End)
+			return l, nil
+		}
+		item, err := p.parsePattern(tokenizer, idents)
+		if err != nil {
+			return nil, err
+		}
+		l.Items = append(l.Items, item)
+		t = tokenizer.Next()
+		switch t.typ {
+		case tCloseBracket:
+			l.Line = l.Line.span(l.Line, t.End)
+			return l, nil
+		case tComma:
+		default:
+			return nil, t.Errorf("expected ',' or ']', found %v", t)
+		}
+	}
+}
+
+func (p *Parser[V]) parseMapPattern(tokenizer *Tokenizer, idents Identifiers[V]) (Pattern, error) {
+	t := tokenizer.Next()
+	m := &MapPattern{Line: t.Line}
+	for {
+		t = tokenizer.Next()
+		if t.typ != tIdent {
+			return nil, t.Errorf("expected key in map pattern, found %v", t)
+		}
+		key := t.image
+		for _, e := range m.Entries {
+			if e.Key == key {
+				return nil, t.Errorf("key '%s' used twice in map pattern", key)
+			}
+		}
+		var pattern Pattern = &BindPattern{Name: key, Line: t.Line}
+		if tokenizer.Peek().typ == tColon {
+			tokenizer.Next()
+			var err error
+			pattern, err = p.parsePattern(tokenizer, idents)
+			if err != nil {
+				return nil, err
+			}
+		}
+		m.Entries = append(m.Entries, MapPatternEntry{Key: key, Pattern: pattern})
+		t = tokenizer.Next()
+		switch t.typ {
+		case tCloseCurly:
+			m.Line = m.Line.span(m.Line, t.End)
+			return m, nil
+		case tComma:
+		default:
+			return nil, t.Errorf("expected ',' or '}', found %v", t)
+		}
+	}
+}
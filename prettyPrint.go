@@ -3,6 +3,8 @@ package parser2
 import (
 	"bytes"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 func PrettyPrint[V any](ast AST) string {
@@ -12,42 +14,89 @@ func PrettyPrint[V any](ast AST) string {
 }
 
 func prettyPrintAST[V any](buf *writer, ast AST) {
+	if f := buf.pw.f; f != nil {
+		f.enter(buf, ast.GetLine())
+		defer f.leave(ast.GetLine())
+	}
 	switch e := ast.(type) {
 	case *Const[V]:
-		buf.writeString(fmt.Sprint(e.Value))
+		buf.writeSource(e.Line, fmt.Sprint(e.Value))
 	case *Ident:
-		buf.writeString(e.Name)
+		buf.writeSource(e.Line, e.Name)
 	case *MapAccess:
-		prettyPrintAST[V](buf, e.MapValue)
-		buf.writeString(".")
+		if id, ok := e.MapValue.(*Ident); ok && buf.pw.f != nil && id.Line == e.Line {
+			// access to a map created by the parser in a 'this' context
+			buf.writeString(e.Key)
+			break
+		}
+		writeParentheses[V](buf, e.MapValue, postfixParenthesesNeeded[V](e.MapValue))
+		buf.writeString(dotStr(e.Optional))
 		buf.writeString(e.Key)
 	case *ListAccess:
-		prettyPrintAST[V](buf, e.List)
+		writeParentheses[V](buf, e.List, postfixParenthesesNeeded[V](e.List))
 		buf.writeString("[")
 		prettyPrintAST[V](buf, e.Index)
 		buf.writeString("]")
 	case *Unary:
 		buf.writeString(e.Operator)
-		prettyPrintAST[V](buf, e.Value)
+		switch e.Value.(type) {
+		case *Operate, *Unary, *Range:
+			writeParentheses[V](buf, e.Value, true)
+		case *FunctionCall:
+			writeParentheses[V](buf, e.Value, isPipe(e.Value))
+		default:
+			prettyPrintAST[V](buf, e.Value)
+		}
 	case *FunctionCall:
-		prettyPrintAST[V](buf, e.Func)
-		writeArgs[V](buf, e.Args)
+		if e.Piped {
+			writeParentheses[V](buf, e.Args[0], operandParenthesesNeeded[V](e.Args[0]) && !isPipe(e.Args[0]))
+			buf.writeString(" |> ")
+			writeParentheses[V](buf, e.Func, postfixParenthesesNeeded[V](e.Func) || isCall(e.Func))
+			if len(e.Args) > 1 {
+				var names []string
+				if e.ArgNames != nil {
+					names = e.ArgNames[1:]
+				}
+				writeArgs[V](buf, e.Args[1:], names)
+			}
+			break
+		}
+		writeParentheses[V](buf, e.Func, postfixParenthesesNeeded[V](e.Func))
+		writeArgs[V](buf, e.Args, e.ArgNames)
 	case *Operate:
-		if io, ok := e.A.(*Operate); ok && io.Priority < e.Priority {
-			buf.writeString("(")
-			prettyPrintAST[V](buf, e.A)
-			buf.writeString(")")
-		} else {
-			prettyPrintAST[V](buf, e.A)
+		needed := operandParenthesesNeeded[V](e.A)
+		if io, ok := e.A.(*Operate); ok && (io.Priority < e.Priority || io.Priority == e.Priority && e.Associativity != LeftAssociative || io.Associativity == NonAssociative && e.Associativity == NonAssociative) {
+			needed = true
+		}
+		if u, ok := e.A.(*Unary); ok && buf.pw.f != nil {
+			// the unary operation would include the operation
+			if pos := buf.pw.f.unaryPos(u.Operator); pos >= 0 && e.Priority > pos {
+				needed = true
+			}
 		}
+		writeParentheses[V](buf, e.A, needed)
 		buf.writeString(e.Operator)
-		if io, ok := e.B.(*Operate); ok && io.Priority < e.Priority {
-			buf.writeString("(")
-			prettyPrintAST[V](buf, e.B)
-			buf.writeString(")")
-		} else {
-			prettyPrintAST[V](buf, e.B)
+		needed = operandParenthesesNeeded[V](e.B)
+		if io, ok := e.B.(*Operate); ok && (io.Priority < e.Priority || io.Priority == e.Priority && e.Associativity != RightAssociative || io.Associativity == NonAssociative && e.Associativity == NonAssociative) {
+			needed = true
+		}
+		writeParentheses[V](buf, e.B, needed)
+	case *Interpolation:
+		if f := buf.pw.f; f != nil {
+			// keep the escapes as found in the source
+			buf.writeString(f.verbatim(e.Line))
+			break
+		}
+		buf.writeString("\"")
+		for i, str := range e.Strings {
+			if i > 0 {
+				buf.writeString("${")
+				prettyPrintAST[V](buf, e.Values[i-1])
+				buf.writeString("}")
+			}
+			buf.writeString(interpolationEscaper.Replace(str))
 		}
+		buf.writeString("\"")
 	case *ListLiteral:
 		buf.writeString("[")
 		for i, item := range e.List {
@@ -57,14 +106,39 @@ func prettyPrintAST[V any](buf *writer, ast AST) {
 			prettyPrintAST[V](buf, item)
 		}
 		buf.writeString("]")
+	case *ListComprehension:
+		buf.writeString("[")
+		prettyPrintAST[V](buf, e.Value)
+		for _, g := range e.Generators {
+			buf.writeString(" for " + g.Name + " in ")
+			prettyPrintAST[V](buf, g.List)
+			if g.Cond != nil {
+				buf.writeString(" if ")
+				prettyPrintAST[V](buf, g.Cond)
+			}
+		}
+		buf.writeString("]")
+	case *Range:
+		writeParentheses[V](buf, e.From, rangeParenthesesNeeded(e.From))
+		buf.writeString("..")
+		writeParentheses[V](buf, e.To, rangeParenthesesNeeded(e.To))
+		if e.Step != nil {
+			buf.writeString(" step ")
+			writeParentheses[V](buf, e.Step, rangeParenthesesNeeded(e.Step))
+		}
 	case *MapLiteral:
+		flat := buf.pw.f != nil && fits[V](buf, e)
 		buf.writeString("{")
 		ib := buf.down()
 		i := 0
 		for k, v := range e.Map.Iter {
 			if i > 0 {
 				ib.writeString(",")
-				ib.newLine()
+				if flat {
+					ib.writeString(" ")
+				} else {
+					ib.newLine()
+				}
 			}
 			ib.writeString(k + ": ")
 			prettyPrintAST[V](ib, v)
@@ -72,56 +146,48 @@ func prettyPrintAST[V any](buf *writer, ast AST) {
 		}
 		ib.writeString("}")
 	case *ClosureLiteral:
-		if len(e.Names) == 1 {
+		if e.isShort() {
 			buf.writeString(e.Names[0])
 		} else {
-			buf.writeString("(")
-			for i, n := range e.Names {
-				if i > 0 {
-					buf.writeString(", ")
-				}
-				buf.writeString(n)
-			}
-			buf.writeString(")")
+			writeClosureArgs[V](buf, e)
 		}
 		buf.writeString(" -> ")
-		prettyPrintAST[V](buf.down(), e.Func)
+		prettyPrintAST[V](buf.down(), e.Body())
 	case *MethodCall:
-		do := buf.down()
-		if methodParenthesesNeeded(e.Value) {
-			do.writeString("(")
-			prettyPrintAST[V](do, e.Value)
-			do.writeString(")")
-		} else {
-			prettyPrintAST[V](do, e.Value)
+		if buf.pw.f != nil {
+			writeMethodChain[V](buf, e)
+			break
 		}
+		do := buf.down()
+		writeParentheses[V](do, e.Value, postfixParenthesesNeeded[V](e.Value))
 		do.newLine()
-		do.writeString(" ." + e.Name)
-		writeArgs[V](do, e.Args)
+		do.writeString(" " + dotStr(e.Optional) + e.Name)
+		writeArgs[V](do, e.Args, nil)
 	case *Let:
 		if cl, ok := e.Value.(*ClosureLiteral); ok && cl.ThisName != "" {
-			buf.writeString("func " + cl.ThisName + "(")
-			for i, n := range cl.Names {
-				if i > 0 {
-					buf.writeString(", ")
-				}
-				buf.writeString(n)
-			}
-			buf.writeString(")")
-			ib := buf.indent()
-			ib.newLine()
-			prettyPrintAST[V](ib, cl.Func)
-			ib.writeString(";")
+			writeFunc[V](buf, cl)
 		} else {
-			buf.writeString("let " + e.Name + " = ")
+			buf.writeString("let " + e.VarName() + " = ")
 			prettyPrintAST[V](buf, e.Value)
 			buf.writeString(";")
 		}
-		if buf.tab == 0 {
-			buf.writeString("\n")
+		writeInner[V](buf, e.Line, e.Inner)
+	case *FuncGroup:
+		for i, cl := range e.Funcs {
+			writeFunc[V](buf, cl)
+			if i < len(e.Funcs)-1 {
+				writeDefEnd(buf, cl.Line, e.Funcs[i+1].Start)
+			}
 		}
-		buf.newLine()
-		prettyPrintAST[V](buf, e.Inner)
+		writeInner[V](buf, e.Funcs[len(e.Funcs)-1].Line, e.Inner)
+	case *Import:
+		buf.writeString("import " + strconv.Quote(e.Module) + " as " + e.Name + ";")
+		writeInner[V](buf, e.Line, e.Inner)
+	case *ConstDef:
+		buf.writeString("const " + e.Name + " = ")
+		prettyPrintAST[V](buf, e.Value)
+		buf.writeString(";")
+		writeInner[V](buf, e.Line, e.Inner)
 	case *If:
 		do := buf.down()
 		do.writeString("if ")
@@ -139,8 +205,17 @@ func prettyPrintAST[V any](buf *writer, ast AST) {
 		prettyPrintAST[V](do, e.SwitchValue)
 		for _, cs := range e.Cases {
 			do.newLine()
-			do.writeString("case ")
-			prettyPrintAST[V](do, cs.CaseConst)
+			if cs.Pattern != nil {
+				do.writeString("match ")
+				writePattern[V](do, cs.Pattern)
+				if cs.Guard != nil {
+					do.writeString(" if ")
+					prettyPrintAST[V](do, cs.Guard)
+				}
+			} else {
+				do.writeString("case ")
+				prettyPrintAST[V](do, cs.CaseConst)
+			}
 			do.writeString(": ")
 			prettyPrintAST[V](do, cs.Value)
 		}
@@ -157,13 +232,35 @@ func prettyPrintAST[V any](buf *writer, ast AST) {
 	}
 }
 
-func writeArgs[V any](buf *writer, args []AST) {
+// writeClosureArgs writes the arguments of a closure
+// including their default values.
+func writeClosureArgs[V any](buf *writer, cl *ClosureLiteral) {
+	buf.writeString("(")
+	for i, n := range cl.Names {
+		if i > 0 {
+			buf.writeString(", ")
+		}
+		buf.writeString(n)
+		if t := cl.Type(i); t != "" {
+			buf.writeString(": " + t)
+		}
+		if d := cl.Default(i); d != nil {
+			buf.writeString("=")
+			prettyPrintAST[V](buf, d)
+		}
+	}
+	buf.writeString(")")
+}
+
+// writeArgs writes the arguments of a call. The names are the
+// names of the arguments if there are named arguments.
+func writeArgs[V any](buf *writer, args []AST, names []string) {
 	const maxCmplx = 6
 	cmplx := 0
 	for _, arg := range args {
 		arg.Traverse(VisitorFunc(func(ast AST) bool {
 			switch ast.(type) {
-			case *Let, *If, *Switch[V], *MethodCall:
+			case *Let, *FuncGroup, *If, *Switch[V], *MethodCall:
 				cmplx += 2
 			case *TryCatch, *FunctionCall:
 				cmplx++
@@ -171,12 +268,18 @@ func writeArgs[V any](buf *writer, args []AST) {
 			return cmplx < maxCmplx
 		}))
 	}
+	writeName := func(buf *writer, i int) {
+		if names != nil && names[i] != "" {
+			buf.writeString(names[i] + ": ")
+		}
+	}
 	if cmplx < maxCmplx {
 		buf.writeString("(")
 		for i, arg := range args {
 			if i > 0 {
 				buf.writeString(", ")
 			}
+			writeName(buf, i)
 			prettyPrintAST[V](buf, arg)
 		}
 		buf.writeString(")")
@@ -188,6 +291,7 @@ func writeArgs[V any](buf *writer, args []AST) {
 				do.writeString(",")
 				do.newLine()
 			}
+			writeName(do, i)
 			prettyPrintAST[V](do, arg)
 		}
 		do.newLine()
@@ -195,11 +299,127 @@ func writeArgs[V any](buf *writer, args []AST) {
 	}
 }
 
-func methodParenthesesNeeded(value AST) bool {
-	if _, ok := value.(*ClosureLiteral); ok {
+// writeMethodChain writes a chain of method calls. If the chain does
+// not fit in a single line, every method call is written in its own line.
+func writeMethodChain[V any](buf *writer, e *MethodCall) {
+	var chain []*MethodCall
+	var value AST = e
+	for {
+		if mc, ok := value.(*MethodCall); ok && !postfixParenthesesNeeded[V](mc.Value) {
+			chain = append(chain, mc)
+			value = mc.Value
+		} else {
+			break
+		}
+	}
+	if mc, ok := value.(*MethodCall); ok {
+		// method call on a value which needs parentheses
+		chain = append(chain, mc)
+		value = mc.Value
+	}
+
+	flat := fits[V](buf, e)
+	do := buf.down()
+	writeParentheses[V](do, value, postfixParenthesesNeeded[V](value))
+	for i := len(chain) - 1; i >= 0; i-- {
+		mc := chain[i]
+		if flat {
+			do.writeString(dotStr(mc.Optional) + mc.Name)
+		} else {
+			do.newLine()
+			do.pw.f.flush(do, mc.Pos)
+			do.writeString(" " + dotStr(mc.Optional) + mc.Name)
+		}
+		writeArgs[V](do, mc.Args, nil)
+		do.pw.f.leave(mc.Line)
+	}
+}
+
+// writeInner writes the inner expression of a definition
+// like an import which has the given line
+func writeInner[V any](buf *writer, line Line, inner AST) {
+	writeDefEnd(buf, line, inner.GetLine().Start)
+	prettyPrintAST[V](buf, inner)
+}
+
+// writeDefEnd finishes a definition and starts the line of
+// the next definition or expression which starts at next.
+func writeDefEnd(buf *writer, line Line, next int) {
+	if f := buf.pw.f; f != nil {
+		f.leave(line)
+		f.flushTrailing(buf)
+		if f.blankLine(line.End, next) {
+			buf.writeString("\n")
+		}
+	} else if buf.tab == 0 {
+		buf.writeString("\n")
+	}
+	buf.newLine()
+}
+
+// writeFunc writes a closure defined by a func definition
+func writeFunc[V any](buf *writer, cl *ClosureLiteral) {
+	buf.writeString("func " + cl.ThisName)
+	writeClosureArgs[V](buf, cl)
+	if f := buf.pw.f; f != nil {
+		f.flushHeader(buf, cl.Start, cl.Body().GetLine().Start)
+	}
+	ib := buf.indent()
+	ib.newLine()
+	prettyPrintAST[V](ib, cl.Body())
+	ib.writeString(";")
+}
+
+func writeParentheses[V any](buf *writer, ast AST, parentheses bool) {
+	if parentheses {
+		buf.writeString("(")
+		prettyPrintAST[V](buf, ast)
+		buf.writeString(")")
+	} else {
+		prettyPrintAST[V](buf, ast)
+	}
+}
+
+// postfixParenthesesNeeded returns true if the value of a method call,
+// a function call, a map access or a list access needs parentheses
+func postfixParenthesesNeeded[V any](value AST) bool {
+	switch value.(type) {
+	case *ClosureLiteral, *Operate, *Unary, *If, *Switch[V], *TryCatch, *Let, *FuncGroup, *Range:
 		return true
 	}
-	if _, ok := value.(*Operate); ok {
+	return isPipe(value)
+}
+
+// rangeParenthesesNeeded returns true if a bound or the step of a range needs parentheses
+func rangeParenthesesNeeded(value AST) bool {
+	if _, ok := value.(*Range); ok {
+		return true
+	}
+	return isPipe(value)
+}
+
+// operandParenthesesNeeded returns true if the operand of an operation
+// needs parentheses independent of the priority of the operation
+func operandParenthesesNeeded[V any](value AST) bool {
+	switch value.(type) {
+	case *ClosureLiteral, *If, *Switch[V], *TryCatch, *Let, *FuncGroup, *Range:
+		return true
+	}
+	return isPipe(value)
+}
+
+// isPipe returns true if the given AST is a function call
+// written with the pipe operator
+func isPipe(value AST) bool {
+	fc, ok := value.(*FunctionCall)
+	return ok && fc.Piped
+}
+
+// isCall returns true if the given AST is a function or method call.
+// Such a call needs parentheses if it is the target of a pipe.
+func isCall(value AST) bool {
+	switch value.(type) {
+	case *FunctionCall, *MethodCall:
 		return true
 	}
 	return false
@@ -208,11 +428,22 @@ func methodParenthesesNeeded(value AST) bool {
 type posWriter struct {
 	buf bytes.Buffer
 	col int
+	f   *formatter
 }
 
 func (w *posWriter) writeString(s string) {
 	w.buf.WriteString(s)
-	w.col += len(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		// multi line raw string
+		w.col = len(s) - i - 1
+	} else {
+		w.col += len(s)
+	}
+}
+
+func (w *posWriter) endsWithBlank() bool {
+	b := w.buf.Bytes()
+	return len(b) > 0 && b[len(b)-1] == ' '
 }
 
 func (w *posWriter) newLine() {
@@ -260,7 +491,17 @@ func (w *writer) writeIndent() {
 	}
 }
 
+func (w *writer) writeSource(l Line, def string) {
+	if w.pw.f != nil {
+		def = w.pw.f.source(l, def)
+	}
+	w.writeString(def)
+}
+
 func (w *writer) newLine() {
+	if w.pw.f != nil {
+		w.pw.f.flushTrailing(w)
+	}
 	w.pw.newLine()
 	w.writeIndent()
 }
@@ -268,3 +509,40 @@ func (w *writer) newLine() {
 func (w *writer) String() string {
 	return w.pw.String()
 }
+
+func writePattern[V any](buf *writer, p Pattern) {
+	switch pa := p.(type) {
+	case *ValuePattern:
+		prettyPrintAST[V](buf, pa.Value)
+	case *ListPattern:
+		buf.writeString("[")
+		for i, item := range pa.Items {
+			if i > 0 {
+				buf.writeString(", ")
+			}
+			writePattern[V](buf, item)
+		}
+		if pa.HasRest {
+			if len(pa.Items) > 0 {
+				buf.writeString(", ")
+			}
+			buf.writeString("..." + pa.Rest)
+		}
+		buf.writeString("]")
+	case *MapPattern:
+		buf.writeString("{")
+		for i, e := range pa.Entries {
+			if i > 0 {
+				buf.writeString(", ")
+			}
+			buf.writeString(e.Key)
+			if !e.IsShort() {
+				buf.writeString(": ")
+				writePattern[V](buf, e.Pattern)
+			}
+		}
+		buf.writeString("}")
+	default:
+		buf.writeString(p.String())
+	}
+}
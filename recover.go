@@ -0,0 +1,162 @@
+package parser2
+
+import (
+	"fmt"
+	"log"
+)
+
+// ErrorNode is used by ParseRecover to replace a part of the
+// source code which could not be parsed.
+type ErrorNode struct {
+	Err error
+	Line
+}
+
+func (e *ErrorNode) Traverse(visitor Visitor) {
+	visitor.Visit(e)
+}
+
+func (e *ErrorNode) Optimize(Optimizer) {
+}
+
+func (e *ErrorNode) String() string {
+	return "<error>"
+}
+
+// Diagnostic describes a syntax error found by ParseRecover
+type Diagnostic struct {
+	Err error
+	Line
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %v", d.Num, d.Column(), d.Err)
+}
+
+// recovery collects the diagnostics if the parser runs in recovery mode
+type recovery struct {
+	diagnostics []Diagnostic
+}
+
+// ParseRecover parses the given string like Parse, but does not stop at
+// the first syntax error. Instead, the parser skips the erroneous tokens up
+// to the next ';', ')', ']', '}' or keyword and continues. The returned AST
+// contains an ErrorNode for every part of the source that could not be parsed.
+// All errors found are returned as diagnostics.
+func (p *Parser[V]) ParseRecover(str string, idents Identifiers[V]) (AST, []Diagnostic) {
+	tokenizer := p.newTokenizer(str)
+	r := &recovery{}
+	tokenizer.recovery = r
+
+	start := tokenizer.Peek().Line
+	ast, err := p.parseLet(tokenizer, idents)
+	if err != nil {
+		ast, _ = recoverFrom(tokenizer, err, start)
+	}
+	if t := tokenizer.Next(); t.typ != tEof {
+		r.add(unexpected("EOF", t), t.Line)
+	}
+
+	if p.optimizer != nil {
+		ast = Optimize(ast, p.optimizer)
+	}
+
+	if p.debug {
+		log.Println("AST main:\n" + PrettyPrint[V](ast))
+	}
+
+	return ast, r.diagnostics
+}
+
+func (r *recovery) add(err error, line Line) {
+	if l, ok := GetErrorLine(err); ok {
+		line = l
+	}
+	if n := len(r.diagnostics); n > 0 && r.diagnostics[n-1].Pos == line.Pos {
+		// follow-up error at the same position
+		return
+	}
+	r.diagnostics = append(r.diagnostics, Diagnostic{Err: err, Line: line})
+}
+
+// recoverFrom is called if an error occurred while parsing the source
+// starting at start. In fail-fast mode the error is returned unchanged.
+// In recovery mode the error is recorded, the tokens up to the next
+// synchronization point are skipped and an ErrorNode is returned.
+func recoverFrom(tokenizer *Tokenizer, err error, start Line) (AST, error) {
+	if tokenizer.recovery == nil {
+		return nil, err
+	}
+	tokenizer.recovery.add(err, start)
+	skipToSync(tokenizer)
+	return &ErrorNode{Err: err, Line: start.span(start, tokenizer.Last().End)}, nil
+}
+
+// recoverNode is used in case of errors which leave the token stream
+// intact, like unknown identifiers. In recovery mode the error is recorded
+// and an ErrorNode is returned without skipping any tokens.
+func recoverNode(tokenizer *Tokenizer, err error, line Line) (AST, error) {
+	if tokenizer.recovery == nil {
+		return nil, err
+	}
+	tokenizer.recovery.add(err, line)
+	return &ErrorNode{Err: err, Line: line}, nil
+}
+
+// expect consumes the next token which needs to be of the given type.
+// In recovery mode a missing token is recorded and the tokens up to the
+// next synchronization point are skipped. If the expected token is found
+// there, it is consumed.
+func expect(tokenizer *Tokenizer, typ TokenType, image string) error {
+	if tokenizer.recovery == nil {
+		if t := tokenizer.Next(); t.typ != typ || t.image != image {
+			return unexpected(image, t)
+		}
+		return nil
+	}
+	t := tokenizer.Peek()
+	if t.typ == typ && t.image == image {
+		tokenizer.Next()
+		return nil
+	}
+	tokenizer.recovery.add(unexpected(image, t), t.Line)
+	skipToSync(tokenizer)
+	if t := tokenizer.Peek(); t.typ == typ && t.image == image {
+		tokenizer.Next()
+	}
+	return nil
+}
+
+// skipToSync skips all tokens up to the next synchronization point.
+// These are the tokens ';', ',', ')', ']', '}' and keywords which are
+// not nested in brackets. The synchronization token itself is not consumed.
+func skipToSync(tokenizer *Tokenizer) {
+	depth := 0
+	for {
+		switch tokenizer.Peek().typ {
+		case tEof:
+			return
+		case tOpen, tOpenBracket, tOpenCurly:
+			depth++
+		case tClose, tCloseBracket, tCloseCurly:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case tSemicolon, tComma, tKeyWord:
+			if depth == 0 {
+				return
+			}
+		}
+		tokenizer.Next()
+	}
+}
+
+// isSyncToken returns true if the token terminates an expression
+func isSyncToken(t Token) bool {
+	switch t.typ {
+	case tEof, tSemicolon, tComma, tClose, tCloseBracket, tCloseCurly:
+		return true
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package parser2
 
 import (
 	"encoding/hex"
+	"slices"
 	"strings"
 	"unicode/utf8"
 )
@@ -23,6 +24,9 @@ const (
 	tSemicolon
 	tNumber
 	tString
+	tInterpolStart
+	tInterpolMid
+	tInterpolEnd
 	tOperate
 	tEof
 	tInvalid
@@ -32,7 +36,7 @@ const (
 	EOF rune = 0
 )
 
-var TokenEof = Token{tEof, "EOF", -1}
+var TokenEof = Token{tEof, "EOF", Line{Num: -1}}
 
 type Token struct {
 	typ   TokenType
@@ -56,13 +60,20 @@ func (t Token) String() string {
 }
 
 type Tokenizer struct {
+	src              string
 	str              string
+	lastPos          int
+	peekPos          int
+	prev             Line
 	isLast           bool
 	last             rune
-	tok              chan Token
+	mapped           bool
+	token            []Token
+	tokenFirst       int
 	tokenAvail       int
-	token            [2]Token
-	line             Line
+	lastTokenType    TokenType
+	lastWasBlank     bool
+	line             int
 	number           Matcher
 	identifier       Matcher
 	operatorDetector OperatorDetector
@@ -70,6 +81,13 @@ type Tokenizer struct {
 	allowComments    bool
 	keyWord          map[string]bool
 	comfortEnabled   bool
+	recovery         *recovery
+	collectComments  bool
+	comments         []Comment
+	interpolation    []int
+	// funcRuns caches the names of consecutive func definitions
+	// by the start of the first definition
+	funcRuns map[int][]string
 }
 
 type Matcher func(r rune) (func(r rune) bool, bool)
@@ -120,21 +138,24 @@ func NewOperatorDetector(operators []string) OperatorDetector {
 }
 
 func NewTokenizer(text string, number, identifier Matcher, operatorDetector OperatorDetector) *Tokenizer {
-	t := make(chan Token)
 	tok := &Tokenizer{
+		src:              text,
 		str:              text,
 		textOperators:    make(map[string]string),
+		token:            make([]Token, 4),
 		number:           number,
 		identifier:       identifier,
 		keyWord:          map[string]bool{},
 		operatorDetector: operatorDetector,
-		line:             1,
-		tok:              t}
+		lastTokenType:    tInvalid,
+		line:             1}
 	return tok
 }
 
+// Start prepares the tokenizer for reading tokens.
+// The tokens are created on demand by Peek, PeekPeek and Next,
+// so there is nothing to clean up if the parsing is aborted.
 func (t *Tokenizer) Start() *Tokenizer {
-	go t.run(t.tok)
 	return t
 }
 
@@ -146,145 +167,221 @@ func (t *Tokenizer) PeekPeek() Token {
 	return t.forward(2)
 }
 
+// peekN returns the n-th token in front of the tokenizer without consuming it.
+// peekN(1) is the same as Peek.
+func (t *Tokenizer) peekN(n int) Token {
+	return t.forward(n)
+}
+
 func (t *Tokenizer) forward(i int) Token {
 	for t.tokenAvail < i {
-		var ok bool
-		t.token[t.tokenAvail], ok = <-t.tok
-		if ok {
-			t.tokenAvail++
-		} else {
+		if !t.scan() {
 			return TokenEof
 		}
 	}
-	return t.token[i-1]
+	return t.token[(t.tokenFirst+i-1)%len(t.token)]
+}
+
+// clone returns a copy of the tokenizer which is able to look ahead
+// any number of tokens. Reading tokens from the copy does not consume
+// them from the original tokenizer. Errors are not recovered by the copy
+// and comments are not collected.
+func (t *Tokenizer) clone() *Tokenizer {
+	c := *t
+	c.token = slices.Clone(t.token)
+	c.interpolation = slices.Clone(t.interpolation)
+	c.recovery = nil
+	c.collectComments = false
+	c.comments = nil
+	return &c
 }
 
 func (t *Tokenizer) Next() Token {
-	switch t.tokenAvail {
-	case 2:
-		to := t.token[0]
-		t.token[0] = t.token[1]
+	to := t.forward(1)
+	if to.typ != tEof {
+		t.tokenFirst = (t.tokenFirst + 1) % len(t.token)
 		t.tokenAvail--
-		return to
-	case 1:
-		t.tokenAvail--
-		return t.token[0]
-	default:
-		to, ok := <-t.tok
-		if ok {
-			return to
-		} else {
-			return TokenEof
+		t.prev = to.Line
+	}
+	return to
+}
+
+// emit appends a token to the lookahead buffer
+func (t *Tokenizer) emit(to Token) {
+	if t.tokenAvail == len(t.token) {
+		// lookahead buffer is full, increase its size
+		token := make([]Token, max(4, 2*len(t.token)))
+		for i := 0; i < t.tokenAvail; i++ {
+			token[i] = t.token[(t.tokenFirst+i)%len(t.token)]
 		}
+		t.token = token
+		t.tokenFirst = 0
 	}
+	t.token[(t.tokenFirst+t.tokenAvail)%len(t.token)] = to
+	t.tokenAvail++
+}
+
+// Last returns the position of the last token returned by Next.
+func (t *Tokenizer) Last() Line {
+	return t.prev
+}
+
+// offset returns the byte offset of the next rune to read.
+// Comments skipped by peeking the next rune are not included.
+func (t *Tokenizer) offset() int {
+	if t.isLast {
+		return t.peekPos
+	}
+	return len(t.src) - len(t.str)
+}
+
+func (t *Tokenizer) newToken(typ TokenType, image string, line, start int) Token {
+	return Token{typ, image, Line{
+		Num:   line,
+		Pos:   start,
+		Start: start,
+		End:   t.offset(),
+		src:   t.src,
+	}}
 }
 
-func (t *Tokenizer) getLine() Line {
-	return t.line
+// newImplicitToken creates a token which is not present in the source,
+// like the multiplication in "2a". It has an empty range in front of start.
+func (t *Tokenizer) newImplicitToken(typ TokenType, image string, line, start int) Token {
+	return Token{typ, image, Line{
+		Num:   line,
+		Pos:   start,
+		Start: start,
+		End:   start,
+		src:   t.src,
+	}}
 }
 
-func (t *Tokenizer) run(tokens chan<- Token) {
-	lastTokenType := tInvalid
-	lastWasBlank := false
+// scan reads the next token(s) from the source and adds them
+// to the lookahead buffer. If the end of the source is reached,
+// false is returned.
+func (t *Tokenizer) scan() bool {
 	for {
 		thisTokenType := tInvalid
-		switch n := t.next(true); n {
+		n := t.next(true)
+		start := t.lastPos
+		line := t.line
+		switch n {
 		case '\n':
 			t.line++
-			lastWasBlank = true
+			t.lastWasBlank = true
 			continue
 		case ' ', '\r', '\t':
-			lastWasBlank = true
+			t.lastWasBlank = true
 			continue
 		case EOF:
-			close(tokens)
-			return
+			return false
 		case '(':
-			if lastTokenType == tNumber || lastTokenType == tClose || (lastTokenType == tIdent && lastWasBlank) {
-				tokens <- Token{tOperate, "*", t.getLine()}
+			if t.lastTokenType == tNumber || t.lastTokenType == tClose || (t.lastTokenType == tIdent && t.lastWasBlank) {
+				t.emit(t.newImplicitToken(tOperate, "*", line, start))
 			}
-			tokens <- Token{tOpen, "(", t.getLine()}
+			t.emit(t.newToken(tOpen, "(", line, start))
 		case ')':
-			tokens <- Token{tClose, ")", t.getLine()}
+			t.emit(t.newToken(tClose, ")", line, start))
 			if t.comfortEnabled {
 				thisTokenType = tClose
 			}
 		case '[':
-			tokens <- Token{tOpenBracket, "[", t.getLine()}
+			t.emit(t.newToken(tOpenBracket, "[", line, start))
 		case ']':
-			tokens <- Token{tCloseBracket, "]", t.getLine()}
+			t.emit(t.newToken(tCloseBracket, "]", line, start))
 		case '{':
-			tokens <- Token{tOpenCurly, "{", t.getLine()}
+			if n := len(t.interpolation); n > 0 {
+				t.interpolation[n-1]++
+			}
+			t.emit(t.newToken(tOpenCurly, "{", line, start))
 		case '}':
-			tokens <- Token{tCloseCurly, "}", t.getLine()}
+			if n := len(t.interpolation); n > 0 {
+				if t.interpolation[n-1] == 0 {
+					// end of an interpolated expression, continue with the string
+					t.interpolation = t.interpolation[:n-1]
+					t.emit(t.readStr(line, start, true))
+					break
+				}
+				t.interpolation[n-1]--
+			}
+			t.emit(t.newToken(tCloseCurly, "}", line, start))
 		case '.':
-			tokens <- Token{tDot, ".", t.getLine()}
+			t.emit(t.newToken(tDot, ".", line, start))
 		case ':':
-			tokens <- Token{tColon, ":", t.getLine()}
+			t.emit(t.newToken(tColon, ":", line, start))
 		case ',':
-			tokens <- Token{tComma, ",", t.getLine()}
+			t.emit(t.newToken(tComma, ",", line, start))
 		case ';':
-			tokens <- Token{tSemicolon, ";", t.getLine()}
+			t.emit(t.newToken(tSemicolon, ";", line, start))
 		case '"':
-			tokens <- t.readStr()
+			t.emit(t.readStr(line, start, false))
+		case '`':
+			t.emit(t.readRawStr(line, start))
 		case '\'':
 			image := t.readSkip(func(c rune) bool { return c != '\'' }, false)
 			t.next(false)
-			tokens <- Token{tIdent, image, t.getLine()}
+			t.emit(t.newToken(tIdent, image, line, start))
 		case '⁰':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "0", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "0", line, start))
 		case '¹':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "1", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "1", line, start))
 		case '²':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "2", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "2", line, start))
 		case '³':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "3", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "3", line, start))
 		case '⁴':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "4", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "4", line, start))
 		case '⁵':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "5", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "5", line, start))
 		case '⁶':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "6", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "6", line, start))
 		case '⁷':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "7", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "7", line, start))
 		case '⁸':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "8", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "8", line, start))
 		case '⁹':
-			tokens <- Token{tOperate, "^", t.getLine()}
-			tokens <- Token{tNumber, "9", t.getLine()}
+			t.emit(t.newToken(tOperate, "^", line, start))
+			t.emit(t.newToken(tNumber, "9", line, start))
 		default:
 			t.unread()
 			c := t.peek(true)
 			if f, ok := t.number(c); ok {
-				if lastTokenType == tNumber || lastTokenType == tIdent || lastTokenType == tClose {
-					tokens <- Token{tOperate, "*", t.getLine()}
+				if t.lastTokenType == tNumber || t.lastTokenType == tIdent || t.lastTokenType == tClose {
+					t.emit(t.newImplicitToken(tOperate, "*", line, start))
 				}
-				image := t.read(f)
-				tokens <- Token{tNumber, image, t.getLine()}
+				image := t.read(func(c rune) bool {
+					// a number followed by two dots is the start of a range like "1..10"
+					if c == '.' && strings.HasPrefix(t.str, ".") {
+						return false
+					}
+					return f(c)
+				})
+				t.emit(t.newToken(tNumber, image, line, start))
 				if t.comfortEnabled {
 					thisTokenType = tNumber
 				}
 			} else if f, ok := t.identifier(c); ok {
 				image := t.read(f)
 				if to, ok := t.textOperators[image]; ok {
-					tokens <- Token{tOperate, to, t.getLine()}
+					t.emit(t.newToken(tOperate, to, line, start))
 				} else {
 					if t.keyWord[image] {
-						tokens <- Token{tKeyWord, image, t.getLine()}
+						t.emit(t.newToken(tKeyWord, image, line, start))
 					} else {
-						if lastTokenType == tNumber || lastTokenType == tIdent || lastTokenType == tClose {
-							tokens <- Token{tOperate, "*", t.getLine()}
+						if t.lastTokenType == tNumber || t.lastTokenType == tIdent || t.lastTokenType == tClose {
+							t.emit(t.newImplicitToken(tOperate, "*", line, start))
 						}
-						tokens <- Token{tIdent, image, t.getLine()}
+						t.emit(t.newToken(tIdent, image, line, start))
 						if t.comfortEnabled {
 							thisTokenType = tIdent
 						}
@@ -293,29 +390,31 @@ func (t *Tokenizer) run(tokens chan<- Token) {
 				}
 			} else {
 				if op, ok := t.parseOperator(); ok {
-					tokens <- Token{tOperate, op, t.getLine()}
+					t.emit(t.newToken(tOperate, op, line, start))
 				} else {
-					tokens <- Token{tInvalid, op, t.getLine()}
+					t.emit(t.newToken(tInvalid, op, line, start))
 				}
 			}
 		}
-		lastTokenType = thisTokenType
-		lastWasBlank = false
+		t.lastTokenType = thisTokenType
+		t.lastWasBlank = false
+		return true
 	}
 }
 
 func (t *Tokenizer) parseOperator() (string, bool) {
 	r := t.next(false)
 	if d, _ := t.operatorDetector(r); d != nil {
-		op := string(r)
+		op := t.newImage()
+		op.add(r)
 		for {
 			r = t.next(false)
 			var ok bool
 			if d, ok = d(r); d != nil {
-				op += string(r)
+				op.add(r)
 			} else {
 				t.unread()
-				return op, ok
+				return op.String(), ok
 			}
 		}
 	} else {
@@ -327,31 +426,38 @@ func (t *Tokenizer) peek(skipComment bool) rune {
 	if t.isLast {
 		return t.last
 	}
+	t.peekPos = len(t.src) - len(t.str)
 	if len(t.str) == 0 {
 		t.last = EOF
 		return EOF
 	}
 	var size int
 	t.last, size = utf8.DecodeRuneInString(t.str)
+	t.mapped = false
 
 	if t.allowComments && skipComment {
 		if t.last == '/' && len(t.str) > size {
 			s, l := utf8.DecodeRuneInString(t.str[size:])
 			if s == '/' {
+				start := t.peekPos
 				t.str = t.str[size+l:]
 				for {
 					s, l := utf8.DecodeRuneInString(t.str)
 					if s != '\n' && s != '\r' {
 						t.str = t.str[l:]
 						if len(t.str) == 0 {
+							t.addComment(start, t.line)
 							return EOF
 						}
 					} else {
 						break
 					}
 				}
+				t.addComment(start, t.line)
 				t.last, size = utf8.DecodeRuneInString(t.str)
 			} else if s == '*' {
+				start := t.peekPos
+				line := t.line
 				t.str = t.str[size+l:]
 				for {
 					s, l := utf8.DecodeRuneInString(t.str)
@@ -359,6 +465,7 @@ func (t *Tokenizer) peek(skipComment bool) rune {
 						s, l2 := utf8.DecodeRuneInString(t.str[l:])
 						if s == '/' {
 							t.str = t.str[l+l2:]
+							t.addComment(start, line)
 							if len(t.str) == 0 {
 								return EOF
 							}
@@ -384,21 +491,39 @@ func (t *Tokenizer) peek(skipComment bool) rune {
 	switch t.last {
 	case '•':
 		t.last = '*'
+		t.mapped = true
 	case '×':
 		t.last = '*'
+		t.mapped = true
 	case '÷':
 		t.last = '/'
+		t.mapped = true
 	case '–':
 		t.last = '-'
+		t.mapped = true
 	case 'ˆ':
 		t.last = '^'
+		t.mapped = true
 	}
 
 	t.isLast = true
+	t.lastPos = len(t.src) - len(t.str)
 	t.str = t.str[size:]
 	return t.last
 }
 
+// addComment stores the comment which starts at the given offset and
+// ends at the current position, if comments are to be collected.
+func (t *Tokenizer) addComment(start, line int) {
+	if t.collectComments {
+		end := len(t.src) - len(t.str)
+		t.comments = append(t.comments, Comment{
+			Text: t.src[start:end],
+			Line: Line{Num: line, Pos: start, Start: start, End: end, src: t.src},
+		})
+	}
+}
+
 func (t *Tokenizer) consume(skipComment bool) {
 	if !t.isLast {
 		t.peek(skipComment)
@@ -421,10 +546,10 @@ func (t *Tokenizer) read(valid func(c rune) bool) string {
 }
 
 func (t *Tokenizer) readSkip(valid func(c rune) bool, skipComment bool) string {
-	str := strings.Builder{}
+	str := t.newImage()
 	for {
 		if c := t.next(skipComment); c != 0 && valid(c) {
-			str.WriteRune(c)
+			str.add(c)
 		} else {
 			t.unread()
 			return str.String()
@@ -432,14 +557,78 @@ func (t *Tokenizer) readSkip(valid func(c rune) bool, skipComment bool) string {
 	}
 }
 
-func (t *Tokenizer) readStr() Token {
-	str := strings.Builder{}
+// image collects the image of a token. As long as the image is
+// identical to the source, it is a substring of the source, so no
+// allocation is required.
+type image struct {
+	t     *Tokenizer
+	from  int
+	to    int
+	clean bool
+	str   strings.Builder
+}
+
+func (t *Tokenizer) newImage() image {
+	return image{t: t, from: -1, clean: true}
+}
+
+// add adds the rune which was returned by the last call to next
+func (i *image) add(c rune) {
+	t := i.t
+	if i.clean && (t.mapped || (i.from >= 0 && t.lastPos != t.peekPos)) {
+		i.detach()
+	}
+	if i.clean {
+		if i.from < 0 {
+			i.from = t.lastPos
+		}
+		i.to = t.offset()
+	} else {
+		i.str.WriteRune(c)
+	}
+}
+
+// detach copies the runes collected so far, which are in front
+// of the last rune read, to the builder.
+func (i *image) detach() {
+	if i.clean {
+		i.clean = false
+		if i.from >= 0 {
+			i.str.WriteString(i.t.src[i.from:i.t.peekPos])
+		}
+	}
+}
+
+// WriteRune adds a rune which is not found in the source
+func (i *image) WriteRune(c rune) {
+	i.detach()
+	i.str.WriteRune(c)
+}
+
+func (i *image) String() string {
+	if i.clean {
+		if i.from < 0 {
+			return ""
+		}
+		return i.t.src[i.from:i.to]
+	}
+	return i.str.String()
+}
+
+// readStr reads a string literal. If an interpolated expression "${...}"
+// is found, the string read so far is returned as a tInterpolStart token
+// and the tokenizer continues with the tokens of the expression. If the
+// closing '}' of the expression is found, readStr is called again with
+// continued set to true, which returns a tInterpolMid or tInterpolEnd token.
+func (t *Tokenizer) readStr(line, start int, continued bool) Token {
+	str := t.newImage()
 	for {
 		if c := t.next(false); c != '"' {
 			switch c {
 			case 0, '\n', '\r':
-				return Token{tInvalid, "EOL", t.getLine()}
+				return t.newToken(tInvalid, "EOL", line, start)
 			case '\\':
+				str.detach()
 				i := t.next(false)
 				switch i {
 				case 'n':
@@ -452,15 +641,91 @@ func (t *Tokenizer) readStr() Token {
 					str.WriteRune('"')
 				case '\\':
 					str.WriteRune('\\')
+				case '$':
+					str.WriteRune('$')
+				case 'u':
+					r, ok := t.readUnicode()
+					if !ok {
+						t.skipStr()
+						return t.newToken(tInvalid, "invalid unicode escape", line, start)
+					}
+					str.WriteRune(r)
 				default:
 					str.WriteRune('\\')
 					str.WriteRune(i)
 				}
+			case '$':
+				if strings.HasPrefix(t.str, "{") {
+					t.next(false)
+					t.interpolation = append(t.interpolation, 0)
+					if continued {
+						return t.newToken(tInterpolMid, str.String(), line, start)
+					}
+					return t.newToken(tInterpolStart, str.String(), line, start)
+				}
+				str.add(c)
 			default:
-				str.WriteRune(c)
+				str.add(c)
 			}
 		} else {
-			return Token{tString, str.String(), t.getLine()}
+			if continued {
+				return t.newToken(tInterpolEnd, str.String(), line, start)
+			}
+			return t.newToken(tString, str.String(), line, start)
+		}
+	}
+}
+
+// readUnicode reads the four hex digits of a unicode escape like \u00e4
+func (t *Tokenizer) readUnicode() (rune, bool) {
+	var r rune
+	for i := 0; i < 4; i++ {
+		c := t.next(false)
+		switch {
+		case c >= '0' && c <= '9':
+			r = r*16 + c - '0'
+		case c >= 'a' && c <= 'f':
+			r = r*16 + c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			r = r*16 + c - 'A' + 10
+		default:
+			t.unread()
+			return 0, false
+		}
+	}
+	return r, true
+}
+
+// skipStr skips the rest of an invalid string
+func (t *Tokenizer) skipStr() {
+	for {
+		switch t.next(false) {
+		case 0, '"':
+			return
+		case '\n', '\r':
+			t.unread()
+			return
+		}
+	}
+}
+
+// readRawStr reads a raw string literal enclosed in backticks.
+// Raw strings may span several lines and no escapes are processed.
+func (t *Tokenizer) readRawStr(line, start int) Token {
+	str := t.newImage()
+	for {
+		switch c := t.next(false); c {
+		case 0:
+			return t.newToken(tInvalid, "EOF", line, start)
+		case '`':
+			return t.newToken(tString, str.String(), line, start)
+		case '\r':
+			str.detach()
+		case '\n':
+			t.line++
+			str.add(c)
+		default:
+			str.add(c)
 		}
 	}
 }
@@ -2,201 +2,267 @@ package parser2
 
 import (
 	"github.com/stretchr/testify/assert"
+	"runtime"
 	"strings"
 	"testing"
 	"unicode"
 )
 
+// expToken is the expected token used in the tests.
+// Only the line number of the position is checked.
+type expToken struct {
+	typ   TokenType
+	image string
+	line  int
+}
+
+func (e expToken) assert(t *testing.T, found Token) {
+	assert.EqualValues(t, e, expToken{found.typ, found.image, found.Num})
+}
+
 func TestNewTokenizer(t *testing.T) {
 	tests := []struct {
 		name string
 		exp  string
-		want []Token
+		want []expToken
 	}{
 		{
 			name: "op1",
 			exp:  "+-*/",
-			want: []Token{{tOperate, "+", 1}, {tOperate, "-", 1}, {tOperate, "*", 1}, {tOperate, "/", 1}},
+			want: []expToken{{tOperate, "+", 1}, {tOperate, "-", 1}, {tOperate, "*", 1}, {tOperate, "/", 1}},
 		},
 		{
 			name: "op2",
 			exp:  "+->*-+",
-			want: []Token{{tOperate, "+", 1}, {tOperate, "->", 1}, {tOperate, "*", 1}, {tOperate, "-", 1}, {tOperate, "+", 1}},
+			want: []expToken{{tOperate, "+", 1}, {tOperate, "->", 1}, {tOperate, "*", 1}, {tOperate, "-", 1}, {tOperate, "+", 1}},
 		},
 		{
 			name: "simple ident",
 			exp:  "test",
-			want: []Token{{tIdent, "test", 1}},
+			want: []expToken{{tIdent, "test", 1}},
 		},
 		{
 			name: "ident unicode",
 			exp:  "tüb",
-			want: []Token{{tIdent, "tüb", 1}},
+			want: []expToken{{tIdent, "tüb", 1}},
 		},
 		{
 			name: "ident blank",
 			exp:  "a 'A b'",
-			want: []Token{{tIdent, "a", 1}, {tIdent, "A b", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tIdent, "A b", 1}},
 		},
 		{
 			name: "ident blank unicode",
 			exp:  "'tüb'",
-			want: []Token{{tIdent, "tüb", 1}},
+			want: []expToken{{tIdent, "tüb", 1}},
 		},
 		{
 			name: "ident blank unicode comment",
 			exp:  "'t//b'",
-			want: []Token{{tIdent, "t//b", 1}},
+			want: []expToken{{tIdent, "t//b", 1}},
 		},
 		{
 			name: "string",
 			exp:  "\"tüb\"",
-			want: []Token{{tString, "tüb", 1}},
+			want: []expToken{{tString, "tüb", 1}},
 		},
 		{
 			name: "string new line",
 			exp:  "\"t\n",
-			want: []Token{{tInvalid, "EOL", 1}},
+			want: []expToken{{tInvalid, "EOL", 1}},
 		},
 		{
 			name: "string comment",
 			exp:  "\"t//b\"",
-			want: []Token{{tString, "t//b", 1}},
+			want: []expToken{{tString, "t//b", 1}},
 		},
 		{
 			name: "string escape",
 			exp:  "\"t\\\\b\"",
-			want: []Token{{tString, "t\\b", 1}},
+			want: []expToken{{tString, "t\\b", 1}},
 		},
 		{
 			name: "string escape 2",
 			exp:  "\"t\\n\\r\\tb\"",
-			want: []Token{{tString, "t\n\r\tb", 1}},
+			want: []expToken{{tString, "t\n\r\tb", 1}},
 		},
 		{
 			name: "string escape 3",
 			exp:  "\"\\\"\"",
-			want: []Token{{tString, "\"", 1}},
+			want: []expToken{{tString, "\"", 1}},
 		},
 		{
 			name: "string escape 4",
 			exp:  "\"\\#\"",
-			want: []Token{{tString, "\\#", 1}},
+			want: []expToken{{tString, "\\#", 1}},
+		},
+		{
+			name: "string unicode escape",
+			exp:  "\"a\\u00e4b\\u00C4\"",
+			want: []expToken{{tString, "aäbÄ", 1}},
+		},
+		{
+			name: "string invalid unicode escape",
+			exp:  "\"\\u00x\"",
+			want: []expToken{{tInvalid, "invalid unicode escape", 1}},
+		},
+		{
+			name: "raw string",
+			exp:  "`a\\n\"b\nc` d",
+			want: []expToken{{tString, "a\\n\"b\nc", 1}, {tIdent, "d", 2}},
+		},
+		{
+			name: "raw string crlf",
+			exp:  "`a\r\nb`",
+			want: []expToken{{tString, "a\nb", 1}},
+		},
+		{
+			name: "raw string eof",
+			exp:  "`a",
+			want: []expToken{{tInvalid, "EOF", 1}},
+		},
+		{
+			name: "interpolation",
+			exp:  "\"a${b}c${ {d:e}.d }f\"",
+			want: []expToken{
+				{tInterpolStart, "a", 1}, {tIdent, "b", 1},
+				{tInterpolMid, "c", 1}, {tOpenCurly, "{", 1}, {tIdent, "d", 1}, {tColon, ":", 1}, {tIdent, "e", 1}, {tCloseCurly, "}", 1}, {tDot, ".", 1}, {tIdent, "d", 1},
+				{tInterpolEnd, "f", 1}},
+		},
+		{
+			name: "interpolation nested",
+			exp:  "\"a${\"b${c}\"}\"",
+			want: []expToken{{tInterpolStart, "a", 1}, {tInterpolStart, "b", 1}, {tIdent, "c", 1}, {tInterpolEnd, "", 1}, {tInterpolEnd, "", 1}},
+		},
+		{
+			name: "interpolation escaped",
+			exp:  "\"a\\${b}$c\"",
+			want: []expToken{{tString, "a${b}$c", 1}},
 		},
 		{
 			name: "exp",
 			exp:  "(a\n)",
-			want: []Token{{tOpen, "(", 1}, {tIdent, "a", 1}, {tClose, ")", 2}},
+			want: []expToken{{tOpen, "(", 1}, {tIdent, "a", 1}, {tClose, ")", 2}},
 		},
 		{
 			name: "number",
 			exp:  "5.5",
-			want: []Token{{tNumber, "5.5", 1}},
+			want: []expToken{{tNumber, "5.5", 1}},
 		},
 		{
 			name: "comment 1",
 			exp:  "a //test\n b",
-			want: []Token{{tIdent, "a", 1}, {tIdent, "b", 2}},
+			want: []expToken{{tIdent, "a", 1}, {tIdent, "b", 2}},
 		},
 		{
 			name: "comment 2",
 			exp:  "a//->test\nb",
-			want: []Token{{tIdent, "a", 1}, {tIdent, "b", 2}},
+			want: []expToken{{tIdent, "a", 1}, {tIdent, "b", 2}},
 		},
 		{
 			name: "comment 5",
 			exp:  "a/b",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "/", 1}, {tIdent, "b", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "/", 1}, {tIdent, "b", 1}},
 		},
 		{
 			name: "comment 6",
 			exp:  "a/=b",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "/=", 1}, {tIdent, "b", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "/=", 1}, {tIdent, "b", 1}},
 		},
 		{
 			name: "comment 7",
 			exp:  "a/",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "/", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "/", 1}},
 		},
 		{
 			name: "comment 8",
 			exp:  "a//",
-			want: []Token{{tIdent, "a", 1}},
+			want: []expToken{{tIdent, "a", 1}},
 		},
 		{
 			name: "comment 9",
 			exp:  "a//\n",
-			want: []Token{{tIdent, "a", 1}},
+			want: []expToken{{tIdent, "a", 1}},
 		},
 		{
 			name: "comment 10",
 			exp:  "a//ss\n//ss\n\na",
-			want: []Token{{tIdent, "a", 1}, {tIdent, "a", 4}},
+			want: []expToken{{tIdent, "a", 1}, {tIdent, "a", 4}},
 		},
 		{
 			name: "mod",
 			exp:  "a % 10",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "%", 1}, {tNumber, "10", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "%", 1}, {tNumber, "10", 1}},
 		},
 		{
 			name: "var mul",
 			exp:  "a • 10",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "*", 1}, {tNumber, "10", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "*", 1}, {tNumber, "10", 1}},
 		},
 		{
 			name: "var mul 2",
 			exp:  "a × 10",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "*", 1}, {tNumber, "10", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "*", 1}, {tNumber, "10", 1}},
 		},
 		{
 			name: "var div",
 			exp:  "a ÷ 10",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "/", 1}, {tNumber, "10", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "/", 1}, {tNumber, "10", 1}},
 		},
 		{
 			name: "var sub",
 			exp:  "a – 10",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "-", 1}, {tNumber, "10", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "-", 1}, {tNumber, "10", 1}},
 		},
 		{
 			name: "ml comment 1",
 			exp:  "a/* test */+1",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "+", 1}, {tNumber, "1", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "+", 1}, {tNumber, "1", 1}},
 		},
 		{
 			name: "ml comment 2",
 			exp:  "a/*****/+1",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "+", 1}, {tNumber, "1", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "+", 1}, {tNumber, "1", 1}},
 		},
 		{
 			name: "ml comment 3",
 			exp:  "a\n/*****/\n+1",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "+", 3}, {tNumber, "1", 3}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "+", 3}, {tNumber, "1", 3}},
 		},
 		{
 			name: "ml comment 4",
 			exp:  "a\n/*\n***\n*/\n+1",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "+", 5}, {tNumber, "1", 5}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "+", 5}, {tNumber, "1", 5}},
 		},
 		{
 			name: "ml comment 5",
 			exp:  "a\n/* test",
-			want: []Token{{tIdent, "a", 1}},
+			want: []expToken{{tIdent, "a", 1}},
 		},
 		{
 			name: "ml comment 6",
 			exp:  "a\n/*",
-			want: []Token{{tIdent, "a", 1}},
+			want: []expToken{{tIdent, "a", 1}},
 		},
 		{
 			name: "ml comment 7",
 			exp:  "a\n/* *",
-			want: []Token{{tIdent, "a", 1}},
+			want: []expToken{{tIdent, "a", 1}},
+		},
+		{
+			name: "range",
+			exp:  "1..10",
+			want: []expToken{{tNumber, "1", 1}, {tDot, ".", 1}, {tDot, ".", 1}, {tNumber, "10", 1}},
+		},
+		{
+			name: "float range",
+			exp:  "1.5..2.5",
+			want: []expToken{{tNumber, "1.5", 1}, {tDot, ".", 1}, {tDot, ".", 1}, {tNumber, "2.5", 1}},
 		},
 		{
 			name: "ml comment 8",
 			exp:  "a\n/* */",
-			want: []Token{{tIdent, "a", 1}},
+			want: []expToken{{tIdent, "a", 1}},
 		},
 	}
 
@@ -206,7 +272,7 @@ func TestNewTokenizer(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			tok := NewTokenizer(test.exp, simpleNumber, simpleIdentifier, detect).SetComments(true).Start()
 			for _, to := range test.want {
-				assert.EqualValues(t, to, tok.Next())
+				to.assert(t, tok.Next())
 			}
 			assert.EqualValues(t, TokenEof, tok.Next())
 			assert.EqualValues(t, TokenEof, tok.Next())
@@ -219,37 +285,37 @@ func TestOperatorDetect(t *testing.T) {
 		name string
 		exp  string
 		op   []string
-		want []Token
+		want []expToken
 	}{
 		{
 			name: "op1",
 			exp:  "+--->",
 			op:   []string{"+", "--", "->"},
-			want: []Token{{tOperate, "+", 1}, {tOperate, "--", 1}, {tOperate, "->", 1}},
+			want: []expToken{{tOperate, "+", 1}, {tOperate, "--", 1}, {tOperate, "->", 1}},
 		},
 		{
 			name: "op2",
 			exp:  "+-+",
 			op:   []string{"+", "--", "->"},
-			want: []Token{{tOperate, "+", 1}, {tInvalid, "-", 1}, {tOperate, "+", 1}},
+			want: []expToken{{tOperate, "+", 1}, {tInvalid, "-", 1}, {tOperate, "+", 1}},
 		},
 		{
 			name: "op3",
 			exp:  "+-->",
 			op:   []string{"+", "-", "->"},
-			want: []Token{{tOperate, "+", 1}, {tOperate, "-", 1}, {tOperate, "->", 1}},
+			want: []expToken{{tOperate, "+", 1}, {tOperate, "-", 1}, {tOperate, "->", 1}},
 		},
 		{
 			name: "op3",
 			exp:  "+-+---+",
 			op:   []string{"+", "-", "---"},
-			want: []Token{{tOperate, "+", 1}, {tOperate, "-", 1}, {tOperate, "+", 1}, {tOperate, "---", 1}, {tOperate, "+", 1}},
+			want: []expToken{{tOperate, "+", 1}, {tOperate, "-", 1}, {tOperate, "+", 1}, {tOperate, "---", 1}, {tOperate, "+", 1}},
 		},
 		{
 			name: "op3",
 			exp:  "+-+--+",
 			op:   []string{"+", "-", "---"},
-			want: []Token{{tOperate, "+", 1}, {tOperate, "-", 1}, {tOperate, "+", 1}, {tInvalid, "--", 1}, {tOperate, "+", 1}},
+			want: []expToken{{tOperate, "+", 1}, {tOperate, "-", 1}, {tOperate, "+", 1}, {tInvalid, "--", 1}, {tOperate, "+", 1}},
 		},
 	}
 
@@ -258,7 +324,7 @@ func TestOperatorDetect(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			tok := NewTokenizer(test.exp, simpleNumber, simpleIdentifier, NewOperatorDetector(test.op)).Start()
 			for _, to := range test.want {
-				assert.EqualValues(t, to, tok.Next())
+				to.assert(t, tok.Next())
 			}
 			assert.EqualValues(t, TokenEof, tok.Next())
 			assert.EqualValues(t, TokenEof, tok.Next())
@@ -270,47 +336,47 @@ func TestNewTokenizerNoComment(t *testing.T) {
 	tests := []struct {
 		name string
 		exp  string
-		want []Token
+		want []expToken
 	}{
 		{
 			name: "comment 1",
 			exp:  "a //test\n b",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "//", 1}, {tIdent, "test", 1}, {tIdent, "b", 2}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "//", 1}, {tIdent, "test", 1}, {tIdent, "b", 2}},
 		},
 		{
 			name: "comment 2",
 			exp:  "a//->test",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "//", 1}, {tOperate, "->", 1}, {tIdent, "test", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "//", 1}, {tOperate, "->", 1}, {tIdent, "test", 1}},
 		},
 		{
 			name: "comment 4",
 			exp:  "a-//->test",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "-", 1}, {tOperate, "//", 1}, {tOperate, "->", 1}, {tIdent, "test", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "-", 1}, {tOperate, "//", 1}, {tOperate, "->", 1}, {tIdent, "test", 1}},
 		},
 		{
 			name: "comment 5",
 			exp:  "a/b",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "/", 1}, {tIdent, "b", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "/", 1}, {tIdent, "b", 1}},
 		},
 		{
 			name: "comment 6",
 			exp:  "a/=b",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "/=", 1}, {tIdent, "b", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "/=", 1}, {tIdent, "b", 1}},
 		},
 		{
 			name: "comment 7",
 			exp:  "a/",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "/", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "/", 1}},
 		},
 		{
 			name: "comment 8",
 			exp:  "a//",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "//", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "//", 1}},
 		},
 		{
 			name: "comment 9",
 			exp:  "a//\n",
-			want: []Token{{tIdent, "a", 1}, {tOperate, "//", 1}},
+			want: []expToken{{tIdent, "a", 1}, {tOperate, "//", 1}},
 		},
 	}
 
@@ -320,7 +386,7 @@ func TestNewTokenizerNoComment(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			tok := NewTokenizer(test.exp, simpleNumber, simpleIdentifier, detect).Start()
 			for _, to := range test.want {
-				assert.EqualValues(t, to, tok.Next())
+				to.assert(t, tok.Next())
 			}
 			assert.EqualValues(t, TokenEof, tok.Next())
 			assert.EqualValues(t, TokenEof, tok.Next())
@@ -355,17 +421,17 @@ func TestUnicodeExp(t *testing.T) {
 
 	tests := []struct {
 		in   string
-		want []Token
+		want []expToken
 	}{
-		{in: "a¹", want: []Token{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "1", 1}}},
-		{in: "a²", want: []Token{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "2", 1}}},
-		{in: "a³", want: []Token{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "3", 1}}},
-		{in: "a⁴", want: []Token{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "4", 1}}},
-		{in: "a⁵", want: []Token{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "5", 1}}},
-		{in: "a⁶", want: []Token{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "6", 1}}},
-		{in: "a⁷", want: []Token{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "7", 1}}},
-		{in: "a⁸", want: []Token{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "8", 1}}},
-		{in: "a⁹", want: []Token{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "9", 1}}},
+		{in: "a¹", want: []expToken{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "1", 1}}},
+		{in: "a²", want: []expToken{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "2", 1}}},
+		{in: "a³", want: []expToken{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "3", 1}}},
+		{in: "a⁴", want: []expToken{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "4", 1}}},
+		{in: "a⁵", want: []expToken{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "5", 1}}},
+		{in: "a⁶", want: []expToken{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "6", 1}}},
+		{in: "a⁷", want: []expToken{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "7", 1}}},
+		{in: "a⁸", want: []expToken{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "8", 1}}},
+		{in: "a⁹", want: []expToken{{tIdent, "a", 1}, {tOperate, "^", 1}, {tNumber, "9", 1}}},
 	}
 
 	myIdent := func(r rune) (func(r rune) bool, bool) {
@@ -383,7 +449,7 @@ func TestUnicodeExp(t *testing.T) {
 		t.Run(test.in, func(t *testing.T) {
 			tok := NewTokenizer(test.in, simpleNumber, myIdent, NewOperatorDetector([]string{"^"})).Start()
 			for _, to := range test.want {
-				assert.EqualValues(t, to, tok.Next())
+				to.assert(t, tok.Next())
 			}
 			assert.EqualValues(t, TokenEof, tok.Next())
 			assert.EqualValues(t, TokenEof, tok.Next())
@@ -408,3 +474,124 @@ func TestNewTokenToString(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenPosition(t *testing.T) {
+	type pos struct {
+		image      string
+		line, col  int
+		start, end int
+	}
+	tests := []struct {
+		name string
+		exp  string
+		want []pos
+	}{
+		{name: "simple", exp: "a+b", want: []pos{{"a", 1, 1, 0, 1}, {"+", 1, 2, 1, 2}, {"b", 1, 3, 2, 3}}},
+		{name: "blanks", exp: " ab  ->  12 ", want: []pos{{"ab", 1, 2, 1, 3}, {"->", 1, 6, 5, 7}, {"12", 1, 10, 9, 11}}},
+		{name: "lines", exp: "a\n  bb\n c", want: []pos{{"a", 1, 1, 0, 1}, {"bb", 2, 3, 4, 6}, {"c", 3, 2, 8, 9}}},
+		{name: "string", exp: "x=\"tü\"", want: []pos{{"x", 1, 1, 0, 1}, {"=", 1, 2, 1, 2}, {"tü", 1, 3, 2, 7}}},
+		{name: "unicode", exp: "äö+ü", want: []pos{{"äö", 1, 1, 0, 4}, {"+", 1, 3, 4, 5}, {"ü", 1, 4, 5, 7}}},
+		{name: "comment", exp: "a/* x */+b", want: []pos{{"a", 1, 1, 0, 1}, {"+", 1, 9, 8, 9}, {"b", 1, 10, 9, 10}}},
+	}
+
+	detect := NewOperatorDetector([]string{"+", "->", "="})
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			tok := NewTokenizer(test.exp, simpleNumber, simpleIdentifier, detect).SetComments(true).Start()
+			for _, p := range test.want {
+				n := tok.Next()
+				assert.Equal(t, p.image, n.image)
+				assert.Equal(t, p.line, n.Num, "line")
+				assert.Equal(t, p.col, n.Column(), "column")
+				assert.Equal(t, p.start, n.Start, "start")
+				assert.Equal(t, p.end, n.End, "end")
+			}
+			assert.EqualValues(t, tEof, tok.Next().typ)
+		})
+	}
+}
+
+func TestTokenizerAbort(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		_, err := parser.Parse("1+(2*", nil)
+		assert.Error(t, err)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestTokenImage(t *testing.T) {
+	tests := []struct {
+		exp  string
+		want []string
+	}{
+		{exp: "ab/*x*/cd", want: []string{"abcd"}},
+		{exp: "ab//x\ncd", want: []string{"ab", "cd"}},
+		{exp: "a••b", want: []string{"a", "**", "b"}},
+		{exp: "a*•b", want: []string{"a", "**", "b"}},
+		{exp: "a->b", want: []string{"a", "->", "b"}},
+		{exp: "\"a\\tb\"", want: []string{"a\tb"}},
+		{exp: "\"ab\\\"\"", want: []string{"ab\""}},
+		{exp: "\"\"", want: []string{""}},
+	}
+
+	detect := NewOperatorDetector([]string{"*", "**", "->"})
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.exp, func(t *testing.T) {
+			tok := NewTokenizer(test.exp, simpleNumber, simpleIdentifier, detect).SetComments(true).Start()
+			for _, w := range test.want {
+				assert.Equal(t, w, tok.Next().image)
+			}
+			assert.EqualValues(t, tEof, tok.Next().typ)
+		})
+	}
+}
+
+func TestEngineeringNumber(t *testing.T) {
+	tests := []struct {
+		exp  string
+		want []string
+	}{
+		{exp: "0xFF+1", want: []string{"0xFF", "+", "1"}},
+		{exp: "0b1010", want: []string{"0b1010"}},
+		{exp: "1_000_000", want: []string{"1_000_000"}},
+		{exp: "4.7k*x", want: []string{"4.7k", "*", "x"}},
+		{exp: "3µs", want: []string{"3µ", "s"}},
+		{exp: "1e-3m", want: []string{"1e-3m"}},
+		{exp: "10x", want: []string{"10", "x"}},
+	}
+
+	detect := NewOperatorDetector([]string{"*", "+"})
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.exp, func(t *testing.T) {
+			tok := NewTokenizer(test.exp, EngineeringNumber, simpleIdentifier, detect).Start()
+			for _, w := range test.want {
+				assert.Equal(t, w, tok.Next().image)
+			}
+			assert.EqualValues(t, tEof, tok.Next().typ)
+		})
+	}
+}
+
+const benchmarkSource = `
+let a = [1, 2, 3, 4, 5];
+func f(x) x*x + 2*x - "text".size();
+// a comment
+let m = {a: 1, b: 2, c: (x, y) -> x+y};
+a.map(x -> f(x)).reduce((a, b) -> a+b) + m.c(m.a, m.b)
+`
+
+func BenchmarkTokenizer(b *testing.B) {
+	detect := NewOperatorDetector([]string{"+", "-", "*", "->"})
+	for i := 0; i < b.N; i++ {
+		tok := NewTokenizer(benchmarkSource, simpleNumber, simpleIdentifier, detect).
+			SetKeyWords([]string{"let", "func"}).
+			SetComments(true).
+			Start()
+		for tok.Next().typ != tEof {
+		}
+	}
+}
@@ -66,3 +66,39 @@ func Benchmark_filter2(b *testing.B) {
 		})
 	}
 }
+
+const bench3 = `
+data.map(p->let s=p.a*2+p.b; if s>1000 then s-p.b*3 else -s).sum()
+`
+
+func benchmarkBackend(b *testing.B, backend funcGen.Backend) {
+	valueParser := New()
+	valueParser.SetBackend(backend)
+	f, _, err := valueParser.Generate(bench3, "data")
+	if err != nil {
+		b.Fatal(err)
+	}
+	data, _, err := valueParser.Generate("numbers(10000).map(i->{a:i, b:i%7})")
+	if err != nil {
+		b.Fatal(err)
+	}
+	list, err := data.Eval()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := f.Eval(list)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_tree(b *testing.B) {
+	benchmarkBackend(b, funcGen.TreeBackend)
+}
+
+func Benchmark_bytecode(b *testing.B) {
+	benchmarkBackend(b, funcGen.BytecodeBackend)
+}
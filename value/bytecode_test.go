@@ -0,0 +1,29 @@
+package value
+
+import (
+	"github.com/hneemann/parser2/funcGen"
+	"testing"
+)
+
+func TestBytecodeBackend(t *testing.T) {
+	fg := New()
+	fg.SetBackend(funcGen.BytecodeBackend)
+	runTestWith(t, fg, []testType{
+		{exp: "1+2*3", res: Int(7)},
+		{exp: "let a=2; let b=a*3; b-a", res: Int(4)},
+		{exp: "if 1<2 then \"a\" else \"b\"", res: String("a")},
+		{exp: "-(2.5)", res: Float(-2.5)},
+		{exp: "let m={a:1, b:{c:3}}; m.b.c+m.a", res: Int(4)},
+		{exp: "let m={a:1}; m?.b ?? 5", res: Int(5)},
+		{exp: "let l=[1,2,3]; l[1]", res: Int(2)},
+		{exp: "[1,2,3].map(x->x*x).sum()", res: Int(14)},
+		{exp: "let f=x->x+1; f(f(1))", res: Int(3)},
+		{exp: "sqrt(16)", res: Float(4)},
+		{exp: "func fak(n) if n<2 then 1 else n*fak(n-1); fak(10)", res: Int(3628800)},
+		{exp: "func sum(n,acc) if n=0 then acc else sum(n-1,acc+n); sum(100000,0)", res: Int(5000050000)},
+		{exp: "let m={f:x->x*2}; m.f(21)", res: Int(42)},
+		{exp: "try 1/\"a\" catch 7", res: Int(7)},
+		{exp: "let [a,b]=[1,2]; a+b", res: Int(3)},
+		{exp: "[x*2 for x in [1,2,3] if x>1]", res: NewList(Int(4), Int(6))},
+	})
+}
@@ -0,0 +1,83 @@
+package value
+
+import (
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	runTest(t, []testType{
+		{exp: "1..5", res: NewList(Int(1), Int(2), Int(3), Int(4), Int(5))},
+		{exp: "0..10 step 5", res: NewList(Int(0), Int(5), Int(10))},
+		{exp: "0..11 step 5", res: NewList(Int(0), Int(5), Int(10))},
+		{exp: "5..1 step -2", res: NewList(Int(5), Int(3), Int(1))},
+		{exp: "5..1", res: NewList()},
+		{exp: "5..4 step 2", res: NewList()},
+		{exp: "let n=3; 1..n+1", res: NewList(Int(1), Int(2), Int(3), Int(4))},
+		{exp: "0..1 step 0.25", res: NewList(Float(0), Float(0.25), Float(0.5), Float(0.75), Float(1))},
+		{exp: "0..0.3 step 0.1", res: NewList(Float(0), Float(0.1), Float(0.2), Float(0.30000000000000004))},
+		{exp: "(1..100).size()", res: Int(100)},
+		{exp: "(0..100 step 5).size()", res: Int(21)},
+		{exp: "(1..10).map(x->x*x).accept(x->x>50)", res: NewList(Int(64), Int(81), Int(100))},
+		{exp: "1..3 |> (l->l.sum())", res: Int(6)},
+		{exp: "let a=[1,2,3]; a[0]..a[2]", res: NewList(Int(1), Int(2), Int(3))},
+	})
+}
+
+func TestComprehension(t *testing.T) {
+	runTest(t, []testType{
+		{exp: "[x*x for x in [1,2,3,4,5] if x>3]", res: NewList(Int(16), Int(25))},
+		{exp: "[x*x for x in 1..5]", res: NewList(Int(1), Int(4), Int(9), Int(16), Int(25))},
+		{exp: "[x+y for x in [10,20] for y in [1,2]]", res: NewList(Int(11), Int(12), Int(21), Int(22))},
+		{exp: "[[x,y] for x in 1..3 for y in x..3 if x+y=4].string()", res: String("[[1, 3], [2, 2]]")},
+		{exp: "[[x,y] for x in 1..3 if x>1 for y in 1..x].string()", res: String("[[2, 1], [2, 2], [3, 1], [3, 2], [3, 3]]")},
+		{exp: "let o=10; [x+o for x in 1..3]", res: NewList(Int(11), Int(12), Int(13))},
+		{exp: "let o=10; let f=n->[x*o for x in 1..n]; f(2)", res: NewList(Int(10), Int(20))},
+		{exp: "let l=[1,2]; [x*y for x in l for y in l]", res: NewList(Int(1), Int(2), Int(2), Int(4))},
+		{exp: "[[y*x for y in 1..x] for x in 1..3].string()", res: String("[[1], [2, 4], [3, 6, 9]]")},
+		{exp: "[f(2) for f in [x->x+1, x->x*3]]", res: NewList(Int(3), Int(6))},
+		{exp: "[x->x*y for y in 1..3].map(f->f(2))", res: NewList(Int(2), Int(4), Int(6))},
+		{exp: "[if x>1 then x else 0 for x in 1..3]", res: NewList(Int(0), Int(2), Int(3))},
+		{exp: "[x for x in []]", res: NewList()},
+		{exp: "[x.a for x in [{a:1},{a:2}]]", res: NewList(Int(1), Int(2))},
+		{exp: "[x for x in 1..1000000].first()", res: Int(1)},
+		{exp: "[x for x in 1..10].size()", res: Int(10)},
+		{exp: "[x for x in 1..10 if x>5].size()", res: Int(5)},
+		{exp: "[[1, 2], [3]].string()", res: String("[[1, 2], [3]]")},
+		{exp: "[(x, y)->x+y, 2].size()", res: Int(2)},
+	})
+}
+
+func TestRangeSize(t *testing.T) {
+	tests := []struct {
+		exp   string
+		size  int
+		known bool
+	}{
+		{exp: "1..100", size: 100, known: true},
+		{exp: "0..100 step 5", size: 21, known: true},
+		{exp: "100..0 step -3", size: 34, known: true},
+		{exp: "1..0", size: 0, known: true},
+		{exp: "0..1 step 0.1", size: 11, known: true},
+		{exp: "[x*x for x in 1..10]", size: 10, known: true},
+		{exp: "[x for x in 1..10 if x>3]", known: false},
+		{exp: "[x+y for x in 1..3 for y in 1..3]", known: false},
+	}
+	fg := New()
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.exp, func(t *testing.T) {
+			f, _, err := fg.Generate(test.exp)
+			assert.NoError(t, err)
+			v, err := f(funcGen.NewEmptyStack[Value]())
+			assert.NoError(t, err)
+			l, ok := v.(*List)
+			assert.True(t, ok)
+			size, known := l.SizeIfKnown()
+			assert.Equal(t, test.known, known)
+			assert.Equal(t, test.size, size)
+			assert.False(t, l.itemsPresent, "list is materialized")
+		})
+	}
+}
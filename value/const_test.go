@@ -1,6 +1,9 @@
 package value
 
 import (
+	"github.com/hneemann/parser2"
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
@@ -13,3 +16,62 @@ func TestConst(t *testing.T) {
 		{exp: "func f(pi) pi*2; f(2)", res: Int(4)},
 	})
 }
+
+func TestConstDef(t *testing.T) {
+	runTest(t, []testType{
+		{exp: "const a=3; a", res: Int(3)},
+		{exp: "const a=3; const b=a*2; a+b", res: Int(9)},
+		{exp: "const s=\"a\"+\"b\"; s", res: String("ab")},
+		{exp: "const l=[1, 2, 3]; l.size()", res: Int(3)},
+		{exp: "const r=sqrt(16); r", res: Float(4)},
+		{exp: "const f=x->x*x; f(3)", res: Int(9)},
+		{exp: "const w=10; func f(x) x*w; f(2)", res: Int(20)},
+		{exp: "const w=10; let g=x->x+w; g(1)", res: Int(11)},
+	})
+}
+
+func TestGetConstants(t *testing.T) {
+	fg := New()
+	ast, err := fg.CreateAst("const width=800; const title=\"Plot\"; let a=width/2; const half=a; half", fg.Identifier())
+	assert.NoError(t, err)
+	c, err := parser2.GetConstants[Value](ast)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, c.Size())
+	width, ok := c.Get("width")
+	assert.True(t, ok)
+	assert.Equal(t, Int(800), width)
+	title, ok := c.Get("title")
+	assert.True(t, ok)
+	assert.Equal(t, String("Plot"), title)
+	half, ok := c.Get("half")
+	assert.True(t, ok)
+	assert.Equal(t, Float(400), half)
+
+	f, _, err := fg.GenerateFromAST(ast)
+	assert.NoError(t, err)
+	res, err := f(funcGen.NewEmptyStack[Value]())
+	assert.NoError(t, err)
+	assert.Equal(t, Float(400), res)
+
+	ast, err = fg.CreateAst("let a=1; func f(x) const c=2; x*c; f(a)", fg.Identifier())
+	assert.NoError(t, err)
+	c, err = parser2.GetConstants[Value](ast)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, c.Size())
+
+	notFolded := &parser2.ConstDef{Name: "c", Value: &parser2.Ident{Name: "a"}, Inner: &parser2.Ident{Name: "c"}}
+	_, err = parser2.GetConstants[Value](notFolded)
+	assert.ErrorContains(t, err, "value of const 'c' is not a constant")
+}
+
+func TestConstFormat(t *testing.T) {
+	fg := New()
+	formatted, err := fg.Format("const  c=2*3; c", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "const c = 2*3;\nc\n", formatted)
+
+	_, err = fg.Format("const c=a*3; c", "a")
+	assert.ErrorContains(t, err, "value of const 'c' is not a constant")
+	_, err = fg.Lint("const c=a*3; c", []string{"a"})
+	assert.ErrorContains(t, err, "value of const 'c' is not a constant")
+}
@@ -0,0 +1,132 @@
+package value
+
+import (
+	"fmt"
+	"github.com/hneemann/parser2"
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// recordingDebugger records the events and returns the given actions
+type recordingDebugger struct {
+	actions     []funcGen.DebugAction
+	breakpoints map[int]bool
+	events      []string
+}
+
+func (r *recordingDebugger) Break(line parser2.Line) bool {
+	return r.breakpoints[line.Num]
+}
+
+func (r *recordingDebugger) Stopped(ev funcGen.DebugEvent[Value]) funcGen.DebugAction {
+	var vars []string
+	for i, n := range ev.Names {
+		v := fmt.Sprint(ev.Values[i])
+		if _, ok := ev.Values[i].(Closure); ok {
+			v = "<closure>"
+		}
+		vars = append(vars, n+"="+v)
+	}
+	r.events = append(r.events, fmt.Sprintf("%d:%s:%d[%s]", ev.Num, ev.Kind, ev.Depth, strings.Join(vars, ",")))
+	if len(r.actions) == 0 {
+		return funcGen.DebugStepInto
+	}
+	a := r.actions[0]
+	r.actions = r.actions[1:]
+	return a
+}
+
+func TestDebugger(t *testing.T) {
+	const src = "let f=x->x*n;\nlet a=f(3);\nlet b=[1,2].map(f);\na+b.size()"
+	tests := []struct {
+		name        string
+		actions     []funcGen.DebugAction
+		breakpoints map[int]bool
+		events      []string
+		res         Value
+		err         string
+	}{
+		{name: "stepInto",
+			events: []string{
+				"1:let:0[n=2]",
+				"2:let:0[n=2,f=<closure>]",
+				"2:function call:0[n=2,f=<closure>]",
+				"1:closure:1[x=3,n=2]",
+				"3:let:0[n=2,f=<closure>,a=6]",
+				"3:method call:0[n=2,f=<closure>,a=6]",
+				"4:method call:0[n=2,f=<closure>,a=6,b=[2, 4]]",
+				"1:closure:1[x=1,n=2]",
+				"1:closure:1[x=2,n=2]",
+			},
+			res: Int(8)},
+		{name: "stepOver",
+			actions: []funcGen.DebugAction{funcGen.DebugStepOver, funcGen.DebugStepOver, funcGen.DebugStepOver, funcGen.DebugStepOver, funcGen.DebugStepOver, funcGen.DebugStepOver},
+			events: []string{
+				"1:let:0[n=2]",
+				"2:let:0[n=2,f=<closure>]",
+				"2:function call:0[n=2,f=<closure>]",
+				"3:let:0[n=2,f=<closure>,a=6]",
+				"3:method call:0[n=2,f=<closure>,a=6]",
+				"4:method call:0[n=2,f=<closure>,a=6,b=[2, 4]]",
+			},
+			res: Int(8)},
+		{name: "continue",
+			actions: []funcGen.DebugAction{funcGen.DebugContinue},
+			events:  []string{"1:let:0[n=2]"},
+			res:     Int(8)},
+		{name: "breakpoint",
+			actions:     []funcGen.DebugAction{funcGen.DebugContinue, funcGen.DebugContinue, funcGen.DebugContinue},
+			breakpoints: map[int]bool{3: true},
+			events: []string{
+				"1:let:0[n=2]",
+				"3:let:0[n=2,f=<closure>,a=6]",
+				"3:method call:0[n=2,f=<closure>,a=6]",
+			},
+			res: Int(8)},
+		{name: "abort",
+			actions: []funcGen.DebugAction{funcGen.DebugStepInto, funcGen.DebugAbort},
+			events: []string{
+				"1:let:0[n=2]",
+				"2:let:0[n=2,f=<closure>]",
+			},
+			err: "evaluation aborted by debugger"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &recordingDebugger{actions: test.actions, breakpoints: test.breakpoints}
+			fg := New()
+			fg.SetDebugger(d)
+			f, _, err := fg.Generate(src, "n")
+			assert.NoError(t, err)
+			res, err := f.Eval(Int(2))
+			if test.err == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, test.res, res)
+			} else {
+				assert.ErrorContains(t, err, test.err)
+			}
+			assert.Equal(t, test.events, d.events)
+		})
+	}
+}
+
+func TestDebuggerAbortNotCaught(t *testing.T) {
+	d := &recordingDebugger{actions: []funcGen.DebugAction{funcGen.DebugAbort}}
+	fg := New()
+	fg.SetDebugger(d)
+	f, _, err := fg.Generate("try n.map(x->x*2).size() catch 0", "n")
+	assert.NoError(t, err)
+	_, err = f.Eval(NewList(Int(1), Int(2)))
+	assert.ErrorIs(t, err, funcGen.ErrDebugAbort)
+}
+
+func TestDebuggerRequiresTreeBackend(t *testing.T) {
+	fg := New()
+	fg.SetDebugger(&recordingDebugger{})
+	fg.SetBackend(funcGen.BytecodeBackend)
+	_, _, err := fg.Generate("n*2", "n")
+	assert.ErrorContains(t, err, "the debugger requires the tree backend")
+}
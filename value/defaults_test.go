@@ -0,0 +1,65 @@
+package value
+
+import (
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDefaultArgs(t *testing.T) {
+	runTest(t, []testType{
+		{exp: "func f(x, scale=2) x*scale; f(3)", res: Int(6)},
+		{exp: "func f(x, scale=2) x*scale; f(3, 3)", res: Int(9)},
+		{exp: "func f(x, scale=2) x*scale; f(3, scale: 4)", res: Int(12)},
+		{exp: "func f(x, scale=2) x*scale; f(scale: 4, x: 1)", res: Int(4)},
+		{exp: "func f(x, y=1, z=2) x+y*z; f(1, z: 5)", res: Int(6)},
+		{exp: "func f(x, y=\"a\") y+x; f(\"b\")", res: String("ab")},
+		{exp: "func f(x, y=[1,2]) x+y.size(); f(1)", res: Int(3)},
+		{exp: "func f(n, acc=1) if n<2 then acc else f(n-1, acc: acc*n); f(5)", res: Int(120)},
+		{exp: "let f=(x, y=1)->x+y; [f(1), f(1,2), f(y: 3, x: 1)]", res: NewList(Int(2), Int(3), Int(4))},
+		{exp: "let o=numbers(3).size(); let f=(x, y=10)->x+y+o; [f(1), f(1, y: 1)]", res: NewList(Int(14), Int(5))},
+		{exp: "let f=(x=1)->x*2; f()", res: Int(2)},
+		{exp: "let f=(x=1)->x*2; f(x: 3)", res: Int(6)},
+		{exp: "let f=({a}, b=1)->a+b; f({a: 1})", res: Int(2)},
+		{exp: "let f=(x, y=1)->x+y; f.invoke([1])", res: Int(2)},
+		{exp: "let m={f:(x, y=2)->x*y}; m.f(3)", res: Int(6)},
+		{exp: "floor(x: 2.5)", res: Float(2)},
+	})
+}
+
+func TestDefaultArgsStatic(t *testing.T) {
+	fg := New().AddStaticFunction("scale", funcGen.Function[Value]{
+		Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
+			return Int(st.Get(0).(Int) * st.Get(1).(Int)), nil
+		},
+		Args:   2,
+		IsPure: true,
+	}.SetDescription("x", "factor", "Scales x.").SetDefaults(Int(3)))
+
+	tests := []struct {
+		exp string
+		res Value
+	}{
+		{exp: "scale(2)", res: Int(6)},
+		{exp: "scale(2, 4)", res: Int(8)},
+		{exp: "scale(2, factor: 5)", res: Int(10)},
+		{exp: "scale(factor: 5, x: 3)", res: Int(15)},
+		{exp: "let a=numbers(3).size(); scale(factor: a, x: a)", res: Int(9)},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.exp, func(t *testing.T) {
+			f, _, err := fg.Generate(test.exp)
+			assert.NoError(t, err)
+			res, err := f(funcGen.NewEmptyStack[Value]())
+			assert.NoError(t, err)
+			assert.Equal(t, test.res, res)
+		})
+	}
+
+	for _, f := range fg.GetStaticDocumentation().Functions {
+		if f.Name == "scale" {
+			assert.Equal(t, "scale(x, factor=3)\n\tScales x.", f.Description.String("scale"))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package value
+
+import (
+	"github.com/hneemann/parser2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDestructuring(t *testing.T) {
+	runTest(t, []testType{
+		{exp: "let [a,b,c]=[1,2,3]; a+b*c", res: Int(7)},
+		{exp: "let [a]=[5]; a", res: Int(5)},
+		{exp: "let [a,b]=numbers(2).map(i->i+3); a*b", res: Int(12)},
+		{exp: "let p={Name:\"Bob\",Age:42}; let {Name, Age}=p; Name+\" \"+Age", res: String("Bob 42")},
+		{exp: "let {b}={a:1,b:2}; b", res: Int(2)},
+		{exp: "let f=({x,y})->x+y; f({x:1,y:2})", res: Int(3)},
+		{exp: "let f=([x,y],z)->(x+y)*z; f([1,2],3)", res: Int(9)},
+		{exp: "let f=(z,{x})->x*z; f(2,{x:4})", res: Int(8)},
+		{exp: "func f([a,b]) a-b; f([5,2])", res: Int(3)},
+		{exp: "func f({a},[b]) a-b; f({a:5},[1])", res: Int(4)},
+		{exp: "let o=2; [[1,2],[3,4]].map(([a,b])->a+b+o)", res: NewList(Int(5), Int(9))},
+		{exp: "[{a:1},{a:2}].map(({a})->a)", res: NewList(Int(1), Int(2))},
+		{exp: "[[1,2],[3,4]].map(([a,b])->a*b)", res: NewList(Int(2), Int(12))},
+		{exp: "({a:1}).a", res: Int(1)},
+		{exp: "([1,2])[1]", res: Int(2)},
+		{exp: "numbers(12).groupByString(i->\"n\"+round(i/4)).order(a->a.key).map(({key,values})->key+\":\"+values.size()).string()",
+			res: String("[n0:2, n1:4, n2:4, n3:2]")},
+		{exp: "let [k,v]=[\"a\",1]; {k:k,v:v}.string()", res: String("{k:a, v:1}")},
+	})
+}
+
+func TestDestructuringString(t *testing.T) {
+	tests := []struct {
+		exp  string
+		want string
+	}{
+		{exp: "let [a,b]=x; a", want: "let [a, b]=x; a"},
+		{exp: "let {a,b}=x; a", want: "let {a, b}=x; a"},
+		{exp: "({a,b})->a+x", want: "({a, b})->a+x"},
+		{exp: "([a],b)->a+b+x", want: "([a], b)->(a+b)+x"},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.exp, func(t *testing.T) {
+			var idents parser2.Identifiers[Value]
+			ast, err := New().CreateAst(test.exp, idents.Add("x"))
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, ast.String())
+		})
+	}
+}
@@ -48,6 +48,7 @@ func TestErrors(t *testing.T) {
 		{"numbers(10).multiUse({a:a->a.map(a->a.a)})", "not a map"},
 		{"numbers(10).multiUse({a:l->l.reduce((a,b)->a.e+b), b:l->l.reduce((a,b)->a+b)})", "not a map"},
 		{"numbers(10).multiUse({a:l->1, b:l->l->2})", "timed out"},
+		{"let f=(a: number)->a; f(1)", "unknown type 'number'"},
 		{"numbers(10).multiUse({a:l->l.reduce((a,b)->a+b)+l.reduce((a,b)->a*b)})", "copied iterator a can only be used once"},
 		{"numbers(10).map(e->e.e).multiUse({a:l->l.reduce((a,b)->a+b)})", "not a map"},
 		{"numbers(10).multiUse({a:l->l.mapReduce(0,(s,i)->s+i), b:l->l.notFound(i->i+1)})", "notFound"},
@@ -64,9 +65,64 @@ func TestErrors(t *testing.T) {
 		{"[1,2,3,4].set(-1,0)", "index -1 out of range"},
 		{"[1,2,3,4].set(4,0)", "index 4 out of range"},
 		{"true-2", "operation '-' not defined on bool, int"},
+		{"1<2<3", "operator '<' is not associative, use parentheses"},
+		{"\"a${1+}\"", "unexpected token type"},
+		{"\"a${1\"", "expected '}', found 'EOL'"},
+		{"\"a${[1][2]}\"", "error in string interpolation"},
+		{"\"\\u12\"", "invalid unicode escape"},
+		{"1>=2>=3", "operator '>=' is not associative, use parentheses"},
 		{"func f(x) x+b; f(2)", "identifier 'b' not found"},
 		{"func f(x,x) x+x; f(2,2)", "'x' used twice"},
+		{"1..\"a\"", "range requires numbers"},
+		{"0..5 step 0", "must not be zero"},
+		{"[x for x in 5]", "for requires a list"},
+		{"[x for x in [1] for y in x].size()", "for requires a list"},
+		{"[x for x in [1] for x in [2]]", "'x' used twice"},
+		{"[x for x in [1] if 1].size()", "condition is not a bool"},
+		{"[x for x in [1] if x.a].size()", "error in list comprehension"},
+		{"[x for y in [1]]", "identifier 'x' not found"},
+		{"func f(x) let g=y->h(y); func h(y) y*x; g(x); f(1)", "identifier 'h' not found"},
+		{"func f(x) g(x); let a=1; func g(x) x; f(1)", "identifier 'g' not found"},
+		{"func f(x) g(x); func g(x) y; f(1)", "identifier 'y' not found"},
 		{"let f=(x,x)-> x+x; f(2,2)", "'x' used twice"},
+		{"let [a,b]=[1,2,3]; a", "list destructuring requires 2 items, found 3"},
+		{"let [a,b]=[1]; a", "error in destructuring [a, b]"},
+		{"let [a,b]={a:1}; a", "destructuring requires a list"},
+		{"let {a,c}={a:1,b:2}; a", "key 'c' not found in map; available are: a, b"},
+		{"let {a}=[1]; a", "destructuring requires a map"},
+		{"let [a,a]=[1,2]; a", "'a' used twice in destructuring"},
+		{"let [a,b]=[1,2]; let [b]=[3]; a", "redeclaration of 'b'"},
+		{"let f=({x,y})->x+y; f({x:1})", "key 'y' not found in map"},
+		{"func f([a,b],a) a; f([1,2],3)", "'a' used twice"},
+		{"let [a,1]=[1,2]; a", "expected identifier in destructuring"},
+		{"switch 1 match [a, a]: a default 0", "'a' used twice in pattern"},
+		{"switch 1 match [a, ...r, b]: a default 0", "rest of a list pattern has to be the last item"},
+		{"switch 1 match {a: 1, a}: 1 default 0", "key 'a' used twice in map pattern"},
+		{"switch 1 match x if x: 1 default 0", "guard does not return a bool"},
+		{"switch 1 match x 1 default 0", "expected ':'"},
+		{"switch 1 match ,: 1 default 0", "expected pattern"},
+		{"let f=a->switch 1 match a: 1 default 0; f(1)", "redeclaration of 'a'"},
+		{"let f=(x, y=1)->x+y; f()", "required 1 to 2, found 0"},
+		{"let f=(x, y=1)->x+y; f(1,2,3)", "required 1 to 2, found 3"},
+		{"func f(x, y=1) x+y; f(1,2,3)", "required 1 to 2, found 3"},
+		{"func f(x, y=1) x+y; f(y: 2)", "argument 'x' is missing"},
+		{"func f(x, y=1) x+y; f(1, z: 2)", "function has no argument 'z'; available are: x, y"},
+		{"func f(x, y=1) x+y; f(1, x: 2)", "argument 'x' given twice"},
+		{"func f(x, y=1) x+y; f(x: 1, 2)", "positional argument follows named argument"},
+		{"1?.a", "'.a' not possible; Int is not a map"},
+		{"{a:1}.b", "key 'b' not found in map"},
+		{"const a=random(); a", "value of const 'a' is not a constant"},
+		{"func f(x) const a=x+1; a; f(1)", "value of const 'a' is not a constant"},
+		{"const a; a", "unexpected token, expected '='"},
+		{"const 1=2; 1", "no identifier followed by const"},
+		{"[1,2] |> size", "identifier 'size' not found"},
+		{"let l=[1]; 2 |> l.size()", "a method call can not be the target of a pipe"},
+		{"func f(x) x; 1 |> f(2)", "wrong number of arguments"},
+		{"func f(x=1, y) x+y; f(1, 2)", "argument 'y' requires a default value"},
+		{"let g=a->(x=a)->x; g(1)()", "default value is not a constant"},
+		{"let f=(x, y)->x+y; f(1, z: 2)", "function has no argument 'z'"},
+		{"sin(y: 1)", "function has no argument 'y'"},
+		{"numbers(3).map(x: 2)", "named arguments are not supported in method calls"},
 		{exp: "throw(\"error: zzzz\")", err: "error: zzzz"},
 		{exp: "func mul(a,b) a*b; mul.invoke([2,3,4])", err: "wrong number of arguments in invoke: 3 instead of 2"},
 		{exp: "func mul(a,b) a*b; mul(2)", err: "wrong number of arguments at call of function, required 2, found 1 in line 1"},
@@ -74,20 +130,23 @@ func TestErrors(t *testing.T) {
 		{exp: "[].size(1)", err: ", required 0, found 1"},
 	}
 
-	fg := New().AddStaticFunction("error", toLargeErrorFunc(100))
-	for _, tt := range tests {
-		test := tt
-		t.Run(test.exp, func(t *testing.T) {
-			f, _, err := fg.Generate(test.exp)
-			var r Value
-			if err == nil {
-				r, err = f(funcGen.NewEmptyStack[Value]())
-			}
-			if err == nil {
-				t.Errorf("expected an error containing '%v', result was: %v", test.err, r)
-			} else {
-				assert.True(t, strings.Contains(err.Error(), test.err), "expected error containing '%v', got: %v", test.err, err.Error())
-			}
-		})
+	for name, backend := range backends {
+		fg := New().AddStaticFunction("error", toLargeErrorFunc(100))
+		fg.SetBackend(backend)
+		for _, tt := range tests {
+			test := tt
+			t.Run(name+"/"+test.exp, func(t *testing.T) {
+				f, _, err := fg.Generate(test.exp)
+				var r Value
+				if err == nil {
+					r, err = f(funcGen.NewEmptyStack[Value]())
+				}
+				if err == nil {
+					t.Errorf("expected an error containing '%v', result was: %v", test.err, r)
+				} else {
+					assert.True(t, strings.Contains(err.Error(), test.err), "expected error containing '%v', got: %v", test.err, err.Error())
+				}
+			})
+		}
 	}
 }
@@ -0,0 +1,146 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEvalContext(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  string
+		ec   func() *funcGen.EvalContext
+		kind funcGen.LimitKind
+		res  Value
+	}{
+		{name: "ok", exp: "[1,2,3].map(x->x*2).sum()", res: Int(12),
+			ec: func() *funcGen.EvalContext { return funcGen.NewEvalContext(nil).SetMaxSteps(100).SetMaxListItems(100) }},
+		{name: "recursion", exp: "func f(n) if n<0 then 0 else f(n+1); f(0)", kind: funcGen.StepLimit,
+			ec: func() *funcGen.EvalContext { return funcGen.NewEvalContext(nil).SetMaxSteps(1000) }},
+		{name: "deepRecursion", exp: "func f(n) 1+f(n+1); f(0)", kind: funcGen.StepLimit,
+			ec: func() *funcGen.EvalContext { return funcGen.NewEvalContext(nil).SetMaxSteps(1000) }},
+		{name: "map", exp: "numbers(n).map(x->x*2).sum()", kind: funcGen.StepLimit,
+			ec: func() *funcGen.EvalContext { return funcGen.NewEvalContext(nil).SetMaxSteps(1000) }},
+		{name: "items", exp: "numbers(n).sum()", kind: funcGen.ListItemLimit,
+			ec: func() *funcGen.EvalContext { return funcGen.NewEvalContext(nil).SetMaxListItems(1000) }},
+		{name: "comprehension", exp: "[x*2 for x in 1..n].size()", kind: funcGen.ListItemLimit,
+			ec: func() *funcGen.EvalContext { return funcGen.NewEvalContext(nil).SetMaxListItems(1000) }},
+		{name: "index", exp: "numbers(n).accept(x->true)[5]", kind: funcGen.ListItemLimit,
+			ec: func() *funcGen.EvalContext { return funcGen.NewEvalContext(nil).SetMaxListItems(1000) }},
+		{name: "try", exp: "try numbers(n).sum() catch 0", kind: funcGen.ListItemLimit,
+			ec: func() *funcGen.EvalContext { return funcGen.NewEvalContext(nil).SetMaxListItems(1000) }},
+		{name: "multiUse", exp: "numbers(n).multiUse({a:l->l.sum(), b:l->l.size()})", kind: funcGen.ListItemLimit,
+			ec: func() *funcGen.EvalContext { return funcGen.NewEvalContext(nil).SetMaxListItems(1000) }},
+		{name: "canceled", exp: "numbers(n).sum()", kind: funcGen.Canceled,
+			ec: func() *funcGen.EvalContext {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return funcGen.NewEvalContext(ctx)
+			}},
+	}
+
+	for name, backend := range backends {
+		fg := New()
+		fg.SetBackend(backend)
+		for _, test := range tests {
+			t.Run(name+"/"+test.name, func(t *testing.T) {
+				f, _, err := fg.Generate(test.exp, "n")
+				assert.NoError(t, err)
+				res, err := f.EvalWith(test.ec(), Int(1000000000))
+				if test.res != nil {
+					assert.NoError(t, err)
+					assert.Equal(t, test.res, res)
+				} else {
+					var le *funcGen.LimitError
+					if assert.True(t, errors.As(err, &le), "no limit error: %v", err) {
+						assert.Equal(t, test.kind, le.Kind)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestEvalContextConstant(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  string
+		kind funcGen.LimitKind
+	}{
+		{name: "index", exp: "numbers(1000000000).accept(x->true)[5]", kind: funcGen.ListItemLimit},
+		{name: "sum", exp: "numbers(1000000000).map(x->x*2).sum()", kind: funcGen.ListItemLimit},
+	}
+
+	for name, backend := range backends {
+		fg := New()
+		fg.SetBackend(backend)
+		for _, test := range tests {
+			t.Run(name+"/"+test.name, func(t *testing.T) {
+				// the constant folding gives up if the expression is too expensive
+				start := time.Now()
+				f, _, err := fg.Generate(test.exp)
+				assert.NoError(t, err)
+				assert.Less(t, time.Since(start), 5*time.Second)
+
+				_, err = f.EvalWith(funcGen.NewEvalContext(nil).SetMaxListItems(1000))
+				var le *funcGen.LimitError
+				if assert.True(t, errors.As(err, &le), "no limit error: %v", err) {
+					assert.Equal(t, test.kind, le.Kind)
+				}
+			})
+		}
+	}
+}
+
+func TestEvalContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	f, _, err := New().Generate("func f(n) if n<0 then 0 else f(n+1); f(0)")
+	assert.NoError(t, err)
+	_, err = f.EvalWith(funcGen.NewEvalContext(ctx))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "evaluation aborted")
+}
+
+func TestEvalContextMemory(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  string
+	}{
+		{name: "eval", exp: "numbers(n).map(i->i*2).eval().size()"},
+		{name: "order", exp: "numbers(n).order(i->-i).first()"},
+		{name: "reverse", exp: "numbers(n).reverse().first()"},
+		{name: "groupByInt", exp: "numbers(n).groupByInt(i->i%1000).size()"},
+		{name: "groupByString", exp: "numbers(n).groupByString(i->\"\"+i%1000).size()"},
+		{name: "groupByEqual", exp: "numbers(n).groupByEqual(i->i%10).size()"},
+		{name: "string", exp: "func f(s, n) if n=0 then s.len() else f(s+s, n-1); f(\"0123456789\", n)"},
+		{name: "interpolation", exp: "func f(s, n) if n=0 then s.len() else f(\"${s}${s}\", n-1); f(\"0123456789\", n)"},
+		{name: "toString", exp: "numbers(n).string().len()"},
+		{name: "index", exp: "numbers(n).map(i->i*2)[n-1]"},
+		{name: "mapEval", exp: "numbers(n).map(i->{a:i}).reduce((a,b)->a.put(\"k\"+b.a, b.a)).eval().size()"},
+	}
+
+	fg := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, _, err := fg.Generate(test.exp, "n")
+			assert.NoError(t, err)
+
+			// small values can be evaluated within the limit
+			_, err = f.EvalWith(funcGen.NewEvalContext(nil).SetMaxMemory(10000), Int(5))
+			assert.NoError(t, err)
+
+			_, err = f.EvalWith(funcGen.NewEvalContext(nil).SetMaxMemory(10000), Int(10000))
+			assert.ErrorContains(t, err, "memory limit exceeded")
+			var le *funcGen.LimitError
+			if assert.True(t, errors.As(err, &le), "no limit error: %v", err) {
+				assert.Equal(t, funcGen.MemoryLimit, le.Kind)
+			}
+		})
+	}
+}
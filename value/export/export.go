@@ -8,13 +8,13 @@ import (
 
 type ListExporter interface {
 	Open() error
-	Add(item value.Value) error
+	Add(st funcGen.Stack[value.Value], item value.Value) error
 	Close() error
 }
 
 type MapExporter interface {
 	Open() error
-	Add(key string, val value.Value) error
+	Add(st funcGen.Stack[value.Value], key string, val value.Value) error
 	Close() error
 }
 
@@ -48,7 +48,7 @@ func Export[V any](st funcGen.Stack[value.Value], val value.Value, exporter Expo
 			if err != nil {
 				return err
 			}
-			err = le.Add(e)
+			err = le.Add(st, e)
 			if err != nil {
 				return err
 			}
@@ -68,7 +68,7 @@ func Export[V any](st funcGen.Stack[value.Value], val value.Value, exporter Expo
 		}
 		for _, k := range keys {
 			if item, ok := v.Get(k); ok {
-				err := ma.Add(k, item)
+				err := ma.Add(st, k, item)
 				if err != nil {
 					return err
 				}
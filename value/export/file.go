@@ -74,7 +74,7 @@ func AddFileHelpers(f *value.FunctionGenerator) {
 				}, nil
 			}
 			return nil, errors.New("zip requires a filename as argument")
-		}).SetMethodDescription("name", "Creates a zip file from the list of files."),
+		}).SetResult("file").SetMethodDescription("name", "Creates a zip file from the list of files."),
 	})
 	f.RegisterMethods(DataTypeId, value.MethodMap{
 		"add": value.MethodAtType(3, func(data *Data, st funcGen.Stack[value.Value]) (value.Value, error) {
@@ -94,7 +94,7 @@ func AddFileHelpers(f *value.FunctionGenerator) {
 				}
 			}
 			return nil, errors.New("add requires a name, a unit and a function")
-		}).SetMethodDescription("name", "unit", "func", "Creates a column in the data file."),
+		}).SetResult("dataFile").SetMethodDescription("name", "unit", "func", "Creates a column in the data file."),
 		"addIf": value.MethodAtType(4, func(data *Data, st funcGen.Stack[value.Value]) (value.Value, error) {
 			if cond, ok := st.Get(1).(value.Bool); ok {
 				if !cond {
@@ -117,25 +117,25 @@ func AddFileHelpers(f *value.FunctionGenerator) {
 				}
 			}
 			return nil, errors.New("addIf requires a bool, a name, a unit and a function")
-		}).SetMethodDescription("cond", "name", "unit", "func", "Creates a column in the data file if the condition is true."),
+		}).SetResult("dataFile").SetMethodDescription("cond", "name", "unit", "func", "Creates a column in the data file if the condition is true."),
 		"timeIsDate": value.MethodAtType(0, func(data *Data, st funcGen.Stack[value.Value]) (value.Value, error) {
 			data.TimeIsDate = true
 			return data, nil
-		}).SetMethodDescription("The time function returns a date given in seconds since 01.01.1970."),
+		}).SetResult("dataFile").SetMethodDescription("The time function returns a date given in seconds since 01.01.1970."),
 		"timeFormat": value.MethodAtType(1, func(data *Data, st funcGen.Stack[value.Value]) (value.Value, error) {
 			if format, ok := st.Get(1).(value.String); ok {
 				data.TimeFormat = string(format)
 				return data, nil
 			}
 			return nil, errors.New("timeFormat requires a format string")
-		}).SetMethodDescription("format", "Sets the time format."),
+		}).SetResult("dataFile").SetMethodDescription("format", "Sets the time format."),
 		"dateFormat": value.MethodAtType(1, func(data *Data, st funcGen.Stack[value.Value]) (value.Value, error) {
 			if format, ok := st.Get(1).(value.String); ok {
 				data.DateFormat = string(format)
 				return data, nil
 			}
 			return nil, errors.New("dateFormat requires a format string")
-		}).SetMethodDescription("format", "Sets the date format."),
+		}).SetResult("dataFile").SetMethodDescription("format", "Sets the date format."),
 		"dat": value.MethodAtType(2, func(data *Data, st funcGen.Stack[value.Value]) (value.Value, error) {
 			if name, ok := st.Get(1).(value.String); ok {
 				if list, ok := st.Get(2).(*value.List); ok {
@@ -151,7 +151,7 @@ func AddFileHelpers(f *value.FunctionGenerator) {
 				}
 			}
 			return nil, errors.New("dat requires a name and a list")
-		}).SetMethodDescription("name", "list", "Creates a dat file."),
+		}).SetResult("file").SetMethodDescription("name", "list", "Creates a dat file."),
 		"csv": value.MethodAtType(2, func(data *Data, st funcGen.Stack[value.Value]) (value.Value, error) {
 			if name, ok := st.Get(1).(value.String); ok {
 				if list, ok := st.Get(2).(*value.List); ok {
@@ -167,7 +167,7 @@ func AddFileHelpers(f *value.FunctionGenerator) {
 				}
 			}
 			return nil, errors.New("csv requires a name and a list")
-		}).SetMethodDescription("name", "list", "Creates a csv file."),
+		}).SetResult("file").SetMethodDescription("name", "list", "Creates a csv file."),
 	})
 	f.AddStaticFunction("dataFile", funcGen.Function[value.Value]{
 		Func: func(st funcGen.Stack[value.Value], closureStore []value.Value) (value.Value, error) {
@@ -190,6 +190,7 @@ func AddFileHelpers(f *value.FunctionGenerator) {
 		},
 		Args:   3,
 		IsPure: true,
+		Result: "dataFile",
 	}.SetDescription("timeName", "timeUnit", "func", "Creates a data file. The given function is used to create the time value. "+
 		"This function returns a data file. This file has the methods 'dat' and 'csv', which can be called with a list. "+
 		"A corresponding file is then generated from this list by calling the specified functions with the list elements. "+
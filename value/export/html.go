@@ -64,6 +64,7 @@ var linkFunc = funcGen.Function[value.Value]{
 	},
 	Args:   2,
 	IsPure: true,
+	Result: "link",
 }.SetDescription("name", "link", "Used to create a link.")
 
 // styleFunc can be used add a CSS style to a value
@@ -77,6 +78,7 @@ var styleFunc = funcGen.Function[value.Value]{
 	},
 	Args:   2,
 	IsPure: true,
+	Result: "format",
 }.SetDescription("style", "value", "Formats the value with the given style.")
 
 // colspanFunc can be used set the colspan of a cell
@@ -100,6 +102,7 @@ var colspanFunc = funcGen.Function[value.Value]{
 	},
 	Args:   2,
 	IsPure: true,
+	Result: "format",
 }.SetDescription("span", "value", "Sets the given colspan to the value.")
 
 // styleFuncCell can be used add a CSS stale to a value
@@ -115,6 +118,7 @@ var styleFuncCell = funcGen.Function[value.Value]{
 	},
 	Args:   2,
 	IsPure: true,
+	Result: "format",
 }.SetDescription("style", "value", "Formats the value with the given style. If used in a table, "+
 	"the style is applied to the cell instead of the containing value.")
 
@@ -165,10 +169,21 @@ func (l Link) GetType() value.Type {
 
 type CustomHTML func(value.Value) (template.HTML, bool, error)
 
+// HtmlOption is an option of the html export
+type HtmlOption func(ex *htmlExporter)
+
+// SIPrefixes is an HtmlOption which prints floats using SI
+// prefixes, so that 4700 becomes "4.7k" instead of "4700".
+func SIPrefixes() HtmlOption {
+	return func(ex *htmlExporter) {
+		ex.siPrefixes = true
+	}
+}
+
 // ToHtml creates an HTML representation of a value
 // Lists and maps are converted to a html table.
 // Everything else is converted to a string by calling the ToString() method.
-func ToHtml(v value.Value, maxListSize int, custom CustomHTML, inlineStyle bool) (res template.HTML, list []Class, err error) {
+func ToHtml(v value.Value, maxListSize int, custom CustomHTML, inlineStyle bool, options ...HtmlOption) (res template.HTML, list []Class, err error) {
 	defer func() {
 		if rec := recover(); rec != nil {
 			log.Print("panic in ToHtml: ", rec)
@@ -181,6 +196,9 @@ func ToHtml(v value.Value, maxListSize int, custom CustomHTML, inlineStyle bool)
 	}
 	w := xmlWriter.New().AvoidShort().PrettyPrint()
 	ex := htmlExporter{w: w, maxListSize: maxListSize, custom: custom, styleMap: make(map[string]string), inlineStyle: inlineStyle}
+	for _, o := range options {
+		o(&ex)
+	}
 	err = ex.toHtml(funcGen.NewEmptyStack[value.Value](), v, nil)
 	if err != nil {
 		return "", list, err
@@ -200,6 +218,7 @@ type htmlExporter struct {
 	classList   []Class
 	styleMap    map[string]string
 	inlineStyle bool
+	siPrefixes  bool
 }
 
 func (ex *htmlExporter) getClassName(style string) string {
@@ -329,7 +348,11 @@ func (ex *htmlExporter) toHtml(st funcGen.Stack[value.Value], v, style value.Val
 	case value.Float:
 		// Create a Unicode representation of the float value.
 		// I don't want to enforce the availability of MathMl just for this.
-		ex.w.Write(NewFormattedFloat(float64(t), 6).Unicode())
+		if ex.siPrefixes {
+			ex.w.Write(NewSIFormattedFloat(float64(t), 6).Unicode())
+		} else {
+			ex.w.Write(NewFormattedFloat(float64(t), 6).Unicode())
+		}
 	default:
 		if v == nil {
 			ex.w.Write("nil")
@@ -354,6 +377,8 @@ func (ex *htmlExporter) toHtml(st funcGen.Stack[value.Value], v, style value.Val
 type FormatedFloat struct {
 	Mantissa string
 	Exponent int
+	// Prefix is the SI prefix used instead of the exponent, if any
+	Prefix string
 }
 
 func NewFormattedFloat(f float64, prec int) FormatedFloat {
@@ -373,19 +398,57 @@ func NewFormattedFloat(f float64, prec int) FormatedFloat {
 	}
 }
 
+// siPrefixes are the SI prefixes used by NewSIFormattedFloat
+// indexed by the decimal exponent divided by three, shifted by five.
+var siPrefixes = []string{"f", "p", "n", "µ", "m", "", "k", "M", "G", "T", "P"}
+
+// NewSIFormattedFloat creates a FormatedFloat which uses an SI prefix
+// instead of an exponent, so that 4700 becomes "4.7k". If the value is
+// out of the range of the SI prefixes, NewFormattedFloat is used.
+func NewSIFormattedFloat(f float64, prec int) FormatedFloat {
+	va := math.Abs(f)
+	if va == 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return NewFormattedFloat(f, prec)
+	}
+	e := int(math.Floor(math.Log10(va) / 3))
+	if va/math.Pow(10, float64(e*3)) >= 1000 {
+		e++
+	} else if va/math.Pow(10, float64(e*3)) < 1 {
+		e--
+	}
+	for {
+		i := e + 5
+		if i < 0 || i >= len(siPrefixes) {
+			return NewFormattedFloat(f, prec)
+		}
+		m := strconv.FormatFloat(f/math.Pow(10, float64(e*3)), 'g', prec, 64)
+		if mv, err := strconv.ParseFloat(m, 64); err == nil && math.Abs(mv) < 1000 {
+			return FormatedFloat{
+				Mantissa: m,
+				Prefix:   siPrefixes[i],
+			}
+		}
+		// rounding has created a mantissa of 1000
+		e++
+	}
+}
+
 func (f FormatedFloat) IsZero() bool {
-	return f.Mantissa == "0" && f.Exponent == 0
+	return f.Mantissa == "0" && f.Exponent == 0 && f.Prefix == ""
 }
 
 func (f FormatedFloat) IsOne() bool {
-	return f.Mantissa == "1" && f.Exponent == 0
+	return f.Mantissa == "1" && f.Exponent == 0 && f.Prefix == ""
 }
 
 // Ascii formats the float value in a more human-readable way.
 // Instead of "2e6" the string "2*10^6" is returned.
 func (f FormatedFloat) Ascii() string {
+	if f.Prefix == "µ" {
+		return f.Mantissa + "u"
+	}
 	if f.Exponent == 0 {
-		return f.Mantissa
+		return f.Mantissa + f.Prefix
 	}
 	s := "10^" + strconv.Itoa(f.Exponent)
 	if f.Mantissa != "1" {
@@ -398,7 +461,7 @@ func (f FormatedFloat) Ascii() string {
 // Instead of "2e-6" the string "2⋅10⁻⁶" is returned.
 func (f FormatedFloat) Unicode() string {
 	if f.Exponent == 0 {
-		return f.Mantissa
+		return f.Mantissa + f.Prefix
 	}
 	s := "10" + ExpStr(f.Exponent)
 	if f.Mantissa != "1" {
@@ -411,6 +474,9 @@ func (f FormatedFloat) Unicode() string {
 func (f FormatedFloat) MathMl(w *xmlWriter.XMLWriter) {
 	if f.Exponent == 0 {
 		w.Open("mn").Write(f.Mantissa).Close()
+		if f.Prefix != "" {
+			w.Open("mi").Attr("mathvariant", "normal").Write(f.Prefix).Close()
+		}
 		return
 	}
 	if f.Mantissa != "1" {
@@ -426,6 +492,12 @@ func (f FormatedFloat) MathMl(w *xmlWriter.XMLWriter) {
 // LaTeX formats the float value to a LaTeX representation.
 // Instead of "2e-6" the string "2\cdot 10^{-6}" is returned.
 func (f FormatedFloat) LaTeX() string {
+	if f.Prefix == "µ" {
+		return f.Mantissa + "\\,\\mu"
+	}
+	if f.Prefix != "" {
+		return f.Mantissa + "\\,\\mathrm{" + f.Prefix + "}"
+	}
 	if f.Exponent == 0 {
 		return f.Mantissa
 	}
@@ -19,6 +19,7 @@ func TestToHtml(t *testing.T) {
 		html        string
 	}{
 		{"nil", nil, 10, "nil"},
+		{"nilValue", value.Nil{}, 10, "nil"},
 		{"int", value.Int(5), 10, "5"},
 		{"bool", value.Bool(true), 10, "true"},
 		{"bool", value.Bool(false), 10, "false"},
@@ -158,6 +159,39 @@ func TestFormatFloat(t *testing.T) {
 	}
 }
 
+func TestSIFormatFloat(t *testing.T) {
+	tests := []struct {
+		v       float64
+		unicode string
+		ascii   string
+		latex   string
+	}{
+		{0, "0", "0", "0"},
+		{1, "1", "1", "1"},
+		{470, "470", "470", "470"},
+		{1000, "1k", "1k", "1\\,\\mathrm{k}"},
+		{4700, "4.7k", "4.7k", "4.7\\,\\mathrm{k}"},
+		{-2.2e6, "-2.2M", "-2.2M", "-2.2\\,\\mathrm{M}"},
+		{1e-3, "1m", "1m", "1\\,\\mathrm{m}"},
+		{3.3e-6, "3.3µ", "3.3u", "3.3\\,\\mu"},
+		{999.9999999, "1k", "1k", "1\\,\\mathrm{k}"},
+		{1e-20, "10⁻²⁰", "10^-20", "10^{-20}"},
+		{2e18, "2⋅10¹⁸", "2*10^18", "2\\cdot 10^{18}"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.v), func(t *testing.T) {
+			ff := NewSIFormattedFloat(tt.v, 6)
+			assert.Equalf(t, tt.unicode, ff.Unicode(), "FormatFloat(%v)", tt.v)
+			assert.Equalf(t, tt.ascii, ff.Ascii(), "FormatFloat(%v)", tt.v)
+			assert.Equalf(t, tt.latex, ff.LaTeX(), "FormatFloat(%v)", tt.v)
+		})
+	}
+
+	h, _, err := ToHtml(value.NewList(value.Float(4700), value.Int(4700)), 10, nil, true, SIPrefixes())
+	assert.NoError(t, err)
+	assert.Equal(t, "<table>\n\t<tr>\n\t\t<td>1.</td>\n\t\t<td>4.7k</td>\n\t</tr>\n\t<tr>\n\t\t<td>2.</td>\n\t\t<td>4700</td>\n\t</tr>\n</table>\n", string(h))
+}
+
 func TestExpStr(t *testing.T) {
 	tests := []struct {
 		n    int
@@ -16,7 +16,7 @@ func (j *jsonListExporter) Open() error {
 	return err
 }
 
-func (j *jsonListExporter) Add(item value.Value) error {
+func (j *jsonListExporter) Add(st funcGen.Stack[value.Value], item value.Value) error {
 	if j.first {
 		j.first = false
 	} else {
@@ -25,7 +25,7 @@ func (j *jsonListExporter) Add(item value.Value) error {
 			return err
 		}
 	}
-	return Export[[]byte](funcGen.NewEmptyStack[value.Value](), item, j.j)
+	return Export[[]byte](st, item, j.j)
 }
 
 func (j *jsonListExporter) Close() error {
@@ -43,7 +43,7 @@ func (j *jsonMapExporter) Open() error {
 	return err
 }
 
-func (j *jsonMapExporter) Add(key string, val value.Value) error {
+func (j *jsonMapExporter) Add(st funcGen.Stack[value.Value], key string, val value.Value) error {
 	if j.first {
 		j.first = false
 	} else {
@@ -57,7 +57,7 @@ func (j *jsonMapExporter) Add(key string, val value.Value) error {
 		return err
 	}
 	j.j.b.WriteString(":")
-	return Export[[]byte](funcGen.NewEmptyStack[value.Value](), val, j.j)
+	return Export[[]byte](st, val, j.j)
 }
 
 func (j *jsonMapExporter) Close() error {
@@ -98,6 +98,10 @@ func (j jsonExporter) Map(value.Map) MapExporter {
 }
 
 func (j jsonExporter) Custom(val value.Value) (bool, error) {
+	if _, ok := val.(value.Nil); ok {
+		j.b.WriteString("null")
+		return true, nil
+	}
 	return false, nil
 }
 
@@ -1,6 +1,7 @@
 package export
 
 import (
+	"errors"
 	"github.com/hneemann/parser2/funcGen"
 	"github.com/hneemann/parser2/listMap"
 	"github.com/hneemann/parser2/value"
@@ -19,6 +20,11 @@ func TestJSON(t *testing.T) {
 			val:  nil,
 			want: "\"nil\"",
 		},
+		{
+			name: "nilValue",
+			val:  value.NewMap(listMap.New[value.Value](1).Append("a", value.Nil{})),
+			want: "{\"a\":null}",
+		},
 		{
 			name: "str",
 			val:  value.String("test"),
@@ -54,3 +60,17 @@ func TestJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONLimit(t *testing.T) {
+	f, _, err := value.New().Generate("numbers(3).map(i->numbers(1000000))")
+	assert.NoError(t, err)
+	list, err := f.Eval()
+	assert.NoError(t, err)
+
+	st := funcGen.NewEmptyStackWithContext[value.Value](funcGen.NewEvalContext(nil).SetMaxListItems(1000))
+	err = Export(st, list, JSON())
+	var le *funcGen.LimitError
+	if assert.True(t, errors.As(err, &le), "no limit error: %v", err) {
+		assert.Equal(t, funcGen.ListItemLimit, le.Kind)
+	}
+}
@@ -8,15 +8,23 @@ import (
 )
 
 type textExporter struct {
-	w       io.Writer
-	spaces  string
-	newline bool
+	w          io.Writer
+	spaces     string
+	newline    bool
+	siPrefixes bool
 }
 
 func NewTextExporter(w io.Writer) *textExporter {
 	return &textExporter{w: w}
 }
 
+// SIPrefixes enables printing floats using SI prefixes,
+// so that 4700 becomes "4.7k" instead of "4700".
+func (e *textExporter) SIPrefixes() *textExporter {
+	e.siPrefixes = true
+	return e
+}
+
 func (e *textExporter) write(s string) {
 	if e.newline {
 		e.w.Write([]byte(e.spaces))
@@ -47,6 +55,10 @@ func (e *textExporter) ToText(v value.Value) error {
 }
 
 func (e *textExporter) toText(st funcGen.Stack[value.Value], v value.Value) error {
+	if f, ok := v.(value.Float); ok && e.siPrefixes {
+		e.write(NewSIFormattedFloat(float64(f), 6).Unicode())
+		return nil
+	}
 	switch t := v.(type) {
 	case *value.List:
 		e.write("[")
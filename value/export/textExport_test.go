@@ -15,6 +15,7 @@ func TestToText(t *testing.T) {
 		text  string
 	}{
 		{"nil", nil, "nil"},
+		{"nilValue", value.Nil{}, "nil"},
 		{"int", value.Int(5), "5"},
 		{"bool", value.Bool(true), "true"},
 		{"bool", value.Bool(false), "false"},
@@ -35,3 +36,10 @@ func TestToText(t *testing.T) {
 	}
 
 }
+
+func TestToTextSIPrefixes(t *testing.T) {
+	var b bytes.Buffer
+	err := NewTextExporter(&b).SIPrefixes().ToText(value.NewList(value.Float(0.0047), value.Float(4), value.Int(4700)))
+	assert.NoError(t, err)
+	assert.Equal(t, "[\n  4.7m,\n  4,\n  4700\n]", b.String())
+}
@@ -16,9 +16,9 @@ func (x xmlListExporter) Open() error {
 	return nil
 }
 
-func (x xmlListExporter) Add(item value.Value) error {
+func (x xmlListExporter) Add(st funcGen.Stack[value.Value], item value.Value) error {
 	x.x.w.Open("entry")
-	err := Export[[]byte](funcGen.NewEmptyStack[value.Value](), item, x.x)
+	err := Export[[]byte](st, item, x.x)
 	if err != nil {
 		return err
 	}
@@ -41,8 +41,7 @@ func (x xmlMapExporter) Open() error {
 	return nil
 }
 
-func (x xmlMapExporter) Add(key string, val value.Value) error {
-	st := funcGen.NewEmptyStack[value.Value]()
+func (x xmlMapExporter) Add(st funcGen.Stack[value.Value], key string, val value.Value) error {
 	if x.isSimple {
 		str, err := val.ToString(st)
 		if err != nil {
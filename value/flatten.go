@@ -9,9 +9,10 @@ import (
 // The returned function takes a yield function as an argument, which is called with each flattened Value and
 // any error encountered.
 // The yield function should return true to continue yielding values or false to stop.
-func Flatten(v Value) func(yield func(v Value, err error) bool) {
+// The given stack is used to evaluate the lists and carries the EvalContext of the caller.
+func Flatten(st funcGen.Stack[Value], v Value) func(yield func(v Value, err error) bool) {
 	return func(yield func(v Value, err error) bool) {
-		flatten(v, nil, yield)
+		flatten(st, v, nil, yield)
 	}
 }
 
@@ -21,28 +22,28 @@ func Flatten(v Value) func(yield func(v Value, err error) bool) {
 func FlattenStack(st funcGen.Stack[Value], start int) func(yield func(v Value, err error) bool) {
 	return func(yield func(v Value, err error) bool) {
 		for i := start; i < st.Size(); i++ {
-			if !flatten(st.Get(i), nil, yield) {
+			if !flatten(st, st.Get(i), nil, yield) {
 				return
 			}
 		}
 	}
 }
 
-func flatten(v Value, err error, yield func(v Value, err error) bool) bool {
+func flatten(st funcGen.Stack[Value], v Value, err error, yield func(v Value, err error) bool) bool {
 	if err != nil {
 		return yield(v, err)
 	}
 
 	if list, ok := v.ToList(); ok {
-		for v, err := range list.Iterate(funcGen.NewEmptyStack[Value]()) {
-			if !flatten(v, err, yield) {
+		for v, err := range list.Iterate(st) {
+			if !flatten(st, v, err, yield) {
 				return false
 			}
 		}
 		return true
 	} else if m, ok := v.ToMap(); ok {
 		for _, value := range m.Iter {
-			if !flatten(value, nil, yield) {
+			if !flatten(st, value, nil, yield) {
 				return false
 			}
 		}
@@ -1,6 +1,8 @@
 package value
 
 import (
+	"errors"
+	"github.com/hneemann/parser2/funcGen"
 	"github.com/hneemann/parser2/listMap"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -37,7 +39,7 @@ func TestFlatten(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			i := 0
-			for v, err := range Flatten(tt.val) {
+			for v, err := range Flatten(funcGen.NewEmptyStack[Value](), tt.val) {
 				assert.NoError(t, err)
 				if i >= len(tt.want) {
 					t.Errorf("Flatten() got more values than expected, got %v", v)
@@ -50,7 +52,7 @@ func TestFlatten(t *testing.T) {
 
 			for n := 1; n < len(tt.want); n++ {
 				i := 0
-				for v, err := range Flatten(tt.val) {
+				for v, err := range Flatten(funcGen.NewEmptyStack[Value](), tt.val) {
 					assert.NoError(t, err)
 					assert.EqualValues(t, tt.want[i], v)
 					if i == n {
@@ -64,3 +66,22 @@ func TestFlatten(t *testing.T) {
 		})
 	}
 }
+
+func TestFlattenLimit(t *testing.T) {
+	f, _, err := New().Generate("numbers(1000000000).map(x->[x])")
+	assert.NoError(t, err)
+	list, err := f.Eval()
+	assert.NoError(t, err)
+
+	st := funcGen.NewEmptyStackWithContext[Value](funcGen.NewEvalContext(nil).SetMaxListItems(1000))
+	n := 0
+	for _, err := range Flatten(st, list) {
+		if err != nil {
+			var le *funcGen.LimitError
+			assert.True(t, errors.As(err, &le), "no limit error: %v", err)
+			break
+		}
+		n++
+	}
+	assert.Less(t, n, 1000)
+}
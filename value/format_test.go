@@ -0,0 +1,42 @@
+package value
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestFormatGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/format/*.in")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+	fg := New()
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			assert.NoError(t, err)
+			formatted, err := fg.Format(string(src), "data")
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			golden := strings.TrimSuffix(file, ".in") + ".golden"
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, []byte(formatted), 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), formatted)
+
+			again, err := fg.Format(formatted, "data")
+			assert.NoError(t, err)
+			assert.Equal(t, formatted, again, "formatting is not idempotent")
+		})
+	}
+}
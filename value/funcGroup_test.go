@@ -0,0 +1,53 @@
+package value
+
+import (
+	"github.com/hneemann/parser2"
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFuncGroup(t *testing.T) {
+	runTest(t, []testType{
+		{exp: "func isEven(n) if n=0 then true else isOdd(n-1); func isOdd(n) if n=0 then false else isEven(n-1); [isEven(10), isOdd(10), isOdd(7)]",
+			res: NewList(Bool(true), Bool(false), Bool(true))},
+		{exp: "func a(n) if n<1 then 0 else b(n-1)+1; func b(n) if n<1 then 0 else c(n-1)+2; func c(n) if n<1 then 0 else a(n-1)+3; a(6)", res: Int(12)},
+		{exp: "func a(n) b(n)*2; func b(n) n+1; a(2)", res: Int(6)},
+		{exp: "func a(n) b(n)*2; func b(n) n+1; func c(n) a(n)+b(n); c(2)", res: Int(9)},
+		{exp: "func sq(x) x*x; func a(n) if n<1 then 0 else sq(n)+b(n-1); func b(n) if n<1 then 0 else a(n-1); a(3)", res: Int(10)},
+		{exp: "let o=10; func a(n) if n<1 then o else b(n-1); func b(n) if n<1 then -o else a(n-1); [a(2), a(3)]", res: NewList(Int(10), Int(-10))},
+		{exp: "func a(n) if n<1 then 0 else [1,2].map(x->b(n-1)+x).sum(); func b(n) a(n); a(2)", res: Int(9)},
+		{exp: "func a(n, s=1) if n<1 then s else b(n-1); func b(n) a(n, s: 5); [a(0), a(1)]", res: NewList(Int(1), Int(5))},
+		{exp: "func f(x) func g(n) if n<1 then x else h(n-1); func h(n) g(n)*2; g(2); f(3)", res: Int(12)},
+		{exp: "func f(x) g(x); func g(x) x+1; let h=f; h(1)", res: Int(2)},
+		{exp: "func count(m) m.list().map(e->leaf(e.value)).sum(); func leaf(v) switch v match map: count(v) default 1; count({a:{b:1, c:{d:2, e:3}}, f:2})", res: Int(4)},
+	})
+}
+
+func TestFuncGroupString(t *testing.T) {
+	fg := New()
+	ast, err := fg.CreateAst("func isEven(n) if n=0 then true else isOdd(n-1); func isOdd(n) if n=0 then false else isEven(n-1); isEven(a)", fg.Identifier().Add("a"))
+	assert.NoError(t, err)
+	g, ok := ast.(*parser2.FuncGroup)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"isEven", "isOdd"}, g.Names())
+	assert.Equal(t, []string{"isOdd"}, g.Funcs[0].OuterIdents)
+	assert.Equal(t, []string{"isEven"}, g.Funcs[1].OuterIdents)
+	assert.Equal(t, "let isEven=n->if n=0 then true else isOdd(n-1); let isOdd=n->if n=0 then false else isEven(n-1); isEven(a)", ast.String())
+
+	// no group is created if there are no forward references
+	ast, err = fg.CreateAst("func a(n) n+1; func b(n) a(n)*2; b(2)", fg.Identifier())
+	assert.NoError(t, err)
+	assert.Equal(t, "6", ast.String())
+}
+
+func TestFuncGroupModule(t *testing.T) {
+	fg := New()
+	fg.SetModuleResolver(funcGen.MapResolver{
+		"parity": "func isEven(n) if n=0 then true else isOdd(n-1); func isOdd(n) if n=0 then false else isEven(n-1);",
+	})
+	runTestWith(t, fg, []testType{
+		{exp: "import \"parity\" as p; [p.isEven(4), p.isOdd(4)]", res: NewList(Bool(true), Bool(false))},
+		{exp: "import \"parity\" as p; p.size()", res: Int(2)},
+	})
+}
@@ -0,0 +1,146 @@
+package value
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hneemann/parser2"
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/hneemann/parser2/listMap"
+	"strconv"
+)
+
+// JSONCodec returns the codec which is required to encode and decode
+// an AST created by this generator using parser2.EncodeJSON and
+// parser2.DecodeJSON.
+func (fg *FunctionGenerator) JSONCodec() parser2.ConstCodec[Value] {
+	return fg.JSONCodecWith(nil)
+}
+
+// JSONCodecWith returns the codec which is required to encode and decode
+// an AST created by this generator. Lists which are not evaluated yet are
+// evaluated limited by the given evaluation context, which may be nil.
+func (fg *FunctionGenerator) JSONCodecWith(ec *funcGen.EvalContext) parser2.ConstCodec[Value] {
+	vc := &valueCodec{ec: ec}
+	c := fg.ConstCodec(vc)
+	vc.items = c
+	return c
+}
+
+// valueCodec encodes the basic values. The items of lists and maps
+// are encoded by the items codec, which is able to encode closures.
+type valueCodec struct {
+	items parser2.ConstCodec[Value]
+	ec    *funcGen.EvalContext
+}
+
+type jsonValue struct {
+	Type    string            `json:"type"`
+	Value   json.RawMessage   `json:"value,omitempty"`
+	Items   []json.RawMessage `json:"items,omitempty"`
+	Entries []jsonMapEntry    `json:"entries,omitempty"`
+}
+
+type jsonMapEntry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (vc *valueCodec) EncodeConst(v Value) (json.RawMessage, error) {
+	var jv jsonValue
+	var err error
+	switch t := v.(type) {
+	case Int:
+		jv = jsonValue{Type: "int"}
+		jv.Value, err = json.Marshal(int(t))
+	case Float:
+		jv = jsonValue{Type: "float"}
+		jv.Value, err = json.Marshal(strconv.FormatFloat(float64(t), 'g', -1, 64))
+	case Bool:
+		jv = jsonValue{Type: "bool"}
+		jv.Value, err = json.Marshal(bool(t))
+	case String:
+		jv = jsonValue{Type: "string"}
+		jv.Value, err = json.Marshal(string(t))
+	case Nil:
+		jv = jsonValue{Type: "nil"}
+	case *List:
+		jv = jsonValue{Type: "list"}
+		var items []Value
+		items, err = t.ToSlice(funcGen.NewEmptyStackWithContext[Value](vc.ec))
+		for _, item := range items {
+			if err != nil {
+				break
+			}
+			var data json.RawMessage
+			data, err = vc.items.EncodeConst(item)
+			jv.Items = append(jv.Items, data)
+		}
+	case Map:
+		jv = jsonValue{Type: "map", Entries: []jsonMapEntry{}}
+		t.Iter(func(key string, item Value) bool {
+			var data json.RawMessage
+			data, err = vc.items.EncodeConst(item)
+			jv.Entries = append(jv.Entries, jsonMapEntry{Key: key, Value: data})
+			return err == nil
+		})
+	default:
+		return nil, fmt.Errorf("value of type %T can not be encoded", v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jv)
+}
+
+func (vc *valueCodec) DecodeConst(data json.RawMessage) (Value, error) {
+	var jv jsonValue
+	err := json.Unmarshal(data, &jv)
+	if err != nil {
+		return nil, err
+	}
+	switch jv.Type {
+	case "int":
+		var i int
+		err = json.Unmarshal(jv.Value, &i)
+		return Int(i), err
+	case "float":
+		var s string
+		err = json.Unmarshal(jv.Value, &s)
+		if err != nil {
+			return nil, err
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		return Float(f), err
+	case "bool":
+		var b bool
+		err = json.Unmarshal(jv.Value, &b)
+		return Bool(b), err
+	case "string":
+		var s string
+		err = json.Unmarshal(jv.Value, &s)
+		return String(s), err
+	case "nil":
+		return Nil{}, nil
+	case "list":
+		items := make([]Value, len(jv.Items))
+		for i, d := range jv.Items {
+			items[i], err = vc.items.DecodeConst(d)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return NewList(items...), nil
+	case "map":
+		m := listMap.New[Value](len(jv.Entries))
+		for _, e := range jv.Entries {
+			v, err := vc.items.DecodeConst(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Append(e.Key, v)
+		}
+		return NewMap(m), nil
+	default:
+		return nil, fmt.Errorf("unknown value type '%s'", jv.Type)
+	}
+}
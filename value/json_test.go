@@ -0,0 +1,81 @@
+package value
+
+import (
+	"github.com/hneemann/parser2"
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		exp string
+		res string
+	}{
+		{exp: "1+2*3", res: "7"},
+		{exp: "1.5*2", res: "3"},
+		{exp: "\"Hello\"+\" World\"", res: "Hello World"},
+		{exp: "let a=[1,2,3]; a.map(x->x*a[1]).sum()", res: "12"},
+		{exp: "[1,2.5,true,\"s\",[1,2],{a:1}]", res: "[1, 2.5, true, s, [1, 2], {a:1}]"},
+		{exp: "let m={a:1,b:{c:2}}; m.a+m.b.c", res: "3"},
+		{exp: "let f=x->x*x; [1,2,3].map(f)", res: "[1, 4, 9]"},
+		{exp: "[x->x+1, x->x+2][1](1)", res: "3"},
+		{exp: "{f:x->x+1}.f(1)", res: "2"},
+		{exp: "func fac(n) if n<2 then 1 else n*fac(n-1); fac(5)", res: "120"},
+		{exp: "let o=1; [a*b+o for a in 1..3 for b in a..3 step 2 if b>1]", res: "[4, 5, 10]"},
+		{exp: "func isEven(n) if n=0 then true else isOdd(n-1); func isOdd(n) if n=0 then false else isEven(n-1); [isEven(4), isOdd(4)]", res: "[true, false]"},
+		{exp: "switch 2 case 1:\"a\" case 2:\"b\" default \"c\"", res: "b"},
+		{exp: "try [1][2] catch e->e.len()>0", res: "true"},
+		{exp: "let f=x->\"v=${x*2}\"; f(3)", res: "v=6"},
+		{exp: "let [a,b]=[1,2]; let {c}={c:3}; a+b+c", res: "6"},
+		{exp: "let o=1; [[1,2],[3,4]].map(([a,b])->a*b+o)", res: "[3, 13]"},
+		{exp: "func f(x, s=2) x*s; [f(1), f(2, s: 3), f(s: 4, x: 1)]", res: "[2, 6, 4]"},
+		{exp: "let o=1; let f=(x, s=2)->x*s+o; [f(1), f(2, s: 3)]", res: "[3, 7]"},
+		{exp: "let o=1; let f=(x, y)->x*y+o; 3 |> f(2)", res: "7"},
+		{exp: "let o=1; let f=(m: map, n: int=2)->m.a*n+o; [f({a:1}), f({a:2}, 3)]", res: "[3, 7]"},
+		{exp: "let f=m->m?.a ?? m?.b; [f({a:1}), f({b:2}), f({}), f(nil)]", res: "[1, 2, nil, nil]"},
+		{exp: "let f=l->switch l match [a, ...r] if a>1: r match {k: 2, v}: v match int: 0 default -1; [f([2,3]), f({k:2, v:5}), f(1), f([1])]", res: "[[3], 5, 0, -1]"},
+	}
+	fg := New()
+	codec := fg.JSONCodec()
+	for _, test := range tests {
+		test := test
+		t.Run(test.exp, func(t *testing.T) {
+			ast, err := fg.CreateAst(test.exp, fg.Identifier())
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			data, err := parser2.EncodeJSON[Value](ast, codec)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			decoded, err := parser2.DecodeJSON[Value](data, codec)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			fu, _, err := fg.GenerateFromAST(decoded)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			st := funcGen.NewEmptyStack[Value]()
+			res, err := fu(st)
+			assert.NoError(t, err)
+			str, err := res.ToString(st)
+			assert.NoError(t, err)
+			assert.EqualValues(t, test.res, str)
+		})
+	}
+}
+
+func TestJSONLimit(t *testing.T) {
+	fg := New()
+	ast, err := fg.CreateAst("numbers(1000000000).map(x->x*2)", fg.Identifier())
+	assert.NoError(t, err)
+
+	_, err = parser2.EncodeJSON[Value](ast, fg.JSONCodecWith(funcGen.NewEvalContext(nil).SetMaxMemory(10000)))
+	assert.ErrorContains(t, err, "memory limit exceeded")
+}
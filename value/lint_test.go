@@ -0,0 +1,69 @@
+package value
+
+import (
+	"github.com/hneemann/parser2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  string
+		want []string
+	}{
+		{name: "clean", exp: "let a=x*2; func f(n) n+a; f(1)"},
+		{name: "unusedLet", exp: "let a=1;\nlet b=2;\na+x", want: []string{"'b' is never used in line 2 [unused-let]"}},
+		{name: "unusedFunc", exp: "func fac(n) if n<2 then 1 else n*fac(n-1); x", want: []string{"'fac' is never used in line 1 [unused-let]"}},
+		{name: "unusedConst", exp: "const A=1; x", want: []string{"'A' is never used in line 1 [unused-let]"}},
+		{name: "unusedDestructured", exp: "let [a, b]=x; a", want: []string{"'b' is never used in line 1 [unused-let]"}},
+		{name: "underscore", exp: "let _a=1; [1,2].map((_i, v)->v)"},
+		{name: "unusedParam", exp: "func f(a, b) a*2; f(1, x)", want: []string{"parameter 'b' is never used in line 1 [unused-param]"}},
+		{name: "unusedClosureParam", exp: "x.map(e->1)", want: []string{"parameter 'e' is never used in line 1 [unused-param]"}},
+		{name: "unusedDestructuredParam", exp: "func f([a, b]) a; f(x)", want: []string{"parameter 'b' is never used in line 1 [unused-param]"}},
+		{name: "funcGroup", exp: "func isEven(n) if n=0 then true else isOdd(n-1); func isOdd(n) if n=0 then false else isEven(n-1); isEven(x)"},
+		{name: "shadowFunc", exp: "let sqrt=2; sqrt*x", want: []string{"'sqrt' shadows a static function in line 1 [shadowing]"}},
+		{name: "shadowConst", exp: "let pi=3; pi*x", want: []string{"'pi' shadows a constant in line 1 [shadowing]"}},
+		{name: "shadowScriptConst", exp: "const A=1; x.map(A->A*2)", want: []string{"'A' is never used in line 1 [unused-let]", "'A' shadows a constant in line 1 [shadowing]"}},
+		{name: "constCondition", exp: "const DEBUG=false;\nif DEBUG then 1 else x", want: []string{"condition 'DEBUG' is constant in line 2 [const-condition]"}},
+		{name: "constConditionInFunc", exp: "func f(n) if 1<2 then n else 0; f(x)", want: []string{"condition '1<2' is constant in line 1 [const-condition]"}},
+		{name: "duplicateCase", exp: "switch x case 1: \"a\" case 2: \"b\" case 1: \"c\" default \"d\"", want: []string{"case is never reached because an earlier case matches the same values in line 1 [unreachable-case]"}},
+		{name: "caseAfterBind", exp: "switch x match n: n match 5: 2 default 3", want: []string{"case is never reached because an earlier case matches all values in line 1 [unreachable-case]"}},
+		{name: "duplicateType", exp: "switch x match list: 1 match [a]: a match list: 2 default 3", want: []string{"case is never reached because an earlier case matches the same values in line 1 [unreachable-case]"}},
+		{name: "guarded", exp: "switch x match n if n>0: n match n: -n default 0"},
+	}
+
+	fg := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warnings, err := fg.Lint(test.exp, []string{"x"})
+			assert.NoError(t, err)
+			var got []string
+			for _, w := range warnings {
+				got = append(got, w.String())
+			}
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestLintDisabled(t *testing.T) {
+	fg := New()
+	src := "let a=1; func f(b) 2; let sqrt=3; if true then x else 0"
+	warnings, err := fg.Lint(src, []string{"x"})
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 6)
+
+	warnings, err = fg.Lint(src, []string{"x"}, parser2.LintUnusedLet, parser2.LintShadowing)
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 2)
+	assert.Equal(t, parser2.LintUnusedParam, warnings[0].Rule)
+	assert.Equal(t, parser2.LintConstCondition, warnings[1].Rule)
+	assert.Equal(t, 1, warnings[1].Num)
+	assert.Equal(t, 38, warnings[1].Column())
+}
+
+func TestLintError(t *testing.T) {
+	_, err := New().Lint("let a=1; b", nil)
+	assert.Error(t, err)
+}
@@ -58,18 +58,76 @@ func createSliceIterable(items []Value) ListProducer {
 
 // NewListFromIterable creates a list based on the given Iterable
 func NewListFromIterable(li ListProducer) *List {
-	return &List{iterable: li, itemsPresent: false, size: -1}
+	return &List{iterable: countItems(li), itemsPresent: false, size: -1}
 }
 
 // NewListFromSizedIterable creates a list based on the given Iterable.
 // In contrast to NewListFromIterable, this function is to be used if the
 // size of the iterable is known.
 func NewListFromSizedIterable(li ListProducer, size int) *List {
-	return &List{iterable: li, itemsPresent: false, size: size}
+	return &List{iterable: countItems(li), itemsPresent: false, size: size}
+}
+
+// countItems counts the items produced by the given iterable if
+// the evaluation is limited by an evaluation context.
+func countItems(li ListProducer) ListProducer {
+	return func(st funcGen.Stack[Value]) iterator.Producer[Value] {
+		p := li(st)
+		if !st.HasEvalContext() {
+			return p
+		}
+		return func(yield iterator.Consumer[Value]) {
+			p(func(v Value, err error) bool {
+				if err == nil {
+					if err = st.ListItem(); err != nil {
+						yield(nil, err)
+						return false
+					}
+				}
+				return yield(v, err)
+			})
+		}
+	}
 }
 
 type ListProducer = func(funcGen.Stack[Value]) iterator.Producer[Value]
 
+// NewRange creates the list of numbers from 'from' up to and including 'to'
+// using the given step. If all values are integers, the list contains integers.
+// The size of the list is known without creating the numbers.
+func NewRange(from, to, step Value) (*List, error) {
+	if f, ok := from.(Int); ok {
+		if t, ok := to.(Int); ok {
+			if s, ok := step.(Int); ok {
+				if s == 0 {
+					return nil, errors.New("step of range must not be zero")
+				}
+				size := 0
+				if (t-f)*s >= 0 {
+					size = int((t-f)/s) + 1
+				}
+				return NewListFromSizedIterable(func(st funcGen.Stack[Value]) iterator.Producer[Value] {
+					return iterator.Generate[Value](size, func(i int) (Value, error) { return f + Int(i)*s, nil })
+				}, size), nil
+			}
+		}
+	}
+	f, fOk := from.ToFloat()
+	t, tOk := to.ToFloat()
+	s, sOk := step.ToFloat()
+	if !(fOk && tOk && sOk) {
+		return nil, errors.New("range requires numbers")
+	}
+	if s == 0 {
+		return nil, errors.New("step of range must not be zero")
+	}
+	// the small offset avoids that the upper bound is lost due to rounding errors
+	size := max(int(math.Floor((t-f)/s+1e-9))+1, 0)
+	return NewListFromSizedIterable(func(st funcGen.Stack[Value]) iterator.Producer[Value] {
+		return iterator.Generate[Value](size, func(i int) (Value, error) { return Float(f + float64(i)*s), nil })
+	}, size), nil
+}
+
 // List represents a list of values
 type List struct {
 	items        []Value
@@ -106,7 +164,17 @@ func (l *List) ToString(st funcGen.Stack[Value]) (string, error) {
 		b.WriteString(s)
 	}
 	b.WriteString("]")
-	return b.String(), nil
+	return b.String(), st.Alloc(b.Len())
+}
+
+// stringStack returns the stack used by the String methods. They are
+// called by the fmt package, e.g. to create error messages, so there is
+// no evaluation context of a caller. The stack limits the evaluation to
+// avoid that printing a huge list exhausts the memory.
+func stringStack() funcGen.Stack[Value] {
+	return funcGen.NewEmptyStackWithContext[Value](funcGen.NewEvalContext(nil).
+		SetMaxListItems(10000).
+		SetMaxMemory(1 << 20))
 }
 
 func (l *List) String() string {
@@ -114,7 +182,7 @@ func (l *List) String() string {
 	b.WriteString("[")
 	first := true
 	count := 10
-	st := funcGen.NewEmptyStack[Value]()
+	st := stringStack()
 	for v, err := range l.iterable(st) {
 		if err != nil {
 			return fmt.Sprintf("error in list: %v", err)
@@ -150,6 +218,9 @@ func (l *List) Eval(st funcGen.Stack[Value]) error {
 			if err != nil {
 				return err
 			}
+			if err := st.Alloc(valueSize); err != nil {
+				return err
+			}
 			it = append(it, v)
 		}
 		l.items = it
@@ -231,6 +302,9 @@ func (l *List) CopyToSlice(st funcGen.Stack[Value]) ([]Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := st.Alloc(len(l.items) * valueSize); err != nil {
+		return nil, err
+	}
 	co := make([]Value, len(l.items))
 	copy(co, l.items)
 	return co, nil
@@ -299,7 +373,7 @@ func (l *List) Accept(sta funcGen.Stack[Value]) (*List, error) {
 	}
 	return NewListFromIterable(func(st funcGen.Stack[Value]) iterator.Producer[Value] {
 		return iterator.FilterAuto[Value](l.iterable(st), func() func(v Value) (bool, error) {
-			s := funcGen.NewEmptyStack[Value]()
+			s := st.NewEmpty()
 			return func(v Value) (bool, error) {
 				eval, err := f.Eval(s, v)
 				if err != nil {
@@ -321,7 +395,7 @@ func (l *List) Map(sta funcGen.Stack[Value]) (*List, error) {
 	}
 	return NewListFromSizedIterable(func(st funcGen.Stack[Value]) iterator.Producer[Value] {
 		return iterator.MapAuto[Value, Value](l.iterable(st), func() func(i int, v Value) (Value, error) {
-			s := funcGen.NewEmptyStack[Value]()
+			s := st.NewEmpty()
 			return func(i int, v Value) (Value, error) {
 				return f.Eval(s, v)
 			}
@@ -1082,6 +1156,9 @@ func (l *List) GroupByEqual(st funcGen.Stack[Value], fg *FunctionGenerator) (*Li
 			return nil, err
 		}
 
+		if err := st.Alloc(valueSize); err != nil {
+			return nil, err
+		}
 		added := false
 		for ind, item := range items {
 			eq, err := fg.equal(st, item.key, key)
@@ -1095,6 +1172,9 @@ func (l *List) GroupByEqual(st funcGen.Stack[Value], fg *FunctionGenerator) (*Li
 			}
 		}
 		if !added {
+			if err := st.Alloc(mapEntrySize); err != nil {
+				return nil, err
+			}
 			items = append(items, item{key: key, values: []Value{value}})
 		}
 	}
@@ -1153,9 +1233,15 @@ func groupBy(st funcGen.Stack[Value], list *List, keyFunc func(Value) (Value, er
 		if err != nil {
 			return nil, err
 		}
+		if err := st.Alloc(valueSize); err != nil {
+			return nil, err
+		}
 		if l, ok := m[key]; ok {
 			*l = append(*l, value)
 		} else {
+			if err := st.Alloc(mapEntrySize); err != nil {
+				return nil, err
+			}
 			ll := []Value{value}
 			m[key] = &ll
 		}
@@ -1493,31 +1579,40 @@ func (l *List) Set(st funcGen.Stack[Value]) (Value, error) {
 func createListMethods(fg *FunctionGenerator) MethodMap {
 	return MethodMap{
 		"accept": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Accept(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(item) bool",
 				"Filters the list by the given function. If the function returns true, the item is accepted, otherwise it is skipped."),
 		"map": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Map(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(item) newItem",
 				"Maps the list by the given function. The function is called for each item in the list and the result is "+
 					"added to the new list."),
 		"reduce": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Reduce(stack) }).
+			SetResult("any").
 			SetMethodDescription("func(item, item) item",
 				"Reduces the list by the given function. The function is called with the first two list items, and the result "+
 					"is used as the first argument for the third item and so on."),
 		"sum": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Sum(stack, fg) }).
+			SetResult("any").
 			SetMethodDescription("Returns the sum of all items in the list. Shorthand for reduce((a,b)->a+b)."),
 		"mapReduce": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.MapReduce(stack) }).
+			SetResult("any").
 			SetMethodDescription("initialSum", "func(sum, item) sum",
 				"MapReduce reduces the list to a single value. The initial value is given as the first argument. The function "+
 					"is called with the initial value and the first item, and the result is used as the first argument for the "+
 					"second item and so on."),
 		"mean": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Mean(stack, fg) }).
+			SetResult("any").
 			SetMethodDescription(
 				"Returns the mean value of the list."),
 		"min": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Min(stack, fg) }).
+			SetResult("any").
 			SetMethodDescription("Returns the minimum value of the list."),
 		"max": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Max(stack, fg) }).
+			SetResult("any").
 			SetMethodDescription("Returns the maximum value of the list."),
 		"minMax": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.MinMax(stack, fg) }).
+			SetResult("map").
 			SetMethodDescription("func(item) value",
 				"Returns the minimum and maximum value of the list. The function is called for each item in the list and the "+
 					"result is compared to the previous minimum and maximum. "+
@@ -1525,43 +1620,52 @@ func createListMethods(fg *FunctionGenerator) MethodMap {
 					"The 'min' and 'max' keys contain the minimum and maximum value, the 'minItem' and 'maxItem' keys contain "+
 					"the item for which the minimum and maximum value was returned and the 'valid' key contains a bool indicating if the list was not empty."),
 		"replaceList": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.ReplaceList(stack) }).
+			SetResult("any").
 			SetMethodDescription("func(list) newItem",
 				"Replaces the list by the result of the given function. The function is called with the list as argument."),
 		"combine": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Combine(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(item, item) newItem",
 				"Combines the list by the given function. The function is called for each pair of items in the list and the "+
 					"result is added to the new list. "+
 					"The resulting list is one item shorter than the original list."),
 		"combine3": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Combine3(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(item, item, item) newItem",
 				"Combines the list by the given function. The function is called for each triplet of items in the list and "+
 					"the result is added to the new list. "+
 					"The resulting list is two items shorter than the original list."),
 		"combineN": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.CombineN(stack) }).
+			SetResult("list").
 			SetMethodDescription("n", "func([item...]) newItem",
 				"Combines the list by the given function. The function is called for each group of n items in the list and "+
 					"the result is added to the new list. "+
 					"The resulting list is n-1 items shorter than the original list."),
 		"multiUse": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.MultiUse(stack) }).
+			SetResult("map").
 			SetMethodDescription("{name: func(item) newItem...}",
 				"MultiUse allows to use the list multiple times without storing or recomputing its elements. The first argument "+
 					"is a map of functions. "+
 					"All the functions are called with the list as argument and the result is returned in a map. "+
 					"The keys in the result map are the same keys used to pass the functions."),
 		"indexWhere": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.IndexWhere(stack) }).
+			SetResult("int").
 			SetMethodDescription("func(item) condition",
 				"Returns the index of the first occurrence of the given function returning true. If this never happens, -1 is returned."),
 		"groupByString": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.GroupByString(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(item) string", "Returns a list of lists grouped by the given function. "+
 				"The function is called for each item in the list and the returned string is used as the key for the group. "+
 				"The result is a list of maps with the keys 'key' and 'values'. The 'key' contains the string returned by the function "+
 				"and 'values' contains a list of items that have the same key."),
 		"groupByInt": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.GroupByInt(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(item) int", "Returns a list of lists grouped by the given function. "+
 				"The function is called for each item in the list and the returned integer is used as the key for the group. "+
 				"The result is a list of maps with the keys 'key' and 'values'. The 'key' contains the integer returned by the function "+
 				"and 'values' contains a list of items that have the same key."),
 		"groupByEqual": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.GroupByEqual(stack, fg) }).
+			SetResult("list").
 			SetMethodDescription("func(item) key", "Returns a list of lists grouped by the given function. "+
 				"The function is called for each item in the list and the returned value is used as the key for the group. "+
 				"The result is a list of maps with the keys 'key' and 'values'. The 'key' contains the value returned by the function "+
@@ -1569,19 +1673,24 @@ func createListMethods(fg *FunctionGenerator) MethodMap {
 				"This method relies only on the Equal operator to determine if two keys are equal. This way no hash can be computed, "+
 				"which makes this method much slower than the other groupBy methods, if the list is large (O(n²))."),
 		"uniqueString": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.UniqueString(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(item) string", "Returns a list of unique strings returned by the given function."),
 		"uniqueInt": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.UniqueInt(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(item) int", "Returns a list of unique integers returned by the given function."),
 		"compact": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Compact(stack) }).
+			SetResult("list").
 			SetMethodDescription("equal(a,b)", "Returns a new list with the items compacted. "+
 				"The given function is called for each successive pair of items in the list."+
 				"If the the function returns true, which means the items are equal, one is removed."),
 		"cross": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Cross(stack) }).
+			SetResult("list").
 			SetMethodDescription("other_list", "func(a,b) newItem",
 				"Returns a new list with the given function applied to each pair of items in the list and the given list. "+
 					"The function is called with an item from the first list and an item from the second list. "+
 					"The length of the resulting list is the product of the lengths of the two lists."),
 		"merge": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Merge(stack) }).
+			SetResult("list").
 			SetMethodDescription("other_list", "func(a,b) bool",
 				"Returns a new list with the items of both lists combined. "+
 					"The given function is called for the pair of the first, non processed items in both lists. If the "+
@@ -1589,29 +1698,36 @@ func createListMethods(fg *FunctionGenerator) MethodMap {
 					"The is repeated until all items of both lists are processed. "+
 					"If the function returns true if a<b holds and both lists are ordered, also the new list is ordered."),
 		"order": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Order(stack, false, fg) }).
+			SetResult("list").
 			SetMethodDescription("func(item) value",
 				"Returns a new list with the items sorted in the order of the values returned by the given function. "+
 					"The function is called for each item in the list and the returned values determine the order."),
 		"orderRev": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Order(stack, true, fg) }).
+			SetResult("list").
 			SetMethodDescription("func(item) value",
 				"Returns a new list with the items sorted in the reverse order of the values returned by the given function. "+
 					"The function is called for each item in the list and the returned values determine the order."),
 		"orderLess": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.OrderLess(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(a, a) bool",
 				"Returns a new list with the items sorted by the given function. "+
 					"The function is called for pairs of items in the list and the returned bool needs to be true if a<b holds."),
 		"reverse": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Reverse(stack) }).
+			SetResult("list").
 			SetMethodDescription("Returns the list in reverse order."),
 		"append": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Append(stack) }).
+			SetResult("list").
 			SetMethodDescription("item", "Returns a new list with the given item appended. "+
 				"If a list is to be created by adding element by element, this method is more efficient than using the '+' operator."),
 		"iir": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.IIr(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(first_item) first_new_item", "func(item, last_new_item) new_item",
 				"Returns a new list with the given functions applied to the items in the list. "+
 					"The first function is called with the first item in the list and returns the first item in the new list. "+
 					"The second function is called with the remaining items in the list as the first argument, and the last new item. "+
 					"For each subsequent item, the function is called with the item and the result of the previous call."),
 		"iirCombine": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.IIrCombine(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(first_item) first_new_item", "func(i0, i1, last_new_item) new_item",
 				"Returns a new list with the given functions applied to the items in the list. "+
 					"The first function is called with the first item in the list and returns the first item in the new list. "+
@@ -1619,73 +1735,94 @@ func createListMethods(fg *FunctionGenerator) MethodMap {
 					"For each subsequent item, the function is called with the the pair of items and the result of the previous call. "+
 					"The item i0 is the item in front of i1."),
 		"iirApply": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.IIrApply(stack) }).
+			SetResult("list").
 			SetMethodDescription("map",
 				"Returns a new list with the given filter applied to the items in the list. "+
 					"Works the same as 'iirCombine' except the required functions are taken from the map, stored in the keys 'initial' and 'filter'."),
 		"visit": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Visit(stack) }).
+			SetResult("any").
 			SetMethodDescription("initial_visitor", "func(visitor, item) visitor",
 				"Visits each item in the list with the given function. The function is called with the visitor and the item. "+
 					"An initial visitor is given as the first argument. The return value of the function is used as the new visitor."),
 		"fsm": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.FSM(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(state, item) state",
 				"Returns a new list with the given function applied to the items in the list. "+
 					"The state is initialized with '{state:0}' and the function is called with the state and the item and returns the new state. "+
 					"See also the function 'goto', which helps to create new state maps."),
 		"top": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Top(stack) }).
+			SetResult("list").
 			SetMethodDescription("n", "Returns the first n items of the list."),
 		"skip": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Skip(stack) }).
+			SetResult("list").
 			SetMethodDescription("n", "Returns a list without the first n items."),
 		"number": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Number(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(n,item) item",
 				"Returns a list with the given function applied to each item in the list. "+
 					"The function is called with the index of the item and the item itself."),
 		"present": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Present(stack) }).
+			SetResult("bool").
 			SetMethodDescription("func(item) bool", "Returns true if the given function returns true for any item in the list."),
 		"set": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Set(stack) }).
+			SetResult("list").
 			SetMethodDescription("index", "item", "Replaces the item at the given index with the given item. Returns the new list."),
 		"size": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) {
 			size, err := list.Size(stack)
 			return Int(size), err
 		}).
+			SetResult("int").
 			SetMethodDescription("Returns the number of items in the list."),
 		"first": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.First(stack) }).
+			SetResult("any").
 			SetMethodDescription("Returns the first item in the list."),
 		"single": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Single(stack) }).
+			SetResult("any").
 			SetMethodDescription("Returns the first item in the list."),
 		"last": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Last(stack) }).
+			SetResult("any").
 			SetMethodDescription("Returns the last item in the list."),
 		"eval": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list, list.Eval(stack) }).
+			SetResult("list").
 			SetMethodDescription("Evaluates the list and stores all items in memory."),
 		"string": MethodAtType(0, func(list *List, stack funcGen.Stack[Value]) (Value, error) {
 			s, err := list.ToString(stack)
 			return String(s), err
 		}).
+			SetResult("string").
 			SetMethodDescription("Returns the list as a string."),
 		"movingWindow": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.MovingWindow(stack) }).
+			SetResult("list").
 			SetMethodDescription("func(item) float", "Returns a list of lists. "+
 				"The inner lists contain all items that are close to each other. "+
 				"Two items are close to each other if the given function returns a similar value for both items. "+
 				"Similarity is defined as the absolute difference being smaller than 1."),
 		"movingWindowRemove": MethodAtType(1, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.MovingWindowRemove(stack) }).
+			SetResult("list").
 			SetMethodDescription("func([list of items]) bool", "Returns a list of lists. "+
 				"The given remove-function is called with a sublist of items. At every call a new item from the original list is added to the sublist. "+
 				"If the function returns true the first item of the sublist is removed and the function is called again until it returns false. "+
 				"If the function returns false or if the sublist contains only one item, the sublist is added to the result."),
 		"createInterpolation": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.CreateInterpolation(stack) }).
+			SetResult("closure").
 			SetMethodDescription("func(item) x", "func(item) y",
 				"Returns a function that interpolates between the given points."),
 		"linearReg": MethodAtType(2, func(list *List, stack funcGen.Stack[Value]) (Value, error) { return list.Linear(stack) }).
+			SetResult("map").
 			SetMethodDescription("func(item) x", "func(item) y",
 				"Returns a map containing the values a and b of the linear regression function y=a*x+b that fits the data points."),
 		"binning": MethodAtType(5, Binning).
+			SetResult("map").
 			SetMethodDescription("start", "size", "count", "indexFunc", "valueFunc",
 				"Returns a map with the binning results. The index function must return the index of the bin for a specific element "+
 					"of the list, and the value function must return the value to be added to the bin. "+
 					"If only the number of times a value was in a bin is to be counted, the value function must return the constant one."),
 		"binning2d": MethodAtType(9, Binning2d).
+			SetResult("map").
 			SetMethodDescription("startX", "sizeX", "countX", "startY", "sizeYX", "countY", "indexFuncX", "indexFuncY", "valueFunc",
 				"Returns a map with the binning results."),
 		"collectBinning": MethodAtType(0, CollectBinning).
+			SetResult("map").
 			SetMethodDescription("Sums up a list of binning results to create a total result."),
 	}
 }
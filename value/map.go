@@ -148,11 +148,11 @@ func (v Map) ToString(st funcGen.Stack[Value]) (string, error) {
 		return "", innerErr
 	}
 	b.WriteString("}")
-	return b.String(), nil
+	return b.String(), st.Alloc(b.Len())
 }
 
 func (v Map) String() string {
-	s, err := v.ToString(funcGen.NewEmptyStack[Value]())
+	s, err := v.ToString(stringStack())
 	if err != nil {
 		return fmt.Sprintf("Map Error: %v", err)
 	}
@@ -504,40 +504,52 @@ func (v Map) Replace(stack funcGen.Stack[Value]) (Map, error) {
 }
 func createMapMethods() MethodMap {
 	return MethodMap{
-		"eval": MethodAtType(0, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.Eval() }).
+		"eval": MethodAtType(0, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.Eval(stack) }).
+			SetResult("map").
 			SetMethodDescription("Evaluates the map to a real hash map. This is more efficient if the map has many " +
 				"keys and the associated values are requested often."),
 		"accept": MethodAtType(1, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.Accept(stack) }).
+			SetResult("map").
 			SetMethodDescription("func(key, value) bool",
 				"Accept takes a function as argument and returns a new map with all entries for which the function returns true."),
 		"map": MethodAtType(1, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.Map(stack) }).
+			SetResult("map").
 			SetMethodDescription("func(key, value) value",
 				"Map takes a function as argument and returns a new map with the same keys and all values replaced by the function."),
 		"replaceMap": MethodAtType(1, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.ReplaceMap(stack) }).
+			SetResult("any").
 			SetMethodDescription("func(map) value",
 				"Takes a function as argument and returns the result of the function. "+
 					"The function is called with the map as argument."),
 		"list": MethodAtType(0, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.List(), nil }).
+			SetResult("list").
 			SetMethodDescription("Returns a list of maps with the key and value of each entry in the map."),
 		"size": MethodAtType(0, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return Int(m.Size()), nil }).
+			SetResult("int").
 			SetMethodDescription("Returns the number of entries in the map."),
 		"string": MethodAtType(0, func(m Map, stack funcGen.Stack[Value]) (Value, error) {
 			s, err := m.ToString(stack)
 			return String(s), err
 		}).
+			SetResult("string").
 			SetMethodDescription("Returns a string representation of the map."),
 		"isAvail": MethodAtType(-1, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.IsAvail(stack) }).
+			SetResult("bool").
 			SetMethodDescription("key", "Returns true if the key is available in the map."),
 		"get": MethodAtType(1, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.GetM(stack) }).
+			SetResult("any").
 			SetMethodDescription("key", "Returns the value for the given key."),
 		"put": MethodAtType(2, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.PutM(stack) }).
+			SetResult("map").
 			SetMethodDescription("key", "value",
 				"Returns a new map with the given key and value added."),
 		"replace": MethodAtType(1, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.Replace(stack) }).
+			SetResult("map").
 			SetMethodDescription("func(map) rep_map",
 				"Calls the given function with the original map as argument and returns a 'replacement' map. "+
 					"The key/values from the 'replacement' map are used to replace the key/values in the original map."),
 		"combine": MethodAtType(2, func(m Map, stack funcGen.Stack[Value]) (Value, error) { return m.Combine(stack) }).
+			SetResult("map").
 			SetMethodDescription("other_map", "func(a,b) r",
 				"Combines the two maps with the given funktion to a new map. The function is called for each key that is in both maps. "+
 					"The first argument is the value of the first map and the second argument is the value of the second map. "+
@@ -576,11 +588,18 @@ func (v Map) keyListDescription() string {
 	return b.String()
 }
 
-func (v Map) Eval() (Value, error) {
+func (v Map) Eval(st funcGen.Stack[Value]) (Value, error) {
 	rm := make(RealMap)
+	var err error
 	v.Iter(func(key string, v Value) bool {
+		if err = st.Alloc(mapEntrySize + len(key)); err != nil {
+			return false
+		}
 		rm[key] = v
 		return true
 	})
+	if err != nil {
+		return nil, err
+	}
 	return NewMap(rm), nil
 }
@@ -0,0 +1,77 @@
+package value
+
+import (
+	"github.com/hneemann/parser2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const shapes = `
+func area(s)
+  switch s
+    match {type: "circle", r}: r*r*3
+    match {type: "rect", w, h}: w*h
+    default -1;
+[{type:"circle", r:2}, {type:"rect", w:2, h:3}, {type:"line"}, 5].map(area)
+`
+
+const sumList = `
+func sum(l)
+  switch l
+    match []: 0
+    match [first, ...rest]: first+sum(rest)
+    default 0;
+sum([1,2,3,4])
+`
+
+func TestMatch(t *testing.T) {
+	runTest(t, []testType{
+		{exp: "switch [1,2,3] match [a, ...r]: r default 0", res: NewList(Int(2), Int(3))},
+		{exp: "switch [1,2,3] match [a, b, c]: a+b+c default 0", res: Int(6)},
+		{exp: "switch [1,2] match [a, b, c]: a+b+c default 0", res: Int(0)},
+		{exp: "switch [1,2,3,4] match [a, b]: a+b default 0", res: Int(0)},
+		{exp: "switch [1,2,3,4] match [_, b, ...]: b default 0", res: Int(2)},
+		{exp: "switch [1] match [a, ...r]: r.size() default -1", res: Int(0)},
+		{exp: "switch [] match [a, ...r]: 1 match []: 2 default 3", res: Int(2)},
+		{exp: "switch [[1,2],[3]] match [[a,b],[c]]: a+b+c default 0", res: Int(6)},
+		{exp: "switch [1,\"a\"] match [1, s]: s default \"\"", res: String("a")},
+		{exp: "switch [2,\"a\"] match [1, s]: s default \"\"", res: String("")},
+		{exp: "switch {a:1,b:2} match {a, b}: a+b default 0", res: Int(3)},
+		{exp: "switch {a:1,b:2} match {a, c}: a+c default 0", res: Int(0)},
+		{exp: "switch {a:[1,2]} match {a: [x, y]}: x*y default 0", res: Int(2)},
+		{exp: "switch 5 match string: 1 match int: 2 default 3", res: Int(2)},
+		{exp: "switch \"x\" match string: 1 match int: 2 default 3", res: Int(1)},
+		{exp: "switch 5.5 match int: 1 match float: 2 default 3", res: Int(2)},
+		{exp: "switch [1, 2.5] match [int, float]: 1 default 3", res: Int(1)},
+		{exp: "switch 12 match x if x>10: x*2 match x: x default 0", res: Int(24)},
+		{exp: "switch 8 match x if x>10: x*2 match x: x default 0", res: Int(8)},
+		{exp: "switch [5,1] match [a, b] if a<b: a match [a, b]: b default 0", res: Int(1)},
+		{exp: "switch 2 case 1: \"a\" match int: \"int\" default \"c\"", res: String("int")},
+		{exp: "switch true match false: 0 match true: 1 default 2", res: Int(1)},
+		{exp: "switch -1 match -1: 0 default 2", res: Int(0)},
+		{exp: "let v=3; switch [v] match [x]: x+v default 2", res: Int(6)},
+		{exp: "let f=o->switch [o] match [x]: x+o default 2; f(2)", res: Int(4)},
+		{exp: shapes, res: NewList(Int(12), Int(6), Int(-1), Int(-1))},
+		{exp: sumList, res: Int(10)},
+	})
+}
+
+func TestMatchString(t *testing.T) {
+	tests := []struct {
+		exp  string
+		want string
+	}{
+		{exp: "switch x match [a, ...r]: r default 0", want: "switch x match [a, ...r] : r default 0"},
+		{exp: "switch x match {type: \"c\", r} if r>1: r default 0", want: "switch x match {type: \"c\", r} if r>1 : r default 0"},
+		{exp: "switch x match int: 1 case 2: 3 default 0", want: "switch x match int : 1 case 2 : 3 default 0"},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.exp, func(t *testing.T) {
+			var idents parser2.Identifiers[Value]
+			ast, err := New().CreateAst(test.exp, idents.Add("x"))
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, ast.String())
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package value
+
+// The sizes used to estimate the memory allocated by materialized values.
+// The memory is accounted by funcGen.Stack.Alloc.
+const (
+	// valueSize is the size of a value stored in a slice
+	valueSize = 16
+	// mapEntrySize is the size of a map entry without the key
+	mapEntrySize = 48
+)
@@ -0,0 +1,168 @@
+package value
+
+import (
+	"errors"
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var testModules = funcGen.MapResolver{
+	"math":  "func sq(x) x*x; func cube(x) x*sq(x); let two = 2; let half = 1/two; const three = 3;",
+	"fac":   "func fac(n) if n<2 then 1 else n*fac(n-1);",
+	"uses":  "import \"math\" as m; func sq4(x) m.sq(m.sq(x)); let [a, b] = [1, 2];",
+	"list":  "let l = [1, 2, 3].map(x->x*2);",
+	"empty": "",
+	"cycA":  "import \"cycB\" as b; let a = 1;",
+	"cycB":  "import \"cycA\" as a; let b = 1;",
+	"self":  "import \"self\" as s; let a = 1;",
+	"cycF":  "func f(x) import \"cycF\" as c; x; let a = 1;",
+	"expr":  "let a = 1; a+1",
+	"err":   "let a = [1, 2][5];",
+	"heavy": "let sum = numbers(1000000000).sum();",
+}
+
+func TestImport(t *testing.T) {
+	fg := New()
+	fg.SetModuleResolver(testModules)
+	runTestWith(t, fg, []testType{
+		{exp: "import \"math\" as m; m.sq(3)", res: Int(9)},
+		{exp: "import \"math\" as m; m.cube(2)+m.two", res: Int(10)},
+		{exp: "import \"math\" as m; m.three", res: Int(3)},
+		{exp: "import \"math\" as m; m.half", res: Float(0.5)},
+		{exp: "import \"math\" as m; [1, 2, 3].map(x->m.sq(x))", res: NewList(Int(1), Int(4), Int(9))},
+		{exp: "import \"math\" as m; [1, 2].map(m.sq)", res: NewList(Int(1), Int(4))},
+		{exp: "import \"math\" as m; func f(x) m.sq(x)+1; f(2)", res: Int(5)},
+		{exp: "import \"math\" as m; import \"fac\" as f; f.fac(m.sq(2))", res: Int(24)},
+		{exp: "import \"uses\" as u; [u.sq4(2), u.a, u.b]", res: NewList(Int(16), Int(1), Int(2))},
+		{exp: "import \"list\" as l; l.l", res: NewList(Int(2), Int(4), Int(6))},
+		{exp: "import \"empty\" as e; e.size()", res: Int(0)},
+		{exp: "import \"math\" as m; m.size()", res: Int(5)},
+		{exp: "func f(x) import \"math\" as m; m.sq(x); f(5)", res: Int(25)},
+	})
+}
+
+func TestImportErrors(t *testing.T) {
+	fg := New()
+	fg.SetModuleResolver(testModules)
+	tests := []struct {
+		exp string
+		err string
+	}{
+		{exp: "import \"unknown\" as u; 1", err: "module 'unknown' not found"},
+		{exp: "import \"cycA\" as a; 1", err: "import cycle: cycA -> cycB -> cycA"},
+		{exp: "import \"self\" as s; 1", err: "import cycle: self -> self"},
+		{exp: "import \"cycF\" as f; 1", err: "import cycle: cycF -> cycF"},
+		{exp: "import \"expr\" as e; 1", err: "a module may only contain func, let, const and import definitions"},
+		{exp: "import \"err\" as e; 1", err: "error evaluating module 'err'"},
+		{exp: "import \"math\" as m; m.unknown(1)", err: "unknown"},
+		{exp: "import \"math\" as m; import \"fac\" as m; 1", err: "redeclaration of 'm'"},
+		{exp: "import math as m; 1", err: "no module name followed by import"},
+		{exp: "import \"math\" m; 1", err: "expected 'as'"},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.exp, func(t *testing.T) {
+			f, _, err := fg.Generate(test.exp)
+			if err == nil {
+				_, err = f(funcGen.NewEmptyStack[Value]())
+			}
+			if assert.Error(t, err) {
+				assert.True(t, strings.Contains(err.Error(), test.err), "expected '%s', got '%v'", test.err, err)
+			}
+		})
+	}
+
+	_, _, err := New().Generate("import \"math\" as m; 1")
+	assert.ErrorContains(t, err, "no module resolver set")
+}
+
+type countingResolver struct {
+	parent funcGen.ModuleResolver
+	count  map[string]int
+}
+
+func (c countingResolver) Resolve(name string) (string, error) {
+	c.count[name]++
+	return c.parent.Resolve(name)
+}
+
+func TestImportCache(t *testing.T) {
+	cr := countingResolver{parent: testModules, count: map[string]int{}}
+	fg := New()
+	fg.SetModuleResolver(cr)
+	for _, exp := range []string{
+		"import \"math\" as m; m.sq(2)",
+		"import \"uses\" as u; u.sq4(2)",
+		"import \"math\" as m; import \"uses\" as u; m.sq(u.a)",
+	} {
+		f, _, err := fg.Generate(exp)
+		assert.NoError(t, err)
+		_, err = f(funcGen.NewEmptyStack[Value]())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, map[string]int{"math": 1, "uses": 1}, cr.count)
+}
+
+func TestImportEvalContext(t *testing.T) {
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			fg := New()
+			fg.SetBackend(backend)
+			fg.SetModuleResolver(testModules)
+			f, _, err := fg.Generate("import \"heavy\" as h; h.sum")
+			assert.NoError(t, err)
+			_, err = f.EvalWith(funcGen.NewEvalContext(nil).SetMaxListItems(1000))
+			var le *funcGen.LimitError
+			if assert.True(t, errors.As(err, &le), "no limit error: %v", err) {
+				assert.Equal(t, funcGen.ListItemLimit, le.Kind)
+			}
+			assert.ErrorContains(t, err, "error evaluating module 'heavy'")
+		})
+	}
+}
+
+func TestImportDir(t *testing.T) {
+	fg := New()
+	fg.SetModuleResolver(funcGen.DirResolver{Dir: "testdata/modules", Ext: ".exp"})
+
+	runTestWith(t, fg, []testType{
+		{exp: "import \"stats\" as s; [s.mean([1, 2, 3]), s.variance([1, 3]), s.version]", res: NewList(Float(2), Float(1), Int(2))},
+		{exp: "import \"geo/circle\" as c; c.meanArea([1, 3])", res: Float(4 * math.Pi)},
+	})
+
+	_, _, err := fg.Generate("import \"../modules/stats\" as s; 1")
+	assert.ErrorContains(t, err, "module name '../modules/stats' not allowed")
+	_, _, err = fg.Generate("import \"missing\" as s; 1")
+	assert.ErrorContains(t, err, "module 'missing' not found")
+}
+
+func TestImportConcurrent(t *testing.T) {
+	fg := New()
+	fg.SetModuleResolver(testModules)
+	// the parser is initialized lazily at the first use, which is not thread safe
+	_, _, err := fg.Generate("1")
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				f, _, err := fg.Generate("import \"uses\" as u; import \"fac\" as f; f.fac(u.sq4(1)+2)")
+				if assert.NoError(t, err) {
+					res, err := f(funcGen.NewEmptyStack[Value]())
+					assert.NoError(t, err)
+					assert.Equal(t, Int(6), res)
+				}
+
+				_, _, err = fg.Generate("import \"cycB\" as b; 1")
+				assert.ErrorContains(t, err, "import cycle: cycB -> cycA -> cycB")
+			}
+		}()
+	}
+	wg.Wait()
+}
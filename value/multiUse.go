@@ -39,7 +39,7 @@ func (l *List) MultiUse(st funcGen.Stack[Value]) (Map, error) {
 		prList, run, done := iterator.CopyProducer[Value](len(muList))
 		for i, mu := range muList {
 			pr := prList[i]
-			go mu.runConsumer(pr, done)
+			go mu.runConsumer(st.NewEmpty(), pr, done)
 		}
 		err := run(l.iterable(st))
 
@@ -64,9 +64,8 @@ type multiUseList []*multiUseEntry
 // runConsumer calls the closure and sends the result to the result channel. If
 // the closure panics, the panic is recovered and also sent to the result
 // channel. if the closure returns a list, the list is evaluated before it is
-// sent to the result channel.
-func (mu *multiUseEntry) runConsumer(itera iterator.Producer[Value], done func(error)) {
-	st := funcGen.NewEmptyStack[Value]()
+// sent to the result channel. The given stack is used to evaluate the closure.
+func (mu *multiUseEntry) runConsumer(st funcGen.Stack[Value], itera iterator.Producer[Value], done func(error)) {
 	used := false
 	var innerErr error
 	st.Push(NewListFromIterable(func(st funcGen.Stack[Value]) iterator.Producer[Value] {
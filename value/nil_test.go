@@ -0,0 +1,59 @@
+package value
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNil(t *testing.T) {
+	runTest(t, []testType{
+		{exp: "nil", res: Nil{}},
+		{exp: "{a:1}?.a", res: Int(1)},
+		{exp: "{a:1}?.b", res: Nil{}},
+		{exp: "let m={a:{b:2}}; m?.a?.b", res: Int(2)},
+		{exp: "let m={a:{b:2}}; m?.c?.b", res: Nil{}},
+		{exp: "let m={a:{b:2}}; m?.c?.b?.size()", res: Nil{}},
+		{exp: "let m={a:1}; m?.b ?? 5", res: Int(5)},
+		{exp: "let m={a:1}; m?.a ?? 5", res: Int(1)},
+		{exp: "let m={a:1}; m?.a.string()", res: String("1")},
+		{exp: "nil ?? nil ?? 3", res: Int(3)},
+		{exp: "1 ?? throw(\"not evaluated\")", res: Int(1)},
+		{exp: "false ?? true", res: Bool(false)},
+		{exp: "nil?.string()", res: Nil{}},
+		{exp: "nil.string()", res: String("nil")},
+		{exp: "nil = nil", res: Bool(true)},
+		{exp: "nil = 1", res: Bool(false)},
+		{exp: "\"a\" != nil", res: Bool(true)},
+		{exp: "{a:1}?.b = nil", res: Bool(true)},
+		{exp: "[1, nil] = [1, nil]", res: Bool(true)},
+		{exp: "let f=m->m?.a ?? 0; [f({a:2}), f({}), f(nil)]", res: NewList(Int(2), Int(0), Int(0))},
+		{exp: "let f=l->l?.size(); [f(nil), f([1,2])]", res: NewList(Nil{}, Int(2))},
+		{exp: "let f=m->m?.a; [{a:1}, {b:2}].map(f)", res: NewList(Int(1), Nil{})},
+		{exp: "switch {a:1}?.b match nil: 1 default 2", res: Int(1)},
+		{exp: "switch {a:1}?.b case nil: 1 default 2", res: Int(1)},
+		{exp: "string(nil)", res: String("nil")},
+	})
+}
+
+func TestNilString(t *testing.T) {
+	tests := []struct {
+		exp  string
+		want string
+	}{
+		{exp: "nil", want: "nil"},
+		{exp: "{a:1}?.b", want: "nil"},
+		{exp: "[1, {a:1}?.b]", want: "[1, nil]"},
+		{exp: "{a:nil}", want: "{a:nil}"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.exp, func(t *testing.T) {
+			f, _, err := New().Generate(test.exp)
+			assert.NoError(t, err)
+			res, err := f.Eval()
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, fmt.Sprint(res))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package value
+
+import (
+	"github.com/hneemann/parser2"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EnableEngineeringNumbers enables the number literals used in engineering.
+// These are hexadecimal and binary numbers like "0xFF" and "0b1010",
+// underscores as digit separators like "1_000_000" and SI prefixes
+// like "4.7k", "10m" or "3µ". Since "2m" is no longer read as "2*m",
+// this mode needs to be enabled explicitly. It must be enabled before the
+// parser is created.
+func (fg *FunctionGenerator) EnableEngineeringNumbers() *FunctionGenerator {
+	fg.SetNumberMatcher(parser2.EngineeringNumber)
+	fg.engineeringNumbers = true
+	return fg
+}
+
+// siExponents are the decimal exponents of the SI prefixes
+var siExponents = map[rune]int{
+	'f': -15, 'p': -12, 'n': -9, 'u': -6, 'µ': -6, 'μ': -6, 'm': -3,
+	'k': 3, 'M': 6, 'G': 9, 'T': 12, 'P': 15,
+}
+
+// parseEngineeringNumber parses the numbers accepted by parser2.EngineeringNumber.
+// A number with an SI prefix is an int if the number without the prefix is an
+// int and the prefix does not create a fraction.
+func parseEngineeringNumber(n string) (Value, error) {
+	if len(n) > 2 && n[0] == '0' && strings.ContainsRune("xXbB", rune(n[1])) {
+		i, err := strconv.ParseInt(n, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		return Int(i), nil
+	}
+
+	exp := 0
+	for r, e := range siExponents {
+		if strings.HasSuffix(n, string(r)) {
+			n = n[:len(n)-len(string(r))]
+			exp = e
+			break
+		}
+	}
+
+	// ParseFloat checks if the underscores are placed correctly
+	fl, err := strconv.ParseFloat(n, 64)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.ContainsAny(n, ".eE") && exp >= 0 {
+		if i, err := strconv.Atoi(strings.ReplaceAll(n, "_", "")); err == nil {
+			e := exp
+			for ; e > 0 && i <= math.MaxInt/10; e-- {
+				i *= 10
+			}
+			if e == 0 {
+				return Int(i), nil
+			}
+		}
+	}
+	if exp != 0 {
+		// parsed again to avoid rounding errors caused by the scaling
+		fl, err = strconv.ParseFloat(n+"e"+strconv.Itoa(exp), 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Float(fl), nil
+}
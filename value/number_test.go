@@ -0,0 +1,41 @@
+package value
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEngineeringNumbers(t *testing.T) {
+	runTestWith(t, New().EnableEngineeringNumbers(), []testType{
+		{exp: "0xFF", res: Int(255)},
+		{exp: "0x_ff_ff", res: Int(65535)},
+		{exp: "0b1010", res: Int(10)},
+		{exp: "1_000_000", res: Int(1000000)},
+		{exp: "1_000.5", res: Float(1000.5)},
+		{exp: "0012", res: Int(12)},
+		{exp: "1e3", res: Float(1000)},
+		{exp: "2.5e-3", res: Float(0.0025)},
+		{exp: "4.7k", res: Float(4700)},
+		{exp: "2k", res: Int(2000)},
+		{exp: "2G", res: Int(2000000000)},
+		{exp: "10m", res: Float(0.01)},
+		{exp: "3µ", res: Float(3e-6)},
+		{exp: "3u", res: Float(3e-6)},
+		{exp: "100n+1p", res: Float(100.001e-9)},
+		{exp: "let m=2; 10m*m", res: Float(0.02)},
+		{exp: "[a for a in 1..3]", res: NewList(Int(1), Int(2), Int(3))},
+		{exp: "0x10+0b10", res: Int(18)},
+	})
+}
+
+func TestEngineeringNumbersError(t *testing.T) {
+	fg := New().EnableEngineeringNumbers()
+	for _, exp := range []string{"0b12", "1__0", "0xG"} {
+		_, _, err := fg.Generate(exp)
+		assert.Error(t, err, exp)
+	}
+
+	// not enabled by default
+	_, _, err := New().Generate("4.7k")
+	assert.Error(t, err)
+}
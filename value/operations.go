@@ -10,25 +10,28 @@ import (
 // Equal does not cover lists and maps
 func Equal(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "=")
-	m.Register(BoolTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(BoolTypeId, BoolTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(a.(Bool) == b.(Bool)), nil
 	})
-	m.Register(IntTypeId, IntTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(a.(Int) == b.(Int)), nil
 	})
-	m.Register(StringTypeId, StringTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(StringTypeId, StringTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(a.(String) == b.(String)), nil
 	})
-	m.Register(FloatTypeId, FloatTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, FloatTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(a.(Float) == b.(Float)), nil
 	})
-	m.Register(IntTypeId, FloatTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, FloatTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(Float(a.(Int)) == b.(Float)), nil
 	})
-	m.Register(FloatTypeId, IntTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, IntTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(a.(Float) == Float(b.(Int))), nil
 	})
 	deepEqual := &operationMatrixDeepEqual{equal: m, ef: func(st funcGen.Stack[Value], a, b Value) (bool, error) {
+		if isNil(a) || isNil(b) {
+			return isNil(a) && isNil(b), nil
+		}
 		eq, err := m.Calc(st, a, b)
 		if err != nil {
 			return false, err
@@ -54,6 +57,9 @@ type operationMatrixDeepEqual struct {
 }
 
 func (o *operationMatrixDeepEqual) Calc(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	if isNil(a) || isNil(b) {
+		return Bool(isNil(a) && isNil(b)), nil
+	}
 	if aa, ok := a.(*List); ok {
 		if bb, ok := b.(*List); ok {
 			equals, err := aa.Equals(st, bb, o.ef)
@@ -73,21 +79,25 @@ func (o *operationMatrixDeepEqual) Register(a, b Type, op funcGen.OperatorFunc[V
 	o.equal.Register(a, b, op)
 }
 
+func (o *operationMatrixDeepEqual) RegisterResult(a, b, result Type, op funcGen.OperatorFunc[Value]) {
+	o.equal.RegisterResult(a, b, result, op)
+}
+
 func Less(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "<")
-	m.Register(IntTypeId, IntTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(a.(Int) < b.(Int)), nil
 	})
-	m.Register(StringTypeId, StringTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(StringTypeId, StringTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(a.(String) < b.(String)), nil
 	})
-	m.Register(FloatTypeId, FloatTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, FloatTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(a.(Float) < b.(Float)), nil
 	})
-	m.Register(IntTypeId, FloatTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, FloatTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(Float(a.(Int)) < b.(Float)), nil
 	})
-	m.Register(FloatTypeId, IntTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, IntTypeId, BoolTypeId, func(_ funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Bool(a.(Float) < Float(b.(Int))), nil
 	})
 
@@ -115,7 +125,11 @@ type operationMatrixStringAdd struct {
 func (o operationMatrixStringAdd) Calc(st funcGen.Stack[Value], a, b Value) (Value, error) {
 	if a.GetType() == StringTypeId {
 		str, err := b.ToString(st)
-		return a.(String) + String(str), err
+		if err != nil {
+			return nil, err
+		}
+		s := a.(String) + String(str)
+		return s, st.Alloc(len(s))
 	}
 	return o.parent.Calc(st, a, b)
 }
@@ -124,27 +138,31 @@ func (o operationMatrixStringAdd) Register(a, b Type, op funcGen.OperatorFunc[Va
 	o.parent.Register(a, b, op)
 }
 
+func (o operationMatrixStringAdd) RegisterResult(a, b, result Type, op funcGen.OperatorFunc[Value]) {
+	o.parent.RegisterResult(a, b, result, op)
+}
+
 func Add(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "+")
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Int) + b.(Int), nil
 	})
-	m.Register(FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Float) + b.(Float), nil
 	})
-	m.Register(IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Float(a.(Int)) + b.(Float), nil
 	})
-	m.Register(FloatTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Float) + Float(b.(Int)), nil
 	})
-	m.Register(ListTypeId, ListTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(ListTypeId, ListTypeId, ListTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return NewListFromIterable(
 			func(st funcGen.Stack[Value]) iterator.Producer[Value] {
 				return iterator.Append(a.(*List).iterable(st), b.(*List).iterable(st))
 			}), nil
 	})
-	m.Register(MapTypeId, MapTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(MapTypeId, MapTypeId, MapTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Map).Merge(b.(Map))
 	})
 	return operationMatrixStringAdd{m}
@@ -152,16 +170,16 @@ func Add(fg *FunctionGenerator) OperationMatrix {
 
 func Sub(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "-")
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Int) - b.(Int), nil
 	})
-	m.Register(FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Float) - b.(Float), nil
 	})
-	m.Register(IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Float(a.(Int)) - b.(Float), nil
 	})
-	m.Register(FloatTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Float) - Float(b.(Int)), nil
 	})
 	return m
@@ -169,7 +187,7 @@ func Sub(fg *FunctionGenerator) OperationMatrix {
 
 func Left(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "<<")
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Int) << b.(Int), nil
 	})
 	return m
@@ -177,7 +195,7 @@ func Left(fg *FunctionGenerator) OperationMatrix {
 
 func Right(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, ">>")
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Int) >> b.(Int), nil
 	})
 	return m
@@ -185,7 +203,7 @@ func Right(fg *FunctionGenerator) OperationMatrix {
 
 func Mod(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "%")
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Int) % b.(Int), nil
 	})
 	return m
@@ -193,16 +211,16 @@ func Mod(fg *FunctionGenerator) OperationMatrix {
 
 func Mul(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "*")
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Int) * b.(Int), nil
 	})
-	m.Register(FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Float) * b.(Float), nil
 	})
-	m.Register(IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Float(a.(Int)) * b.(Float), nil
 	})
-	m.Register(FloatTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Float) * Float(b.(Int)), nil
 	})
 	return m
@@ -210,16 +228,16 @@ func Mul(fg *FunctionGenerator) OperationMatrix {
 
 func Div(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "/")
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Float(a.(Int)) / Float(b.(Int)), nil
 	})
-	m.Register(FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Float) / b.(Float), nil
 	})
-	m.Register(IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Float(a.(Int)) / b.(Float), nil
 	})
-	m.Register(FloatTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Float) / Float(b.(Int)), nil
 	})
 	return m
@@ -227,7 +245,7 @@ func Div(fg *FunctionGenerator) OperationMatrix {
 
 func Pow(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "^")
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		aa := a.(Int)
 		bb := b.(Int)
 		if bb > 0 && bb < 10 {
@@ -240,13 +258,13 @@ func Pow(fg *FunctionGenerator) OperationMatrix {
 			return Int(math.Pow(float64(aa), float64(bb))), nil
 		}
 	})
-	m.Register(FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Float(math.Pow(float64(a.(Float)), float64(b.(Float)))), nil
 	})
-	m.Register(FloatTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(FloatTypeId, IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Float(math.Pow(float64(a.(Float)), float64(b.(Int)))), nil
 	})
-	m.Register(IntTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, FloatTypeId, FloatTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return Float(math.Pow(float64(a.(Int)), float64(b.(Float)))), nil
 	})
 	return m
@@ -254,10 +272,10 @@ func Pow(fg *FunctionGenerator) OperationMatrix {
 
 func Neg(fg *FunctionGenerator) *SimpleUnary {
 	u := NewUnaryOperationList(fg, "-")
-	u.Register(IntTypeId, func(a Value) (Value, error) {
+	u.RegisterResult(IntTypeId, IntTypeId, func(a Value) (Value, error) {
 		return -a.(Int), nil
 	})
-	u.Register(FloatTypeId, func(a Value) (Value, error) {
+	u.RegisterResult(FloatTypeId, FloatTypeId, func(a Value) (Value, error) {
 		return -a.(Float), nil
 	})
 	return u
@@ -265,7 +283,7 @@ func Neg(fg *FunctionGenerator) *SimpleUnary {
 
 func Not(fg *FunctionGenerator) *SimpleUnary {
 	u := NewUnaryOperationList(fg, "!")
-	u.Register(BoolTypeId, func(a Value) (Value, error) {
+	u.RegisterResult(BoolTypeId, BoolTypeId, func(a Value) (Value, error) {
 		return !a.(Bool), nil
 	})
 	return u
@@ -273,10 +291,10 @@ func Not(fg *FunctionGenerator) *SimpleUnary {
 
 func And(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "&")
-	m.Register(BoolTypeId, BoolTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(BoolTypeId, BoolTypeId, BoolTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Bool) && b.(Bool), nil
 	})
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Int) & b.(Int), nil
 	})
 	return m
@@ -284,10 +302,10 @@ func And(fg *FunctionGenerator) OperationMatrix {
 
 func Or(fg *FunctionGenerator) OperationMatrix {
 	m := NewOperationMatrix(fg, "|")
-	m.Register(BoolTypeId, BoolTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(BoolTypeId, BoolTypeId, BoolTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Bool) || b.(Bool), nil
 	})
-	m.Register(IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
+	m.RegisterResult(IntTypeId, IntTypeId, IntTypeId, func(st funcGen.Stack[Value], a, b Value) (Value, error) {
 		return a.(Int) | b.(Int), nil
 	})
 	return m
@@ -0,0 +1,46 @@
+package value
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	runTest(t, []testType{
+		{exp: "16 |> sqrt", res: Float(4)},
+		{exp: "16 |> sqrt |> round", res: Int(4)},
+		{exp: "func f(x, y) x-y; 5 |> f(2)", res: Int(3)},
+		{exp: "func f(x, y) x-y; 5 |> f(2) |> f(1)", res: Int(2)},
+		{exp: "func f(x, y) x-y; 2+3 |> f(1)", res: Int(4)},
+		{exp: "func f(x, y=10) x*y; 2 |> f", res: Int(20)},
+		{exp: "func f(x, y=10) x*y; 2 |> f(y: 3)", res: Int(6)},
+		{exp: "let sq=x->x*x; 3 |> sq", res: Int(9)},
+		{exp: "let a=numbers(3).size(); let f=(x, y)->x*y+a; 2 |> f(3)", res: Int(9)},
+		{exp: "let m={f:x->x+1}; 1 |> m.f", res: Int(2)},
+		{exp: "let f=a->x->x*a; 3 |> (f(2))", res: Int(6)},
+		{exp: "[1,2,3] |> (l->l.size())", res: Int(3)},
+		{exp: "[1,2,3].map(x->x |> sqrt |> round)", res: NewList(Int(1), Int(1), Int(2))},
+		{exp: "[sqrt(4) |> round, 1]", res: NewList(Int(2), Int(1))},
+	})
+}
+
+func TestPipeString(t *testing.T) {
+	tests := []struct {
+		exp string
+		str string
+	}{
+		{exp: "let f=(x, y)->x-y+a; a |> f(2)", str: "let f=(x, y)->(x-y)+a; f(a, 2)"},
+		{exp: "a |> sqrt", str: "sqrt(a)"},
+		{exp: "4 |> sqrt", str: "2"},
+		{exp: "let sq=x->x*x; 3 |> sq", str: "9"},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.exp, func(t *testing.T) {
+			fg := New()
+			ast, err := fg.CreateAst(test.exp, fg.Identifier().Add("a"))
+			assert.NoError(t, err)
+			assert.Equal(t, test.str, ast.String())
+		})
+	}
+}
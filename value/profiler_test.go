@@ -0,0 +1,242 @@
+package value
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+const profileSrc = `let f=x->sqrt(x)*n;
+let l=numbers(10).map(f);
+l.size()`
+
+func TestProfiler(t *testing.T) {
+	p := funcGen.NewProfiler()
+	fg := New()
+	fg.SetProfiler(p)
+	f, _, err := fg.Generate(profileSrc, "n")
+	assert.NoError(t, err)
+	res, err := f.Eval(Int(2))
+	assert.NoError(t, err)
+	assert.Equal(t, Int(10), res)
+
+	calls := map[string]int64{}
+	for _, e := range p.Entries() {
+		calls[e.Name] = e.Calls
+	}
+	assert.Equal(t, int64(10), calls["sqrt"])
+	assert.Equal(t, int64(1), calls["map"])
+	assert.Equal(t, int64(1), calls["size"])
+	assert.Equal(t, int64(10), calls["Operate: sqrt(x)*n"])
+	assert.Equal(t, int64(10), calls["FunctionCall: sqrt(x)"])
+	assert.Equal(t, int64(1), calls["MethodCall: l.size()"])
+
+	an := p.Annotate(profileSrc)
+	lines := strings.Split(an, "\n")
+	assert.Contains(t, lines[0], "calls")
+	assert.Regexp(t, "^ +10 .*  let f=x->sqrt\\(x\\)\\*n;$", lines[1])
+	assert.Regexp(t, "^ +1 .*  l.size\\(\\)$", lines[3])
+	assert.Contains(t, an, "  .map\n")
+	assert.Contains(t, an, "  sqrt\n")
+
+	var b bytes.Buffer
+	assert.NoError(t, p.WritePprof(&b))
+	r, err := gzip.NewReader(&b)
+	assert.NoError(t, err)
+	proto, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	for _, s := range []string{"calls", "nanoseconds", "script", "sqrt", "FunctionCall: sqrt(x)"} {
+		assert.True(t, bytes.Contains(proto, []byte(s)), s)
+	}
+}
+
+func TestProfilerModes(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(fg *FunctionGenerator)
+		err   string
+	}{
+		{name: "tree", setup: func(fg *FunctionGenerator) {}},
+		{name: "bytecode", setup: func(fg *FunctionGenerator) { fg.SetBackend(funcGen.BytecodeBackend) },
+			err: "the profiler requires the tree backend"},
+		{name: "debugger", setup: func(fg *FunctionGenerator) { fg.SetDebugger(&recordingDebugger{}) },
+			err: "the profiler can not be used together with the debugger"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fg := New()
+			fg.SetProfiler(funcGen.NewProfiler())
+			test.setup(fg)
+			_, _, err := fg.Generate("n*2", "n")
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestProfilerPprof(t *testing.T) {
+	p := funcGen.NewProfiler()
+	fg := New()
+	fg.SetProfiler(p)
+	f, _, err := fg.Generate(`func fib(n) if n<2 then n else fib(n-1)+fib(n-2);
+                              numbers(20).map(i->fib(i)).sum()`)
+	assert.NoError(t, err)
+	_, err = f.Eval()
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	assert.NoError(t, p.WritePprof(&b))
+	r, err := gzip.NewReader(&b)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	prof := decodeProto(t, data)
+
+	var strs []string
+	for _, s := range prof.bytes(6) {
+		strs = append(strs, string(s))
+	}
+	str := func(i int64) string {
+		if assert.True(t, i >= 0 && i < int64(len(strs)), "string %d not found", i) {
+			return strs[i]
+		}
+		return ""
+	}
+
+	sampleTypes := prof.messages(t, 1)
+	assert.Len(t, sampleTypes, 2)
+	assert.Equal(t, "time", str(sampleTypes[1].int(1)))
+
+	functions := map[int64]bool{}
+	names := map[string]bool{}
+	for _, fu := range prof.messages(t, 5) {
+		functions[fu.int(1)] = true
+		name := str(fu.int(2))
+		names[name] = true
+		assert.NotEqual(t, name, str(fu.int(3)))
+	}
+	assert.True(t, names["FunctionCall: fib(n-1)"])
+	assert.True(t, names["MethodCall: numbers(20).map(i->fib(i))"])
+
+	locations := map[int64]bool{}
+	for _, l := range prof.messages(t, 4) {
+		locations[l.int(1)] = true
+		for _, line := range l.messages(t, 4) {
+			assert.True(t, functions[line.int(1)], "function %d not found", line.int(1))
+		}
+	}
+
+	var total int64
+	depth := 0
+	for _, s := range prof.messages(t, 2) {
+		stack := s.packed(t, 1)
+		for _, id := range stack {
+			assert.True(t, locations[int64(id)], "location %d not found", id)
+		}
+		depth = max(depth, len(stack))
+		values := s.packed(t, 2)
+		if assert.Len(t, values, 2) {
+			total += int64(values[1])
+		}
+	}
+	assert.True(t, total > 0)
+	duration := prof.int(10)
+	assert.True(t, total <= duration, "total %d exceeds wall time %d", total, duration)
+
+	// the recursive calls are contained in the stacks
+	assert.True(t, depth > 20, depth)
+}
+
+// protoMessage is a decoded protocol buffers message. It maps the field
+// numbers to the values, which are uint64 for varints and byte slices
+// for length delimited fields.
+type protoMessage map[int][]any
+
+// decodeProto decodes the protocol buffers message written by WritePprof.
+// Only the wire types used by WritePprof are supported.
+func decodeProto(t *testing.T, data []byte) protoMessage {
+	m := protoMessage{}
+	uvarint := func() uint64 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatal("invalid varint")
+		}
+		data = data[n:]
+		return v
+	}
+	for len(data) > 0 {
+		key := uvarint()
+		field := int(key >> 3)
+		switch key & 7 {
+		case 0:
+			m[field] = append(m[field], uvarint())
+		case 2:
+			l := uvarint()
+			if l > uint64(len(data)) {
+				t.Fatal("invalid length")
+			}
+			m[field] = append(m[field], data[:l])
+			data = data[l:]
+		default:
+			t.Fatalf("unsupported wire type %d", key&7)
+		}
+	}
+	return m
+}
+
+// int returns the varint stored in the given field, zero if not present
+func (m protoMessage) int(field int) int64 {
+	for _, v := range m[field] {
+		if i, ok := v.(uint64); ok {
+			return int64(i)
+		}
+	}
+	return 0
+}
+
+func (m protoMessage) bytes(field int) [][]byte {
+	var b [][]byte
+	for _, v := range m[field] {
+		if d, ok := v.([]byte); ok {
+			b = append(b, d)
+		}
+	}
+	return b
+}
+
+func (m protoMessage) messages(t *testing.T, field int) []protoMessage {
+	var msg []protoMessage
+	for _, d := range m.bytes(field) {
+		msg = append(msg, decodeProto(t, d))
+	}
+	return msg
+}
+
+// packed returns the packed or unpacked varints stored in the given field
+func (m protoMessage) packed(t *testing.T, field int) []uint64 {
+	var values []uint64
+	for _, v := range m[field] {
+		switch d := v.(type) {
+		case uint64:
+			values = append(values, d)
+		case []byte:
+			for len(d) > 0 {
+				i, n := binary.Uvarint(d)
+				if n <= 0 {
+					t.Fatal("invalid varint")
+				}
+				values = append(values, i)
+				d = d[n:]
+			}
+		}
+	}
+	return values
+}
@@ -130,7 +130,8 @@ func (s String) BehindList(st funcGen.Stack[Value]) (Value, error) {
 func (s String) Replace(st funcGen.Stack[Value]) (Value, error) {
 	if oldStr, ok := st.Get(1).(String); ok {
 		if newStr, ok := st.Get(2).(String); ok {
-			return String(strings.Replace(string(s), string(oldStr), string(newStr), -1)), nil
+			r := strings.Replace(string(s), string(oldStr), string(newStr), -1)
+			return String(r), st.Alloc(len(r))
 		}
 	}
 	return nil, errors.New("replace needs two strings (old,new) as arguments")
@@ -155,40 +156,51 @@ func (s String) ParseToInt() (Value, error) {
 func createStringMethods() MethodMap {
 	return MethodMap{
 		"len": MethodAtType(0, func(str String, stack funcGen.Stack[Value]) (Value, error) { return Int(len(string(str))), nil }).
+			SetResult("int").
 			SetMethodDescription("Returns the length of the string."),
 		"string": MethodAtType(0, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str, nil }).
+			SetResult("string").
 			SetMethodDescription("Returns the string itself."),
 		"trim": MethodAtType(0, func(str String, stack funcGen.Stack[Value]) (Value, error) {
 			return String(strings.TrimSpace(string(str))), nil
-		}).SetMethodDescription("Returns the string without leading and trailing spaces."),
+		}).SetResult("string").SetMethodDescription("Returns the string without leading and trailing spaces."),
 		"toLower": MethodAtType(0, func(str String, stack funcGen.Stack[Value]) (Value, error) {
 			return String(strings.ToLower(string(str))), nil
-		}).SetMethodDescription("Returns the string in lower case."),
+		}).SetResult("string").SetMethodDescription("Returns the string in lower case."),
 		"toUpper": MethodAtType(0, func(str String, stack funcGen.Stack[Value]) (Value, error) {
 			return String(strings.ToUpper(string(str))), nil
-		}).SetMethodDescription("Returns the string in upper case."),
+		}).SetResult("string").SetMethodDescription("Returns the string in upper case."),
 		"contains": MethodAtType(1, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str.Contains(stack) }).
+			SetResult("bool").
 			SetMethodDescription("substr",
 				"Returns true if the string contains the substr."),
 		"indexOf": MethodAtType(1, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str.IndexOf(stack) }).
+			SetResult("int").
 			SetMethodDescription("substr",
 				"Returns the index of the first occurrence of substr in the string. Returns -1 if not found."),
 		"split": MethodAtType(1, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str.Split(stack) }).
+			SetResult("list").
 			SetMethodDescription("sep",
 				"Splits the string at the separator and returns a list of strings."),
 		"cut": MethodAtType(2, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str.Cut(stack) }).
+			SetResult("string").
 			SetMethodDescription("pos", "len",
 				"Returns a substring starting at pos with length len. "+
 					"If len is negative, the rest of the string is returned."),
 		"behind": MethodAtType(1, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str.Behind(stack) }).
+			SetResult("string").
 			SetMethodDescription("prefix", "Returns the string behind the prefix up to the next newline."),
 		"behindList": MethodAtType(1, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str.BehindList(stack) }).
+			SetResult("list").
 			SetMethodDescription("header", "Returns the lines following behind the header line up to the next empty line."),
 		"replace": MethodAtType(2, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str.Replace(stack) }).
+			SetResult("string").
 			SetMethodDescription("old", "new", "Replaces all occurrences of old with new."),
 		"toFloat": MethodAtType(0, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str.ParseToFloat() }).
+			SetResult("float").
 			SetMethodDescription("Parses the string to a float."),
 		"toInt": MethodAtType(0, func(str String, stack funcGen.Stack[Value]) (Value, error) { return str.ParseToInt() }).
+			SetResult("int").
 			SetMethodDescription("Parses the string to an int."),
 	}
 }
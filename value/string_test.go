@@ -1,6 +1,10 @@
 package value
 
-import "testing"
+import (
+	"github.com/hneemann/parser2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
 
 func TestString(t *testing.T) {
 	runTest(t, []testType{
@@ -29,5 +33,24 @@ func TestString(t *testing.T) {
 		{exp: "\"Items:\\na\\nb\\nc\\n\\ntestf\".behindList(\"Items:\").reduce((a,b)->a+\", \"+b)", res: String("a, b, c")},
 		{exp: "\"Items:\\na\\nb\\nc\".behindList(\"Items:\").reduce((a,b)->a+\", \"+b)", res: String("a, b, c")},
 		{exp: "\"Items:\\na\\nb\\nc\".behindList(\"Hello:\").mapReduce(\"\",(s,b)->s+\", \"+b)", res: String("")},
+
+		{exp: "\"\\u00e4\\u00d6\\t\"", res: String("äÖ\t")},
+		{exp: "`a\\n\nb`", res: String("a\\n\nb")},
+		{exp: "`${a}`", res: String("${a}")},
+		{exp: "let p={Name:\"Bob\", Age:42}; \"Name: ${p.Name}, age ${p.Age}\"", res: String("Name: Bob, age 42")},
+		{exp: "let a=2; \"${a}*${a}=${a*a}\"", res: String("2*2=4")},
+		{exp: "let a=2; \"${a}\"", res: String("2")},
+		{exp: "\"${1+2}\"", res: String("3")},
+		{exp: "let m={a:1}; \"${ {b:m.a}.b }\"", res: String("1")},
+		{exp: "let a=\"x\"; \"[${\"<${a}>\"}]\"", res: String("[<x>]")},
+		{exp: "let l=[1,2]; \"list: ${l.map(e->\"(${e})\").reduce((a,b)->a+b)}\"", res: String("list: (1)(2)")},
+		{exp: "\"\\${a}$\"", res: String("${a}$")},
 	})
 }
+
+func TestInterpolationString(t *testing.T) {
+	var idents parser2.Identifiers[Value]
+	ast, err := New().CreateAst("\"a\\\"${a+1}\\n\\$\"", idents.Add("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, "\"a\\\"${a+1}\\n\\$\"", ast.String())
+}
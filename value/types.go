@@ -0,0 +1,596 @@
+package value
+
+import (
+	"fmt"
+	"github.com/hneemann/parser2"
+	"github.com/hneemann/parser2/funcGen"
+	"slices"
+)
+
+// Arg is an argument of a function together with its type.
+// It is used to declare the types of the arguments passed to
+// a generated function. A zero type means the type is unknown.
+type Arg struct {
+	Name string
+	Type Type
+}
+
+// GenerateTyped creates a function like Generate does, but before the
+// function is created, the types are checked by CheckTypes. The types of
+// the arguments are used as the starting point of the type inference.
+func (fg *FunctionGenerator) GenerateTyped(exp string, args ...Arg) (funcGen.Func[Value], bool, error) {
+	names := make([]string, len(args))
+	for i, a := range args {
+		names[i] = a.Name
+	}
+	ast, err := fg.CreateAst(exp, fg.Identifier().AddArgs(names, nil))
+	if err != nil {
+		return nil, false, err
+	}
+	if err := fg.CheckTypes(ast, args...); err != nil {
+		return nil, false, err
+	}
+	return fg.GenerateFromAST(ast, names...)
+}
+
+// CheckTypes infers the types of the expressions in the given AST and
+// returns an error if there is an expression which definitely fails at
+// run time because of the types of its operands, like "1+\"x\"" or a
+// method which does not exist on the type of the value it is called on.
+// Expressions whose types are not known are not checked. The types are
+// taken from constants, literals, the annotations of closure arguments
+// like "(p: map) -> ...", the declared types of the given arguments and
+// the known results of operations, functions and methods.
+func (fg *FunctionGenerator) CheckTypes(ast parser2.AST, args ...Arg) error {
+	var scope *typeScope
+	for _, a := range args {
+		scope = scope.add(a.Name, &typeInfo{typ: a.Type})
+	}
+	tc := typeChecker{fg: fg}
+	_, err := tc.check(ast, scope)
+	if err != nil {
+		return fmt.Errorf("type error: %w", err)
+	}
+	return nil
+}
+
+// typeInfo is the information about a value known at compile time
+type typeInfo struct {
+	// typ is the type of the value, zero if not known
+	typ Type
+	// fields are the known entries of a map, nil if not known
+	fields map[string]*typeInfo
+	// closure is the literal a closure is created from, nil if not known
+	closure *parser2.ClosureLiteral
+	// result is the result of a closure, nil if not known
+	result *typeInfo
+}
+
+var unknown = &typeInfo{}
+
+func known(t Type) *typeInfo {
+	return &typeInfo{typ: t}
+}
+
+// merge returns the information valid for both values
+func (t *typeInfo) merge(o *typeInfo) *typeInfo {
+	if t.typ == o.typ {
+		return known(t.typ)
+	}
+	return unknown
+}
+
+// typeScope contains the types of the variables in scope
+type typeScope struct {
+	name   string
+	info   *typeInfo
+	parent *typeScope
+}
+
+func (s *typeScope) add(name string, info *typeInfo) *typeScope {
+	return &typeScope{name: name, info: info, parent: s}
+}
+
+func (s *typeScope) get(name string) *typeInfo {
+	for ; s != nil; s = s.parent {
+		if s.name == name {
+			return s.info
+		}
+	}
+	return unknown
+}
+
+// typedOperation is implemented by operations which are able to tell
+// if they are defined on the given operand types. If so, also the type
+// of the result is returned if known.
+type typedOperation interface {
+	resultType(a, b Type) (Type, bool)
+}
+
+func (o *operationMatrixSimple) resultType(a, b Type) (Type, bool) {
+	if a >= Type(len(o.matrix)) || b >= Type(len(o.matrix[a])) || o.matrix[a][b] == nil {
+		return 0, false
+	}
+	return o.results[a][b], true
+}
+
+func (o operationMatrixStringAdd) resultType(a, b Type) (Type, bool) {
+	if a == StringTypeId {
+		return StringTypeId, true
+	}
+	if to, ok := o.parent.(typedOperation); ok {
+		return to.resultType(a, b)
+	}
+	return 0, true
+}
+
+func (o *operationMatrixDeepEqual) resultType(a, b Type) (Type, bool) {
+	if a == NilTypeId || b == NilTypeId || (a == b && (a == ListTypeId || a == MapTypeId)) {
+		return BoolTypeId, true
+	}
+	if to, ok := o.equal.(typedOperation); ok {
+		return to.resultType(a, b)
+	}
+	return 0, true
+}
+
+func (su *SimpleUnary) resultType(a Type) (Type, bool) {
+	if a >= Type(len(su.list)) || su.list[a] == nil {
+		return 0, false
+	}
+	return su.results[a], true
+}
+
+// isCoreType returns true if the type is one of the types whose
+// values are known not to be a list or a map if the type is not
+// the list or map type.
+func isCoreType(t Type) bool {
+	switch t {
+	case IntTypeId, FloatTypeId, StringTypeId, BoolTypeId, ListTypeId, MapTypeId, NilTypeId, ClosureTypeId:
+		return true
+	}
+	return false
+}
+
+// comparisons are the operations which always return a bool
+var comparisons = map[string]bool{"=": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true, "~": true}
+
+type typeChecker struct {
+	fg *FunctionGenerator
+}
+
+func (tc typeChecker) name(t Type) string {
+	return tc.fg.typeDescriptions[t].Name
+}
+
+// resultInfo returns the information about the result of a function
+// whose result type is declared by the given name
+func (tc typeChecker) resultInfo(result string) *typeInfo {
+	if t := tc.typeByName(result); t != 0 {
+		return known(t)
+	}
+	return unknown
+}
+
+// typeByName returns the type with the given name
+func (tc typeChecker) typeByName(name string) Type {
+	for t := Type(1); t <= tc.fg.typeId; t++ {
+		if tc.fg.typeDescriptions[t].Name == name {
+			return t
+		}
+	}
+	return 0
+}
+
+// constInfo returns the information about a constant value
+func (tc typeChecker) constInfo(v Value, withFields bool) *typeInfo {
+	info := known(v.GetType())
+	switch c := v.(type) {
+	case Map:
+		if withFields {
+			info.fields = map[string]*typeInfo{}
+			c.Iter(func(key string, v Value) bool {
+				info.fields[key] = tc.constInfo(v, false)
+				return true
+			})
+		}
+	case Closure:
+		info.closure = c.Source
+	}
+	return info
+}
+
+func (tc typeChecker) checkList(list []parser2.AST, scope *typeScope) ([]*typeInfo, error) {
+	infos := make([]*typeInfo, len(list))
+	for i, a := range list {
+		info, err := tc.check(a, scope)
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = info
+	}
+	return infos, nil
+}
+
+// expect returns an error if the type is known and differs from the expected type
+func (tc typeChecker) expect(info *typeInfo, expected Type, line parser2.Line, m string) error {
+	if info.typ != 0 && info.typ != expected {
+		return line.Errorf("%s: %s", m, tc.name(info.typ))
+	}
+	return nil
+}
+
+func (tc typeChecker) check(ast parser2.AST, scope *typeScope) (*typeInfo, error) {
+	switch a := ast.(type) {
+	case *parser2.Const[Value]:
+		if c, ok := a.Value.(Closure); ok && c.Source != nil {
+			// the closure is created by the optimizer, so
+			// its body is checked like a closure literal
+			return tc.check(c.Source, nil)
+		}
+		return tc.constInfo(a.Value, true), nil
+	case *parser2.Ident:
+		return scope.get(a.Name), nil
+	case *parser2.Let:
+		value, err := tc.check(a.Value, scope)
+		if err != nil {
+			return nil, err
+		}
+		if d := a.Destructuring; d != nil {
+			if isCoreType(value.typ) {
+				if d.IsMap && value.typ != MapTypeId {
+					return nil, d.Errorf("destructuring requires a map, found %s", tc.name(value.typ))
+				}
+				if !d.IsMap && value.typ != ListTypeId {
+					return nil, d.Errorf("destructuring requires a list, found %s", tc.name(value.typ))
+				}
+			}
+			for _, n := range d.Names {
+				info := unknown
+				if f, ok := value.fields[n]; ok {
+					info = f
+				}
+				scope = scope.add(n, info)
+			}
+			return tc.check(a.Inner, scope)
+		}
+		return tc.check(a.Inner, scope.add(a.Name, value))
+	case *parser2.ConstDef:
+		value, err := tc.check(a.Value, scope)
+		if err != nil {
+			return nil, err
+		}
+		return tc.check(a.Inner, scope.add(a.Name, value))
+	case *parser2.Import:
+		return tc.check(a.Inner, scope.add(a.Name, known(MapTypeId)))
+	case *parser2.FuncGroup:
+		for _, cl := range a.Funcs {
+			scope = scope.add(cl.ThisName, &typeInfo{typ: ClosureTypeId, closure: cl})
+		}
+		for _, cl := range a.Funcs {
+			if _, err := tc.check(cl, scope); err != nil {
+				return nil, err
+			}
+		}
+		return tc.check(a.Inner, scope)
+	case *parser2.If:
+		cond, err := tc.check(a.Cond, scope)
+		if err != nil {
+			return nil, err
+		}
+		if err := tc.expect(cond, BoolTypeId, a.Cond.GetLine(), "if condition is not a bool"); err != nil {
+			return nil, err
+		}
+		then, err := tc.check(a.Then, scope)
+		if err != nil {
+			return nil, err
+		}
+		els, err := tc.check(a.Else, scope)
+		if err != nil {
+			return nil, err
+		}
+		return then.merge(els), nil
+	case *parser2.Switch[Value]:
+		return tc.checkSwitch(a, scope)
+	case *parser2.TryCatch:
+		if _, err := tc.check(a.Try, scope); err != nil {
+			return nil, err
+		}
+		if _, err := tc.check(a.Catch, scope); err != nil {
+			return nil, err
+		}
+		return unknown, nil
+	case *parser2.Unary:
+		value, err := tc.check(a.Value, scope)
+		if err != nil {
+			return nil, err
+		}
+		if value.typ != 0 {
+			if su := tc.fg.GetUnaryList(a.Operator); su != nil {
+				r, ok := su.resultType(value.typ)
+				if !ok {
+					return nil, a.Errorf("unary operation '%s' not defined on %s", a.Operator, tc.name(value.typ))
+				}
+				return known(r), nil
+			}
+		}
+		return unknown, nil
+	case *parser2.Operate:
+		return tc.checkOperate(a, scope)
+	case *parser2.ClosureLiteral:
+		if _, err := tc.checkList(a.Defaults, scope); err != nil {
+			return nil, err
+		}
+		info := &typeInfo{typ: ClosureTypeId, closure: a}
+		inner := scope
+		for i, n := range a.Names {
+			inner = inner.add(n, known(tc.typeByName(a.Type(i))))
+		}
+		if a.Recursive {
+			inner = inner.add(a.ThisName, info)
+		}
+		result, err := tc.check(a.Func, inner)
+		if err != nil {
+			return nil, err
+		}
+		info.result = result
+		return info, nil
+	case *parser2.Interpolation:
+		if _, err := tc.checkList(a.Values, scope); err != nil {
+			return nil, err
+		}
+		return known(StringTypeId), nil
+	case *parser2.ListLiteral:
+		if _, err := tc.checkList(a.List, scope); err != nil {
+			return nil, err
+		}
+		return known(ListTypeId), nil
+	case *parser2.Range:
+		bounds := []parser2.AST{a.From, a.To}
+		if a.Step != nil {
+			bounds = append(bounds, a.Step)
+		}
+		infos, err := tc.checkList(bounds, scope)
+		if err != nil {
+			return nil, err
+		}
+		for i, info := range infos {
+			if isCoreType(info.typ) && info.typ != IntTypeId && info.typ != FloatTypeId {
+				return nil, bounds[i].GetLine().Errorf("range requires numbers, found %s", tc.name(info.typ))
+			}
+		}
+		return known(ListTypeId), nil
+	case *parser2.ListComprehension:
+		return tc.checkComprehension(a, scope)
+	case *parser2.MapLiteral:
+		info := &typeInfo{typ: MapTypeId, fields: map[string]*typeInfo{}}
+		var err error
+		a.Map.Iter(func(key string, v parser2.AST) bool {
+			var f *typeInfo
+			f, err = tc.check(v, scope)
+			info.fields[key] = f
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return info, nil
+	case *parser2.MapAccess:
+		value, err := tc.check(a.MapValue, scope)
+		if err != nil {
+			return nil, err
+		}
+		if a.Optional && value.typ == NilTypeId {
+			return value, nil
+		}
+		if isCoreType(value.typ) && value.typ != MapTypeId {
+			return nil, a.Errorf("'.%s' not possible; %s is not a map", a.Key, tc.name(value.typ))
+		}
+		if value.fields != nil {
+			if f, ok := value.fields[a.Key]; ok {
+				return f, nil
+			}
+			if !a.Optional {
+				return nil, a.Errorf("key '%s' not found in map", a.Key)
+			}
+		}
+		return unknown, nil
+	case *parser2.ListAccess:
+		infos, err := tc.checkList([]parser2.AST{a.List, a.Index}, scope)
+		if err != nil {
+			return nil, err
+		}
+		if isCoreType(infos[0].typ) && infos[0].typ != ListTypeId {
+			return nil, a.Errorf("not a list: %s", tc.name(infos[0].typ))
+		}
+		if err := tc.expect(infos[1], IntTypeId, a.Index.GetLine(), "not an int"); err != nil {
+			return nil, err
+		}
+		return unknown, nil
+	case *parser2.FunctionCall:
+		return tc.checkFunctionCall(a, scope)
+	case *parser2.MethodCall:
+		return tc.checkMethodCall(a, scope)
+	}
+	return unknown, nil
+}
+
+func (tc typeChecker) checkOperate(a *parser2.Operate, scope *typeScope) (*typeInfo, error) {
+	infos, err := tc.checkList([]parser2.AST{a.A, a.B}, scope)
+	if err != nil {
+		return nil, err
+	}
+	at, bt := infos[0].typ, infos[1].typ
+	switch a.Operator {
+	case "&", "|":
+		// evaluated with short circuit, so only bools are allowed
+		for i, info := range infos {
+			if err := tc.expect(info, BoolTypeId, []parser2.AST{a.A, a.B}[i].GetLine(), "not a bool"); err != nil {
+				return nil, err
+			}
+		}
+		return known(BoolTypeId), nil
+	case "??":
+		if at != 0 && at != NilTypeId {
+			return infos[0], nil
+		}
+		if at == NilTypeId {
+			return infos[1], nil
+		}
+		return unknown, nil
+	}
+	if at != 0 && bt != 0 {
+		if to, ok := tc.fg.GetOpImpl(a.Operator).(typedOperation); ok {
+			r, ok := to.resultType(at, bt)
+			if !ok {
+				return nil, a.Errorf("operation '%s' not defined on %s, %s", a.Operator, tc.name(at), tc.name(bt))
+			}
+			if r != 0 {
+				return known(r), nil
+			}
+		}
+	}
+	if comparisons[a.Operator] {
+		return known(BoolTypeId), nil
+	}
+	return unknown, nil
+}
+
+func (tc typeChecker) checkSwitch(a *parser2.Switch[Value], scope *typeScope) (*typeInfo, error) {
+	if _, err := tc.check(a.SwitchValue, scope); err != nil {
+		return nil, err
+	}
+	result, err := tc.check(a.Default, scope)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range a.Cases {
+		inner := scope
+		if c.Pattern != nil {
+			for _, n := range parser2.PatternVars(c.Pattern) {
+				inner = inner.add(n, unknown)
+			}
+		} else if _, err := tc.check(c.CaseConst, scope); err != nil {
+			return nil, err
+		}
+		if c.Guard != nil {
+			guard, err := tc.check(c.Guard, inner)
+			if err != nil {
+				return nil, err
+			}
+			if err := tc.expect(guard, BoolTypeId, c.Guard.GetLine(), "guard does not return a bool"); err != nil {
+				return nil, err
+			}
+		}
+		value, err := tc.check(c.Value, inner)
+		if err != nil {
+			return nil, err
+		}
+		result = result.merge(value)
+	}
+	return result, nil
+}
+
+func (tc typeChecker) checkComprehension(a *parser2.ListComprehension, scope *typeScope) (*typeInfo, error) {
+	inner := scope
+	for _, g := range a.Generators {
+		list, err := tc.check(g.List, inner)
+		if err != nil {
+			return nil, err
+		}
+		if isCoreType(list.typ) && list.typ != ListTypeId {
+			return nil, g.List.GetLine().Errorf("for requires a list, found %s", tc.name(list.typ))
+		}
+		inner = inner.add(g.Name, unknown)
+		if g.Cond != nil {
+			cond, err := tc.check(g.Cond, inner)
+			if err != nil {
+				return nil, err
+			}
+			if err := tc.expect(cond, BoolTypeId, g.Cond.GetLine(), "condition is not a bool"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := tc.check(a.Value, inner); err != nil {
+		return nil, err
+	}
+	return known(ListTypeId), nil
+}
+
+func (tc typeChecker) checkFunctionCall(a *parser2.FunctionCall, scope *typeScope) (*typeInfo, error) {
+	args, err := tc.checkList(a.Args, scope)
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := a.Func.(*parser2.Ident); ok {
+		if i, isStatic := tc.fg.Identifier()(id.Name); isStatic && i.IsFunc {
+			if f, ok := tc.fg.GetStaticFunction(id.Name); ok {
+				return tc.resultInfo(f.Result), nil
+			}
+			return unknown, nil
+		}
+	}
+	fu, err := tc.check(a.Func, scope)
+	if err != nil {
+		return nil, err
+	}
+	if isCoreType(fu.typ) && fu.typ != ClosureTypeId {
+		return nil, a.Errorf("not a function: %s", tc.name(fu.typ))
+	}
+	if cl := fu.closure; cl != nil {
+		for i, arg := range args {
+			p := i
+			if a.ArgNames != nil && a.ArgNames[i] != "" {
+				p = slices.Index(cl.Names, a.ArgNames[i])
+			}
+			if p < 0 || arg.typ == 0 {
+				continue
+			}
+			required := tc.typeByName(cl.Type(p))
+			if required != 0 && arg.typ != required && !(required == FloatTypeId && arg.typ == IntTypeId) {
+				return nil, a.Args[i].GetLine().Errorf("argument '%s' is a %s, but a %s is required", cl.Names[p], tc.name(arg.typ), tc.name(required))
+			}
+		}
+	}
+	if fu.result != nil {
+		return fu.result, nil
+	}
+	return unknown, nil
+}
+
+func (tc typeChecker) checkMethodCall(a *parser2.MethodCall, scope *typeScope) (*typeInfo, error) {
+	value, err := tc.check(a.Value, scope)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tc.checkList(a.Args, scope); err != nil {
+		return nil, err
+	}
+	if value.typ == 0 || (a.Optional && value.typ == NilTypeId) {
+		return unknown, nil
+	}
+	if f, ok := value.fields[a.Name]; ok {
+		// a closure stored in the map is called
+		if f.result != nil {
+			return f.result, nil
+		}
+		return unknown, nil
+	}
+	methods := tc.fg.methods[value.typ]
+	if methods == nil {
+		return unknown, nil
+	}
+	me, ok := methods[a.Name]
+	if !ok {
+		if value.typ == MapTypeId {
+			// may be a closure stored in the map
+			return unknown, nil
+		}
+		return nil, a.Errorf("method '%s' not found on %s", a.Name, tc.name(value.typ))
+	}
+	if me.Args > 0 && me.Args != len(a.Args)+1 {
+		return nil, a.Errorf("wrong number of arguments at call of \"%s\", required %d, found %d", me.Description.String(a.Name), me.Args-1, len(a.Args))
+	}
+	return tc.resultInfo(me.Result), nil
+}
@@ -0,0 +1,135 @@
+package value
+
+import (
+	"github.com/hneemann/parser2/funcGen"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  string
+		err  string
+	}{
+		{name: "ok", exp: "let a=1; a+2.5"},
+		{name: "stringAdd", exp: "\"x\"+1"},
+		{name: "add", exp: "1+\"x\"", err: "operation '+' not defined on int, string"},
+		{name: "let", exp: "let a=true;\na*2", err: "operation '*' not defined on bool, int in line 2"},
+		{name: "field", exp: "let p={age:3}; p.age-\"x\"", err: "operation '-' not defined on int, string"},
+		{name: "missingKey", exp: "let p={age:3}; p.name", err: "key 'name' not found in map"},
+		{name: "optionalKey", exp: "let p={age:3}; p?.name ?? 1"},
+		{name: "notMap", exp: "let a=1; a.b", err: "'.b' not possible; int is not a map"},
+		{name: "notList", exp: "let a=\"x\"; a[0]", err: "not a list: string"},
+		{name: "unary", exp: "-\"x\"", err: "unary operation '-' not defined on string"},
+		{name: "ifCond", exp: "if 1 then 2 else 3", err: "if condition is not a bool: int"},
+		{name: "and", exp: "true & 1", err: "not a bool: int"},
+		{name: "method", exp: "[1,2].size()+1"},
+		{name: "methodResult", exp: "[1,2].size()+\"x\"", err: "operation '+' not defined on int, string"},
+		{name: "unknownMethod", exp: "let a=1; a.map(x->x)", err: "method 'map' not found on int"},
+		{name: "methodArgs", exp: "[1,2].map()", err: "wrong number of arguments at call of"},
+		{name: "mapClosure", exp: "let m={f:x->x*2}; m.f(2)"},
+		{name: "staticFunc", exp: "sqrt(2)+\"x\"", err: "operation '+' not defined on float, string"},
+		{name: "closureResult", exp: "let f=x->\"a\"+x; f(1)-1", err: "operation '-' not defined on string, int"},
+		{name: "annotation", exp: "let f=(p: map)->p.a; f({a:1})"},
+		{name: "annotationUse", exp: "let f=(p: map)->p.size()-\"x\"; f({a:1})", err: "operation '-' not defined on int, string"},
+		{name: "annotationCall", exp: "let f=(p: map)->p.a;\nf(1)", err: "argument 'p' is a int, but a map is required in line 2"},
+		{name: "annotationNamed", exp: "let f=(a: int, b: string)->b+a; f(b: [x], a: 2)", err: "argument 'b' is a list, but a string is required"},
+		{name: "annotationFloat", exp: "func f(x: float) x*2; f(1)"},
+		{name: "funcAnnotation", exp: "func f(s: string) s.len(); f([1])", err: "argument 's' is a list, but a string is required"},
+		{name: "unknownArg", exp: "x+1"},
+		{name: "switch", exp: "switch 1 case 1: 2 default \"a\"+1"},
+		{name: "comprehension", exp: "[a for a in 5]", err: "for requires a list, found int"},
+		{name: "range", exp: "\"a\"..5", err: "range requires numbers, found string"},
+		{name: "destructuring", exp: "let [a, b]=1; a", err: "destructuring requires a list, found int"},
+		{name: "destructuredField", exp: "let {a, b}={a:1, b:true}; a+b", err: "operation '+' not defined on int, bool"},
+		{name: "ifMerge", exp: "let a=if x then 1 else 2; a+\"s\"", err: "operation '+' not defined on int, string"},
+		{name: "divResult", exp: "1/2-\"x\"", err: "operation '-' not defined on float, string"},
+		{name: "powResult", exp: "2^2-\"x\"", err: "operation '-' not defined on int, string"},
+		{name: "negResult", exp: "-1.5+true", err: "operation '+' not defined on float, bool"},
+		{name: "listAdd", exp: "([1]+[2]).size()"},
+	}
+
+	fg := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := fg.CreateAst(test.exp, fg.Identifier().AddArgs([]string{"x"}, nil))
+			assert.NoError(t, err)
+			err = fg.CheckTypes(ast, Arg{Name: "x"})
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, test.err)
+				assert.ErrorContains(t, err, "type error")
+			}
+		})
+	}
+}
+
+func TestGenerateTyped(t *testing.T) {
+	fg := New()
+	f, _, err := fg.GenerateTyped("p.size()*2", Arg{Name: "p", Type: ListTypeId})
+	assert.NoError(t, err)
+	v, err := f.Eval(NewList(Int(1), Int(2)))
+	assert.NoError(t, err)
+	assert.Equal(t, Int(4), v)
+
+	_, _, err = fg.GenerateTyped("p.len()", Arg{Name: "p", Type: ListTypeId})
+	assert.ErrorContains(t, err, "method 'len' not found on list")
+
+	_, _, err = fg.GenerateTyped("a+b", Arg{Name: "a", Type: IntTypeId}, Arg{Name: "b", Type: BoolTypeId})
+	assert.ErrorContains(t, err, "operation '+' not defined on int, bool")
+
+	f, _, err = fg.GenerateTyped("(s: string)->s.toUpper()")
+	assert.NoError(t, err)
+	v, err = f.Eval()
+	assert.NoError(t, err)
+	c, ok := v.(Closure)
+	assert.True(t, ok)
+	v, err = c.Eval(funcGen.NewEmptyStack[Value](), String("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, String("A"), v)
+}
+
+func TestResultTypesDeclared(t *testing.T) {
+	fg := New()
+	tc := typeChecker{fg: fg}
+	checkResult := func(t *testing.T, result string) {
+		if result != "any" {
+			assert.NotZero(t, tc.typeByName(result), "result type '%s' is not declared", result)
+		}
+	}
+	for typ := Type(1); typ <= fg.typeId; typ++ {
+		for name, m := range fg.methods[typ] {
+			t.Run(tc.name(typ)+"."+name, func(t *testing.T) {
+				checkResult(t, m.Result)
+			})
+		}
+	}
+	for _, op := range []string{"=", "<", "+", "-", "*", "/", "^", "%", "<<", ">>", "&", "|"} {
+		to, ok := fg.GetOpImpl(op).(typedOperation)
+		assert.True(t, ok, op)
+		for a := Type(1); a <= fg.typeId; a++ {
+			for b := Type(1); b <= fg.typeId; b++ {
+				if r, ok := to.resultType(a, b); ok {
+					assert.NotZero(t, r, "result of %s %s %s is not declared", tc.name(a), op, tc.name(b))
+				}
+			}
+		}
+	}
+	for _, op := range []string{"-", "!"} {
+		su := fg.GetUnaryList(op)
+		for a := Type(1); a <= fg.typeId; a++ {
+			if r, ok := su.resultType(a); ok {
+				assert.NotZero(t, r, "result of %s%s is not declared", op, tc.name(a))
+			}
+		}
+	}
+	for _, f := range fg.GetStaticDocumentation().Functions {
+		t.Run(f.Name, func(t *testing.T) {
+			sf, ok := fg.GetStaticFunction(f.Name)
+			assert.True(t, ok)
+			checkResult(t, sf.Result)
+		})
+	}
+}
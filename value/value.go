@@ -31,6 +31,7 @@ var (
 	FormatTypeId  Type
 	LinkTypeId    Type
 	FileTypeId    Type
+	NilTypeId     Type
 )
 
 type Value interface {
@@ -129,6 +130,7 @@ func (c Closure) EvalSt(st funcGen.Stack[Value], a ...Value) (Value, error) {
 func createClosureMethods() MethodMap {
 	return MethodMap{
 		"args": MethodAtType(0, func(c Closure, stack funcGen.Stack[Value]) (Value, error) { return Int(c.Args), nil }).
+			SetResult("int").
 			SetMethodDescription("Returns the number of arguments the function takes."),
 		"invoke": MethodAtType(1, func(c Closure, stack funcGen.Stack[Value]) (Value, error) {
 			if l, ok := stack.Get(1).ToList(); ok {
@@ -136,7 +138,7 @@ func createClosureMethods() MethodMap {
 				if err != nil {
 					return nil, err
 				}
-				if len(args) != c.Args {
+				if len(args) > c.Args || len(args) < c.Args-len(c.Defaults) {
 					return nil, fmt.Errorf("wrong number of arguments in invoke: %d instead of %d", len(args), c.Args)
 				}
 				for _, arg := range args {
@@ -147,6 +149,7 @@ func createClosureMethods() MethodMap {
 				return nil, fmt.Errorf("argument of invike needs to be a list, not: %s", TypeName(stack.Get(1)))
 			}
 		}).
+			SetResult("any").
 			SetMethodDescription("arg_list", "Invokes the function. The values of the given list are passed to the function as arguments."),
 	}
 }
@@ -182,6 +185,7 @@ func createBoolMethods() MethodMap {
 			s, err := b.ToString(stack)
 			return String(s), err
 		}).
+			SetResult("string").
 			SetMethodDescription("Returns the string 'true' or 'false'."),
 	}
 }
@@ -210,6 +214,7 @@ func createFloatMethods() MethodMap {
 			s, err := f.ToString(stack)
 			return String(s), err
 		}).
+			SetResult("string").
 			SetMethodDescription("Returns a string representation of the float."),
 	}
 }
@@ -242,6 +247,7 @@ func createIntMethods() MethodMap {
 			s, err := i.ToString(stack)
 			return String(s), err
 		}).
+			SetResult("string").
 			SetMethodDescription("Returns a string representation of the int."),
 	}
 }
@@ -254,10 +260,55 @@ func (i Int) ToFloat() (float64, bool) {
 	return float64(i), true
 }
 
+// Nil is the value returned by the optional chaining "m?.a" if the
+// key is not present in the map. It is also available as constant "nil".
+type Nil struct{}
+
+func (n Nil) ToList() (*List, bool) {
+	return nil, false
+}
+
+func (n Nil) ToMap() (Map, bool) {
+	return EmptyMap, false
+}
+
+func (n Nil) ToFloat() (float64, bool) {
+	return 0, false
+}
+
+func (n Nil) ToString(funcGen.Stack[Value]) (string, error) {
+	return "nil", nil
+}
+
+func (n Nil) GetType() Type {
+	return NilTypeId
+}
+
+func (n Nil) String() string {
+	return "nil"
+}
+
+func createNilMethods() MethodMap {
+	return MethodMap{
+		"string": MethodAtType(0, func(n Nil, stack funcGen.Stack[Value]) (Value, error) {
+			return String("nil"), nil
+		}).
+			SetResult("string").
+			SetMethodDescription("Returns the string 'nil'."),
+	}
+}
+
+func isNil(v Value) bool {
+	_, ok := v.(Nil)
+	return ok
+}
+
 type SimpleUnary struct {
 	list []funcGen.UnaryOperatorFunc[Value]
-	fg   *FunctionGenerator
-	op   string
+	// results are the declared types of the results, zero if not declared
+	results []Type
+	fg      *FunctionGenerator
+	op      string
 }
 
 func NewUnaryOperationList(fg *FunctionGenerator, op string) *SimpleUnary {
@@ -275,25 +326,42 @@ func (su *SimpleUnary) Calc(v Value) (Value, error) {
 	return nil, errors.New("unary operation '" + su.op + "' not defined on " + su.fg.typeDescriptions[aType].Name)
 }
 
+// Register registers the operation on the given type without
+// declaring the type of its result.
 func (su *SimpleUnary) Register(a Type, op funcGen.UnaryOperatorFunc[Value]) {
+	su.RegisterResult(a, 0, op)
+}
+
+// RegisterResult registers the operation on the given type. The type of
+// the result is used by the static type checks.
+func (su *SimpleUnary) RegisterResult(a, result Type, op funcGen.UnaryOperatorFunc[Value]) {
 	for a >= Type(len(su.list)) {
 		su.list = append(su.list, nil)
+		su.results = append(su.results, 0)
 	}
 	if su.list[a] != nil {
 		panic("unary operation '" + su.op + "' is already registered on this types")
 	}
 	su.list[a] = op
+	su.results[a] = result
 }
 
 type OperationMatrix interface {
 	funcGen.OperatorImpl[Value]
+	// Register registers the operation on the given types without
+	// declaring the type of its result.
 	Register(a, b Type, op funcGen.OperatorFunc[Value])
+	// RegisterResult registers the operation on the given types. The type
+	// of the result is used by the static type checks.
+	RegisterResult(a, b, result Type, op funcGen.OperatorFunc[Value])
 }
 
 type operationMatrixSimple struct {
 	matrix [][]funcGen.OperatorImpl[Value]
-	fg     *FunctionGenerator
-	op     string
+	// results are the declared types of the results, zero if not declared
+	results [][]Type
+	fg      *FunctionGenerator
+	op      string
 }
 
 func NewOperationMatrix(fg *FunctionGenerator, op string) OperationMatrix {
@@ -316,18 +384,28 @@ func (o *operationMatrixSimple) Calc(st funcGen.Stack[Value], a, b Value) (Value
 }
 
 func (o *operationMatrixSimple) Register(a, b Type, op funcGen.OperatorFunc[Value]) {
+	o.RegisterResult(a, b, 0, op)
+}
+
+func (o *operationMatrixSimple) RegisterResult(a, b, result Type, op funcGen.OperatorFunc[Value]) {
 	for a >= Type(len(o.matrix)) {
 		o.matrix = append(o.matrix, []funcGen.OperatorImpl[Value]{})
+		o.results = append(o.results, []Type{})
 	}
 	for b >= Type(len(o.matrix[a])) {
 		o.matrix[a] = append(o.matrix[a], nil)
+		o.results[a] = append(o.results[a], 0)
 	}
 	if o.matrix[a][b] != nil {
 		panic("operation '" + o.op + "' is already registered on this types")
 	}
 	o.matrix[a][b] = op
+	o.results[a][b] = result
 }
 func (fg *FunctionGenerator) ParseNumber(n string) (Value, error) {
+	if fg.engineeringNumbers {
+		return parseEngineeringNumber(n)
+	}
 	i, err := strconv.Atoi(n)
 	if err == nil {
 		return Int(i), nil
@@ -343,6 +421,19 @@ func (fg *FunctionGenerator) FromString(s string) Value {
 	return String(s)
 }
 
+// JoinStrings concatenates the parts of an interpolated string
+func (fg *FunctionGenerator) JoinStrings(st funcGen.Stack[Value], values []Value) (Value, error) {
+	var b strings.Builder
+	for _, v := range values {
+		s, err := v.ToString(st)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(s)
+	}
+	return String(b.String()), st.Alloc(b.Len())
+}
+
 func (fg *FunctionGenerator) FromClosure(c funcGen.Function[Value]) Value {
 	return Closure(c)
 }
@@ -384,17 +475,60 @@ func (fg *FunctionGenerator) IsMap(mapValue Value) bool {
 	return ok
 }
 
+func (fg *FunctionGenerator) Nil() Value {
+	return Nil{}
+}
+
+func (fg *FunctionGenerator) IsNil(v Value) bool {
+	return isNil(v)
+}
+
 func (fg *FunctionGenerator) FromList(items []Value) Value {
 	return NewList(items...)
 }
 
-func (fg *FunctionGenerator) AccessList(list Value, index Value) (Value, error) {
+func (fg *FunctionGenerator) ListItems(st funcGen.Stack[Value], list Value) ([]Value, bool, error) {
+	if l, ok := list.ToList(); ok {
+		items, err := l.ToSlice(st)
+		return items, true, err
+	}
+	return nil, false, nil
+}
+
+// Range creates the list of numbers of a range like "0..100 step 5"
+func (fg *FunctionGenerator) Range(from, to, step Value, hasStep bool) (Value, error) {
+	if !hasStep {
+		step = Int(1)
+	}
+	return NewRange(from, to, step)
+}
+
+// FromProducer creates a lazy list used by list comprehensions
+func (fg *FunctionGenerator) FromProducer(producer ListProducer, size int) Value {
+	if size < 0 {
+		return NewListFromIterable(producer)
+	}
+	return NewListFromSizedIterable(producer, size)
+}
+
+// Items returns the items of a list used by list comprehensions
+func (fg *FunctionGenerator) Items(list Value) (ListProducer, int, bool) {
+	if l, ok := list.ToList(); ok {
+		if size, ok := l.SizeIfKnown(); ok {
+			return l.iterable, size, true
+		}
+		return l.iterable, -1, true
+	}
+	return nil, 0, false
+}
+
+func (fg *FunctionGenerator) AccessList(st funcGen.Stack[Value], list Value, index Value) (Value, error) {
 	if l, ok := list.ToList(); ok {
 		if i, ok := index.(Int); ok {
 			if i < 0 {
 				return nil, fmt.Errorf("negative list index")
 			} else {
-				size, err := l.Size(funcGen.NewEmptyStack[Value]())
+				size, err := l.Size(st)
 				if err != nil {
 					return nil, err
 				}
@@ -428,6 +562,10 @@ func (fg *FunctionGenerator) GenerateCustom(ast parser2.AST, gc funcGen.Generato
 			if tryErr == nil {
 				return tryVal, nil
 			}
+			if funcGen.IsAborted(tryErr) {
+				// an aborted evaluation can not be caught
+				return nil, tryErr
+			}
 			catchVal, err := catchFunc(st, cs)
 			if err != nil {
 				return nil, l.EnhanceErrorf(err, "error in getting catch function")
@@ -439,8 +577,27 @@ func (fg *FunctionGenerator) GenerateCustom(ast parser2.AST, gc funcGen.Generato
 		}, tPure && cPure, nil
 	}
 	if op, ok := ast.(*parser2.Operate); ok {
-		// AND and OR with short evaluation
+		// AND, OR and the coalescing operator with short evaluation
 		switch op.Operator {
+		case "??":
+			aFunc, aPure, err := g.GenerateFunc(op.A, gc)
+			if err != nil {
+				return nil, false, err
+			}
+			bFunc, bPure, err := g.GenerateFunc(op.B, gc)
+			if err != nil {
+				return nil, false, err
+			}
+			return func(st funcGen.Stack[Value], cs []Value) (Value, error) {
+				aVal, err := aFunc(st, cs)
+				if err != nil {
+					return nil, err
+				}
+				if isNil(aVal) {
+					return bFunc(st, cs)
+				}
+				return aVal, nil
+			}, aPure && bPure, nil
 		case "&":
 			aFunc, aPure, err := g.GenerateFunc(op.A, gc)
 			if err != nil {
@@ -521,6 +678,7 @@ func simpleOnlyFloatFunc(name string, f func(float64) float64) funcGen.Function[
 		},
 		Args:   1,
 		IsPure: true,
+		Result: "float",
 	}.SetDescription("float", "The mathematical "+name+" function.")
 }
 
@@ -542,6 +700,7 @@ func simpleOnlyFloatFuncCheck(name string, argValid func(float65 float64) bool,
 		},
 		Args:   1,
 		IsPure: true,
+		Result: "float",
 	}.SetDescription("float", "The mathematical "+name+" function.")
 }
 
@@ -552,7 +711,8 @@ type FunctionGenerator struct {
 	less             funcGen.BoolFunc[Value]
 	typeDescriptions [maxTypeId]funcGen.TypeDescription
 
-	typeId Type
+	typeId             Type
+	engineeringNumbers bool
 }
 
 func (fg *FunctionGenerator) RegisterType(name string, description string) Type {
@@ -564,9 +724,19 @@ func (fg *FunctionGenerator) RegisterType(name string, description string) Type
 		Name:        name,
 		Description: description,
 	}
+	if fg.FunctionGenerator != nil {
+		fg.addTypeTest(fg.typeId)
+	}
 	return fg.typeId
 }
 
+// addTypeTest makes the given type available in match patterns
+func (fg *FunctionGenerator) addTypeTest(typ Type) {
+	fg.AddTypeTest(fg.typeDescriptions[typ].Name, func(v Value) bool {
+		return v.GetType() == typ
+	})
+}
+
 func (fg *FunctionGenerator) GetMethod(value Value, methodName string) (funcGen.Function[Value], error) {
 	typ := value.GetType()
 	methodMap := fg.methods[typ]
@@ -648,13 +818,15 @@ func New() *FunctionGenerator {
 	FormatTypeId = f.RegisterType("format", "Used to add css to values which is used when they are exported to a html file.")
 	LinkTypeId = f.RegisterType("link", "Used to add a link to a value.")
 	FileTypeId = f.RegisterType("file", "Represents a file which can be downloaded.")
+	NilTypeId = f.RegisterType("nil", "Represents a missing value like the result of 'm?.a' if the key 'a' is not present.")
 
 	fg := funcGen.New[Value]().
 		AddConstant("pi", Float(math.Pi)).
 		AddConstant("true", Bool(true)).
 		AddConstant("false", Bool(false)).
+		AddConstant("nil", Nil{}).
 		SetNumberParser(f).
-		SetKeyWords("let", "func", "if", "then", "else", "func", "switch", "case", "default", "const", "try", "catch").
+		SetKeyWords("let", "func", "if", "then", "else", "func", "switch", "case", "match", "default", "const", "try", "catch", "import", "for").
 		SetListHandler(f).
 		SetMapHandler(f).
 		SetClosureHandler(f).
@@ -667,10 +839,19 @@ func New() *FunctionGenerator {
 			}
 			return false, false
 		}).
+		AddOp("??", false, func(st funcGen.Stack[Value], a Value, b Value) (Value, error) {
+			if isNil(a) {
+				return b, nil
+			}
+			return a, nil
+		}).
 		AddOpImpl("|", true, Or(f)).
 		AddOpImpl("&", true, And(f))
 
 	f.FunctionGenerator = fg
+	for i := Type(1); i <= f.typeId; i++ {
+		f.addTypeTest(i)
+	}
 	equal := Equal(f)
 	less := Less(f)
 
@@ -701,11 +882,11 @@ func New() *FunctionGenerator {
 		}
 		return nil, notAllowed("~", a, b)
 	})
-	fg.AddOpImpl("<", false, less)
-	fg.AddOp(">", false, func(st funcGen.Stack[Value], a Value, b Value) (Value, error) {
+	fg.AddOpBehindAssoc("", "<", false, less, true, parser2.NonAssociative)
+	fg.AddOpBehindAssoc("", ">", false, funcGen.OperatorFunc[Value](func(st funcGen.Stack[Value], a Value, b Value) (Value, error) {
 		return less.Calc(st, b, a)
-	})
-	fg.AddOp("<=", false, func(st funcGen.Stack[Value], a Value, b Value) (Value, error) {
+	}), true, parser2.NonAssociative)
+	fg.AddOpBehindAssoc("", "<=", false, funcGen.OperatorFunc[Value](func(st funcGen.Stack[Value], a Value, b Value) (Value, error) {
 		le, err := less.Calc(st, a, b)
 		if err != nil {
 			return nil, err
@@ -714,8 +895,8 @@ func New() *FunctionGenerator {
 			return Bool(true), nil
 		}
 		return equal.Calc(st, a, b)
-	})
-	fg.AddOp(">=", false, func(st funcGen.Stack[Value], a Value, b Value) (Value, error) {
+	}), true, parser2.NonAssociative)
+	fg.AddOpBehindAssoc("", ">=", false, funcGen.OperatorFunc[Value](func(st funcGen.Stack[Value], a Value, b Value) (Value, error) {
 		le, err := less.Calc(st, b, a)
 		if err != nil {
 			return nil, err
@@ -724,7 +905,7 @@ func New() *FunctionGenerator {
 			return Bool(true), nil
 		}
 		return equal.Calc(st, a, b)
-	})
+	}), true, parser2.NonAssociative)
 
 	fg.AddOpImpl("+", false, Add(f)).
 		AddOpImpl("-", false, Sub(f)).
@@ -733,7 +914,7 @@ func New() *FunctionGenerator {
 		AddOpImpl("*", true, Mul(f)).
 		AddOpImpl("%", false, Mod(f)).
 		AddOpImpl("/", false, Div(f)).
-		AddOpImpl("^", false, Pow(f)).
+		AddOpBehindAssoc("", "^", false, Pow(f), true, parser2.RightAssociative).
 		AddUnary("-", Neg(f)).
 		AddUnary("!", Not(f)).
 		AddStaticFunction("throw", funcGen.Function[Value]{
@@ -746,6 +927,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: false,
+			Result: "any",
 		}.SetDescription("message", "Throws an exception.")).
 		AddStaticFunction("string", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -754,6 +936,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "string",
 		}.SetDescription("value", "Returns the string representation of the value.")).
 		AddStaticFunction("isFloat", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -763,6 +946,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "bool",
 		}.SetDescription("value", "Returns true if the value is a float.")).
 		AddStaticFunction("isInt", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -772,6 +956,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "bool",
 		}.SetDescription("value", "Returns true if the value is a int.")).
 		AddStaticFunction("float", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -783,6 +968,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "float",
 		}.SetDescription("value", "Returns the float representation of the value.")).
 		AddStaticFunction("int", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -796,6 +982,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "int",
 		}.SetDescription("value", "Returns the int representation of the value.")).
 		AddStaticFunction("abs", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -813,6 +1000,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "any",
 		}.SetDescription("value", "If value is negative, returns -value. Otherwise returns the value unchanged.")).
 		AddStaticFunction("sign", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -837,6 +1025,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "any",
 		}.SetDescription("value", "If value is negative -1 is returned. Otherwise 1 is returned.")).
 		AddStaticFunction("sqr", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -851,16 +1040,19 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "any",
 		}.SetDescription("value", "Returns the square of the value.")).
 		AddStaticFunction("random", funcGen.Function[Value]{
 			Func:   randomFunc(),
 			Args:   -1,
 			IsPure: false,
+			Result: "any",
 		}.SetDescription("n", "Returns a random integer between 0 and n-1. If n is missing, a random float value between 0<=r<1 is returned. ")).
 		AddStaticFunction("randomConst", funcGen.Function[Value]{
 			Func:   randomFunc(),
 			Args:   -1,
 			IsPure: true,
+			Result: "any",
 		}.SetDescription("n", "Returns a const random integer between 0 and n-1. If n is missing, a random float value between 0<=r<1 is returned. "+
 			"The random number is treated as a constant, which means that it is generated at compile time and remains unchanged in all evaluations of the generated expression.")).
 		AddStaticFunction("round", funcGen.Function[Value]{
@@ -876,6 +1068,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "int",
 		}.SetDescription("value", "Returns the value rounded to the nearest integer.")).
 		AddStaticFunction("binAnd", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -888,6 +1081,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   2,
 			IsPure: true,
+			Result: "int",
 		}.SetDescription("a", "b", "Returns the binary and of a, b.")).
 		AddStaticFunction("binOr", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -900,16 +1094,19 @@ func New() *FunctionGenerator {
 			},
 			Args:   2,
 			IsPure: true,
+			Result: "int",
 		}.SetDescription("a", "b", "Returns the binary or of a, b.")).
 		AddStaticFunction("bisection", funcGen.Function[Value]{
 			Func:   bisectionValue,
 			Args:   4,
 			IsPure: true,
+			Result: "float",
 		}.SetDescription("func(float) float", "min", "max", "eps", "Searches a zero in the given function by using the bisection method.").VarArgs(3, 4)).
 		AddStaticFunction("createLowPass", funcGen.Function[Value]{
 			Func:   createLowPass,
 			Args:   4,
 			IsPure: true,
+			Result: "map",
 		}.SetDescription("name", "func(p) float", "func(p) float", "tau", "Returns a low pass filter creating signal [name].")).
 		AddStaticFunction("numbers", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -924,6 +1121,7 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "list",
 		}.SetDescription("n", "Returns a list with n integer values, starting with 0.")).
 		AddStaticFunction("goto", funcGen.Function[Value]{
 			Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -935,8 +1133,9 @@ func New() *FunctionGenerator {
 			},
 			Args:   1,
 			IsPure: true,
+			Result: "map",
 		}.SetDescription("n", "Returns a map with the key 'state' set to the given value.")).
-		AddStaticFunction("sprintf", funcGen.Function[Value]{Func: sprintf, Args: -1, IsPure: true}.
+		AddStaticFunction("sprintf", funcGen.Function[Value]{Func: sprintf, Args: -1, IsPure: true, Result: "string"}.
 			SetDescription("format", "args", "The classic, well known sprintf function.")).
 		AddStaticFunction("sqrt", simpleOnlyFloatFuncCheck("sqrt", func(arg float64) bool { return arg >= 0 }, func(x float64) float64 { return math.Sqrt(x) })).
 		AddStaticFunction("ln", simpleOnlyFloatFuncCheck("ln", func(arg float64) bool { return arg >= 0 }, func(x float64) float64 { return math.Log(x) })).
@@ -959,6 +1158,7 @@ func New() *FunctionGenerator {
 	f.RegisterMethods(IntTypeId, createIntMethods())
 	f.RegisterMethods(FloatTypeId, createFloatMethods())
 	f.RegisterMethods(ClosureTypeId, createClosureMethods())
+	f.RegisterMethods(NilTypeId, createNilMethods())
 
 	f.AddStaticFunction("min", funcGen.Function[Value]{
 		Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -981,6 +1181,7 @@ func New() *FunctionGenerator {
 		},
 		Args:   -1,
 		IsPure: true,
+		Result: "any",
 	}.SetDescription("a", "b", "Returns the smaller of a and b."))
 	f.AddStaticFunction("max", funcGen.Function[Value]{
 		Func: func(st funcGen.Stack[Value], cs []Value) (Value, error) {
@@ -1003,6 +1204,7 @@ func New() *FunctionGenerator {
 		},
 		Args:   -1,
 		IsPure: true,
+		Result: "any",
 	}.SetDescription("a", "b", "Returns the larger of a and b."))
 	return f
 }
@@ -41,6 +41,10 @@ func TestBasic(t *testing.T) {
 		{exp: "3.0^3.0", res: Float(27.0)},
 		{exp: "3^4", res: Int(81)},
 		{exp: "2^12", res: Int(4096)},
+		{exp: "2^3^2", res: Int(512)},
+		{exp: "(2^3)^2", res: Int(64)},
+		{exp: "-2^2", res: Int(-4)},
+		{exp: "(1<2)=(2<3)", res: Bool(true)},
 		{exp: "1.0<2.0", res: Bool(true)},
 		{exp: "1.0>2.0", res: Bool(false)},
 		{exp: "1.0>2.0", res: Bool(false)},
@@ -105,6 +109,9 @@ func TestBasic(t *testing.T) {
 		{exp: "let s=3; let f=x->x*x*s;f(2)", res: Int(12)},
 		{exp: "func inv(x) -x; inv(2)", res: Int(-2)},
 		{exp: "func fib(n) if n<=2 then 1 else fib(n-1)+fib(n-2);[fib(10),fib(15)]", res: NewList(Int(55), Int(610))},
+		{exp: "func count(n, c) switch n case 0: c default count(n-1, c+1); count(50000, 0)", res: Int(50000)},
+		{exp: "func depth(n) if n=0 then 0 else 1+depth(n-1); depth(9000)", res: Int(9000)},
+		{exp: "func len(l, n) switch l match []: n match [_, ...r]: len(r, n+1) default -1; len(1..20000, 0)", res: Int(20000)},
 		{exp: "if 1<2 then 1 else 2", res: Int(1)},
 		{exp: "if 1>2 then 1 else 2", res: Int(2)},
 		{exp: "let a=2; if 1<a then 1 else 2", res: Int(1)},
@@ -186,8 +193,21 @@ func TestBasic(t *testing.T) {
 	})
 }
 
+// backends are the backends the tests are run with
+var backends = map[string]funcGen.Backend{"tree": funcGen.TreeBackend, "bytecode": funcGen.BytecodeBackend}
+
+// runTest runs the tests using all backends
 func runTest(t *testing.T, tests []testType) {
-	valueParser := New()
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			fg := New()
+			fg.SetBackend(backend)
+			runTestWith(t, fg, tests)
+		})
+	}
+}
+
+func runTestWith(t *testing.T, valueParser *FunctionGenerator, tests []testType) {
 	for _, test := range tests {
 		test := test
 		t.Run(shrinkSpace(test.exp), func(t *testing.T) {